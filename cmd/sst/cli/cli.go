@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"os"
 	"os/user"
-	"path/filepath"
 	"strings"
 
 	flag "github.com/spf13/pflag"
@@ -13,7 +12,6 @@ import (
 
 	"github.com/charmbracelet/huh"
 	"github.com/fatih/color"
-	"github.com/joho/godotenv"
 	"github.com/sst/sst/v3/internal/util"
 	"github.com/sst/sst/v3/pkg/project"
 )
@@ -26,11 +24,12 @@ type Cli struct {
 	Context   context.Context
 	cancel    context.CancelFunc
 	env       []string
+	envReport *EnvReport
 }
 
 func New(ctx context.Context, cancel context.CancelFunc, root *Command, version string) (*Cli, error) {
 	env := os.Environ()
-	godotenv.Load()
+	loadEnvFiles(".")
 	parsedFlags := map[string]interface{}{}
 	root.init(parsedFlags)
 	flag.CommandLine.Init("sst", flag.ContinueOnError)
@@ -84,6 +83,9 @@ func (c *Cli) Run() error {
 		}
 		required += 1
 	}
+	if err := c.validateOutput(); err != nil {
+		return err
+	}
 	if c.Bool("help") || active.Run == nil || len(c.arguments) < required {
 		return c.PrintHelp()
 	} else {
@@ -213,6 +215,16 @@ type CommandPath []Command
 
 var ErrHelp = util.NewReadableError(nil, "")
 
+// ExitError makes the CLI exit with the code, without printing an error. It's
+// used to pass on the exit code of a process that was run.
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exited with code %d", e.Code)
+}
+
 func (c CommandPath) PrintHelp() error {
 	prefix := []string{}
 	for _, cmd := range c {
@@ -302,17 +314,34 @@ func (c CommandPath) PrintHelp() error {
 	return ErrHelp
 }
 
+// Stage resolves the stage from the --stage flag, the SST_STAGE environment
+// variable, the active profile, or the personal stage, in that order. It
+// then loads the env files for it.
 func (c *Cli) Stage(cfgPath string) (string, error) {
-	stage := c.String("stage")
+	profileName, profile, err := project.LoadActiveProfile(cfgPath)
+	if err != nil {
+		return "", err
+	}
+	stage, source := c.String("stage"), "--stage"
 	if stage == "" {
-		stage = os.Getenv("SST_STAGE")
+		stage, source = c.shellEnv("SST_STAGE"), "SST_STAGE"
 		if stage == "" {
-			stage = project.LoadPersonalStage(cfgPath)
+			stage, source = project.EnvFileStage(cfgPath)
+		}
+		if stage == "" {
+			// from the env files of the directory the command is run in
+			stage, source = os.Getenv("SST_STAGE"), "SST_STAGE in .env"
+		}
+		if stage == "" && profile != nil && profile.Stage != "" {
+			stage, source = profile.Stage, "profile "+profileName
+		}
+		if stage == "" {
+			stage, source = project.LoadPersonalStage(cfgPath), ".sst/stage"
 			if stage == "" {
 				stage = guessStage()
 				if stage == "" {
 					if !term.IsTerminal(int(os.Stdout.Fd())) {
-						return "", util.NewReadableError(nil, "No stage specified. Pass it in with --stage or set the SST_STAGE environment variable. If this is a personal stage it can be set in `.sst/stage`.")
+						return "", util.NewReadableError(nil, "No stage specified. Pass it in with --stage or set the SST_STAGE environment variable. If this is a personal stage it can be set in `.sst/stage`, or with a profile using `sst profile use`.").WithCode("stage_missing")
 					}
 					err := huh.NewForm(
 						huh.NewGroup(
@@ -335,7 +364,9 @@ func (c *Cli) Stage(cfgPath string) (string, error) {
 			}
 		}
 	}
-	godotenv.Overload(filepath.Join(filepath.Dir(cfgPath), ".env."+stage))
+	if err := c.loadEnv(cfgPath, stage, source, profileName, profile); err != nil {
+		return "", err
+	}
 	return stage, nil
 }
 
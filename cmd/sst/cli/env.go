@@ -0,0 +1,74 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/joho/godotenv"
+	"github.com/sst/sst/v3/pkg/project"
+)
+
+// EnvReport describes where the stage and the environment variables of the
+// command come from, it's shown by `sst diagnostic`.
+type EnvReport struct {
+	Profile     string             `json:"profile,omitempty"`
+	Stage       string             `json:"stage"`
+	StageSource string             `json:"stageSource"`
+	Layers      []project.EnvLayer `json:"layers"`
+	// Sources is the layer that each variable that was set comes from.
+	Sources map[string]string `json:"sources"`
+}
+
+// EnvReport returns how the environment was loaded, or nil if the stage has
+// not been resolved yet.
+func (c *Cli) EnvReport() *EnvReport {
+	return c.envReport
+}
+
+// loadEnv sets the environment variables from the env files of the stage
+// and the profile, on top of the shell.
+func (c *Cli) loadEnv(cfgPath, stage, stageSource, profileName string, profile *project.Profile) error {
+	layers, err := project.EnvLayers(cfgPath, stage, profileName, profile)
+	if err != nil {
+		return err
+	}
+	shell := map[string]bool{}
+	for _, item := range c.env {
+		key, _, _ := strings.Cut(item, "=")
+		shell[key] = true
+	}
+	values, sources := project.ResolveEnv(layers, shell)
+	for key, value := range values {
+		os.Setenv(key, value)
+	}
+	c.envReport = &EnvReport{
+		Profile:     profileName,
+		Stage:       stage,
+		StageSource: stageSource,
+		Layers:      layers,
+		Sources:     sources,
+	}
+	return nil
+}
+
+// loadEnvFiles sets the variables from the .env and .env.local in dir that
+// aren't set in the shell, .env.local wins. They don't depend on the stage, so
+// they're loaded before any command runs. The env files of the stage are
+// loaded on top of them by loadEnv.
+func loadEnvFiles(dir string) {
+	for _, name := range []string{".env.local", ".env"} {
+		godotenv.Load(filepath.Join(dir, name))
+	}
+}
+
+// shellEnv returns a variable as it was set in the shell, before any env files
+// were loaded.
+func (c *Cli) shellEnv(key string) string {
+	for _, item := range c.env {
+		if name, value, ok := strings.Cut(item, "="); ok && name == key {
+			return value
+		}
+	}
+	return ""
+}
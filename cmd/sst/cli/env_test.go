@@ -0,0 +1,34 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadEnvFiles(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, ".env"), []byte("SST_TEST_FILE=env\nSST_TEST_LOCAL=env\nSST_TEST_SHELL=env\n"), 0644)
+	os.WriteFile(filepath.Join(dir, ".env.local"), []byte("SST_TEST_LOCAL=local\n"), 0644)
+	t.Setenv("SST_TEST_SHELL", "shell")
+	for _, key := range []string{"SST_TEST_FILE", "SST_TEST_LOCAL"} {
+		t.Setenv(key, "")
+		os.Unsetenv(key)
+	}
+
+	c := &Cli{env: os.Environ()}
+	loadEnvFiles(dir)
+	expected := map[string]string{
+		"SST_TEST_FILE":  "env",
+		"SST_TEST_LOCAL": "local",
+		"SST_TEST_SHELL": "shell",
+	}
+	for key, value := range expected {
+		if got := os.Getenv(key); got != value {
+			t.Errorf("%s = %q, want %q", key, got, value)
+		}
+	}
+	if c.shellEnv("SST_TEST_FILE") != "" || c.shellEnv("SST_TEST_SHELL") != "shell" {
+		t.Error("expected the env files to not count as the shell")
+	}
+}
@@ -0,0 +1,97 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sst/sst/v3/internal/util"
+)
+
+const (
+	OutputText = "text"
+	OutputJSON = "json"
+)
+
+// Result is the document a command writes to stdout with `--output json`.
+type Result struct {
+	Command string       `json:"command"`
+	OK      bool         `json:"ok"`
+	Result  interface{}  `json:"result,omitempty"`
+	Error   *ResultError `json:"error,omitempty"`
+}
+
+type ResultError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Detail  string `json:"detail,omitempty"`
+}
+
+var output struct {
+	sync.Mutex
+	result interface{}
+}
+
+func (c *Cli) validateOutput() error {
+	switch c.String("output") {
+	case "", OutputText, OutputJSON:
+		return nil
+	}
+	return util.NewReadableError(nil, fmt.Sprintf("Unknown output \"%s\", use text or json", c.String("output"))).WithCode("output_invalid")
+}
+
+// JSON is true when the result should be written as a JSON document instead
+// of text.
+func (c *Cli) JSON() bool {
+	return c.String("output") == OutputJSON
+}
+
+// SetResult sets the result document of the command, it's ignored in text
+// mode.
+func (c *Cli) SetResult(result interface{}) {
+	output.Lock()
+	defer output.Unlock()
+	output.result = result
+}
+
+// Progress writes an event to stderr as a line of JSON.
+func (c *Cli) Progress(kind string, data map[string]interface{}) {
+	if !c.JSON() {
+		return
+	}
+	event := map[string]interface{}{}
+	for key, value := range data {
+		event[key] = value
+	}
+	event["type"] = kind
+	event["time"] = time.Now().UTC().Format(time.RFC3339Nano)
+	bytes, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	output.Lock()
+	defer output.Unlock()
+	os.Stderr.Write(append(bytes, '\n'))
+}
+
+// PrintResult writes the result document to stdout, with the error if the
+// command failed.
+func (c *Cli) PrintResult(resultErr *ResultError) error {
+	output.Lock()
+	defer output.Unlock()
+	names := []string{}
+	for _, cmd := range c.path[1:] {
+		names = append(names, cmd.Name)
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(Result{
+		Command: strings.Join(names, " "),
+		OK:      resultErr == nil,
+		Result:  output.result,
+		Error:   resultErr,
+	})
+}
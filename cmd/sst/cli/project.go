@@ -1,6 +1,7 @@
 package cli
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
@@ -10,10 +11,10 @@ import (
 	"time"
 
 	"github.com/briandowns/spinner"
-	"github.com/joho/godotenv"
 	"github.com/sst/sst/v3/internal/util"
 	"github.com/sst/sst/v3/pkg/flag"
 	"github.com/sst/sst/v3/pkg/project"
+	"github.com/sst/sst/v3/pkg/trace"
 )
 
 var logFile = (func() *os.File {
@@ -33,17 +34,17 @@ func (c *Cli) Discover() (string, error) {
 	if cfgPath != "" {
 		abs, err := filepath.Abs(cfgPath)
 		if err != nil {
-			return "", util.NewReadableError(err, "Could not find "+cfgPath)
+			return "", util.NewReadableError(err, "Could not find "+cfgPath).WithCode("config_not_found")
 		}
 		if _, err := os.Stat(abs); os.IsNotExist(err) {
-			return "", util.NewReadableError(err, "Could not find "+abs)
+			return "", util.NewReadableError(err, "Could not find "+abs).WithCode("config_not_found")
 		}
 		return abs, nil
 	}
 
 	match, err := project.Discover()
 	if err != nil {
-		return "", util.NewReadableError(err, "Could not find sst.config.ts")
+		return "", util.NewReadableError(err, "Could not find sst.config.ts").WithCode("config_not_found")
 	}
 	return match, nil
 }
@@ -58,7 +59,11 @@ func (c *Cli) InitProject() (*project.Project, error) {
 
 	stage, err := c.Stage(cfgPath)
 	if err != nil {
-		return nil, util.NewReadableError(err, "Could not find stage")
+		var readable *util.ReadableError
+		if errors.As(err, &readable) {
+			return nil, err
+		}
+		return nil, util.NewReadableError(err, "Could not find stage").WithCode("stage_missing")
 	}
 
 	p, err := project.New(&project.ProjectConfig{
@@ -69,7 +74,6 @@ func (c *Cli) InitProject() (*project.Project, error) {
 	if err != nil {
 		return nil, err
 	}
-	godotenv.Load(filepath.Join(p.PathRoot(), ".env"))
 
 	if flag.SST_LOG == "" {
 		_, err = logFile.Seek(0, 0)
@@ -81,11 +85,11 @@ func (c *Cli) InitProject() (*project.Project, error) {
 		os.MkdirAll(logPath, 0755)
 		nextLogFile, err := os.Create(sstLog)
 		if err != nil {
-			return nil, util.NewReadableError(err, "Could not create log file")
+			return nil, util.NewReadableError(err, "Could not create log file").WithCode("log_file_failed")
 		}
 		_, err = io.Copy(nextLogFile, logFile)
 		if err != nil {
-			return nil, util.NewReadableError(err, "Could not copy log file")
+			return nil, util.NewReadableError(err, "Could not copy log file").WithCode("log_file_failed")
 		}
 		logFile.Close()
 		defer func() {
@@ -100,20 +104,41 @@ func (c *Cli) InitProject() (*project.Project, error) {
 
 	spin := spinner.New(spinner.CharSets[14], 100*time.Millisecond, spinner.WithWriterFile(os.Stderr))
 	spin.Color("cyan")
+	if c.JSON() {
+		spin.Disable()
+	}
 	defer spin.Stop()
 	if !p.CheckPlatform(c.version) {
 		spin.Suffix = "  Upgrading project..."
 		spin.Start()
+		c.Progress("upgrade", nil)
 		err := p.CopyPlatform(c.version)
 		if err != nil {
-			return nil, util.NewReadableError(err, "Could not copy platform code to project directory")
+			return nil, util.NewReadableError(err, "Could not copy platform code to project directory").WithCode("platform_copy_failed")
+		}
+	}
+
+	// with --frozen the lock has to match the config even when nothing needs
+	// to be installed
+	if c.Bool("frozen") {
+		if err := p.VerifyProviderLock(); err != nil {
+			return nil, err
 		}
 	}
 
 	if p.NeedsInstall() {
 		spin.Suffix = "  Installing providers..."
 		spin.Start()
-		err = p.Install()
+		c.Progress("install", nil)
+		span := trace.Start("install providers", map[string]interface{}{
+			"sst.frozen": c.Bool("frozen"),
+		})
+		if c.Bool("frozen") {
+			err = p.InstallFrozen()
+		} else {
+			err = p.Install()
+		}
+		span.End(err)
 		if err != nil {
 			return nil, err
 		}
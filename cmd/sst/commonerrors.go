@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sst/sst/v3/cmd/sst/cli"
+	"github.com/sst/sst/v3/cmd/sst/mosaic/ui"
+	"github.com/sst/sst/v3/pkg/project"
+)
+
+var CmdCommonErrors = &cli.Command{
+	Name: "common-errors",
+	Description: cli.Description{
+		Short: "Search the common errors",
+		Long: strings.Join([]string{
+			"Search the catalog of common errors and how to fix them. Pass in a word, a code, or",
+			"the error message you got.",
+			"",
+			"```bash frame=\"none\"",
+			"sst common-errors TooManyCacheBehaviors",
+			"```",
+			"",
+			"The errors of a deploy are matched against this catalog, and the ones that match",
+			"show a hint and a link with the error.",
+			"",
+			"Your app can add its own entries with a `sst.errors.json` next to your `sst.config.ts`.",
+			"The `pattern` is a regular expression that's matched against the error message.",
+			"",
+			"```json title=\"sst.errors.json\"",
+			"[",
+			"  {",
+			"    \"code\": \"LegacyBucketName\",",
+			"    \"message\": \"BucketAlreadyExists: The requested bucket name is not available\",",
+			"    \"pattern\": \"BucketAlreadyExists\",",
+			"    \"short\": [\"Use the generated bucket name, see the runbook.\"],",
+			"    \"link\": \"https://wiki.example.com/runbooks/buckets\"",
+			"  }",
+			"]",
+			"```",
+			"",
+			"These are checked before the built-in ones.",
+		}, "\n"),
+	},
+	Args: []cli.Argument{
+		{
+			Name: "query",
+			Description: cli.Description{
+				Short: "What to search for",
+				Long:  "A word, a code, or an error message to search for. Lists all the errors if not set.",
+			},
+		},
+	},
+	Flags: []cli.Flag{
+		{
+			Name: "json",
+			Type: "bool",
+			Description: cli.Description{
+				Short: "Output as JSON",
+				Long:  "Output the built-in errors as JSON to stdout, this is used to generate the docs.",
+			},
+		},
+	},
+	Run: func(c *cli.Cli) error {
+		if c.Bool("json") {
+			data, err := json.MarshalIndent(project.CommonErrors, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(data))
+			return nil
+		}
+
+		catalog := project.CommonErrors
+		if cfgPath, err := c.Discover(); err == nil {
+			catalog, err = project.LoadCommonErrors(filepath.Dir(cfgPath))
+			if err != nil {
+				return err
+			}
+		}
+		matches := catalog
+		if query := c.Positional(0); query != "" {
+			matches = project.SearchCommonErrors(catalog, query)
+		}
+
+		if c.JSON() {
+			c.SetResult(map[string]interface{}{"errors": matches})
+			return nil
+		}
+		if len(matches) == 0 {
+			fmt.Fprintln(os.Stderr, ui.TEXT_DIM.Render("No common errors found"))
+			return nil
+		}
+		for i, item := range matches {
+			if i > 0 {
+				fmt.Println()
+			}
+			fmt.Println(ui.TEXT_NORMAL_BOLD.Render(item.Code))
+			fmt.Println(ui.TEXT_DIM.Render(item.Message))
+			for _, line := range item.Help() {
+				fmt.Println(ui.TEXT_NORMAL.Render(line))
+			}
+		}
+		return nil
+	},
+}
@@ -1,14 +1,21 @@
 package main
 
 import (
+	"fmt"
+	"os"
 	"strings"
+	"time"
+
+	"github.com/charmbracelet/huh"
 
 	"github.com/sst/sst/v3/cmd/sst/cli"
 	"github.com/sst/sst/v3/cmd/sst/mosaic/ui"
+	"github.com/sst/sst/v3/internal/util"
 	"github.com/sst/sst/v3/pkg/bus"
 	"github.com/sst/sst/v3/pkg/project"
 	"github.com/sst/sst/v3/pkg/server"
 	"golang.org/x/sync/errgroup"
+	"golang.org/x/term"
 )
 
 var CmdDeploy = &cli.Command{
@@ -77,6 +84,81 @@ var CmdDeploy = &cli.Command{
 			"to be able to deploy as many resources as possible and then come back and",
 			"fix the errors.",
 			"",
+			"If your config sets an `approval` for the stage, the changes are previewed first.",
+			"Changes like deleting a database need to be approved before they are deployed.",
+			"",
+			"```bash frame=\"none\"",
+			"sst deploy --stage production --approve 3f2a9c1b7d4e",
+			"```",
+			"",
+			"In a terminal, you are asked to approve them. Otherwise, it fails and prints the token",
+			"to approve this exact set of changes with. `sst diff` prints the same token.",
+			"",
+			"To deploy several stages of your app, like one for each region, pass them in",
+			"with `--stages`.",
+			"",
+			"```bash frame=\"none\"",
+			"sst deploy --stages us-east,eu-west,ap-south",
+			"```",
+			"",
+			"Or deploy every app in a monorepo with `--workspace`. It finds each `sst.config.ts`",
+			"in the current directory and its subdirectories, skipping `node_modules` and",
+			"hidden directories. It can be combined with `--stage` or `--stages`.",
+			"",
+			"```bash frame=\"none\"",
+			"sst deploy --workspace --stage production",
+			"```",
+			"",
+			"These run 2 deploys at a time, use `--concurrency` to change this. The stages of an",
+			"app are deployed one at a time, since they share the `.sst` directory, so the",
+			"concurrency applies to different apps. At the end, it prints a summary and fails",
+			"if any of the deploys did.",
+			"",
+			"If an app uses the outputs of another one, list it in a `sst.workspace.json` in the",
+			"same directory. The apps are keyed by their path.",
+			"",
+			"```json title=\"sst.workspace.json\"",
+			"{",
+			"  \"concurrency\": 4,",
+			"  \"apps\": {",
+			"    \"services/api\": { \"dependsOn\": [\"services/database\"] }",
+			"  }",
+			"}",
+			"```",
+			"",
+			"An app is deployed after the ones it depends on and is skipped if they fail. Their",
+			"outputs are passed in as JSON, keyed by path, in the `SST_WORKSPACE_OUTPUTS`",
+			"environment variable. Secret outputs are left out.",
+			"",
+			"```ts title=\"services/api/sst.config.ts\"",
+			"const outputs = JSON.parse(process.env.SST_WORKSPACE_OUTPUTS ?? \"{}\");",
+			"const url = outputs[\"services/database\"]?.url;",
+			"```",
+			"",
+			"Preview stages, like the ones deployed for each pull request, can be set to expire",
+			"with `--ttl`. Expired stages are removed with `sst stage gc`.",
+			"",
+			"```bash frame=\"none\"",
+			"sst deploy --stage pr-123 --ttl 3d",
+			"```",
+			"",
+			"If a deploy fails or is cancelled, you can `--resume` it once you've fixed the error.",
+			"",
+			"```bash frame=\"none\"",
+			"sst deploy --resume",
+			"```",
+			"",
+			"This only deploys the resources that failed or did not finish in the last deploy,",
+			"along with the resources that depend on them. It lists the resources that were",
+			"already deployed and skips them, so the sites that were built aren't built again",
+			"and the files that were uploaded aren't uploaded again. Run it from the same",
+			"checkout as the deploy that failed, since it uses the output of those builds.",
+			"",
+			"If the changes were previewed for an `approval` policy, the ones the deploy",
+			"didn't get to are resumed as well. Otherwise resources that hadn't started when",
+			"the deploy stopped aren't deployed by a resume, so run `sst deploy` afterwards to",
+			"deploy everything else.",
+			"",
 			"The `sst dev` command deploys your resources a little differently. It skips",
 			"deploying resources that are going to be run locally. Sometimes you want to",
 			"deploy a personal stage without starting `sst dev`.",
@@ -85,6 +167,28 @@ var CmdDeploy = &cli.Command{
 			"sst deploy --dev",
 			"```",
 			"The `--dev` flag will deploy your resources as if you were running `sst dev`.",
+			"",
+			"In CI, use `--frozen` to deploy with the exact providers recorded in the provider lock.",
+			"",
+			"```bash frame=\"none\"",
+			"sst deploy --frozen",
+			"```",
+			"",
+			"This fails if the lock is missing or does not match your `sst.config.ts`, instead of",
+			"resolving new provider versions.",
+			"",
+			"To see where a deploy spends its time, it can be exported as an OpenTelemetry trace.",
+			"There's a span for the deploy, building your config, installing providers, building",
+			"sites, and each component and resource.",
+			"",
+			"```bash frame=\"none\"",
+			"SST_TRACE_ENDPOINT=http://localhost:4318 sst deploy",
+			"```",
+			"",
+			"This is sent over OTLP/HTTP to any collector. Use `SST_TRACE_HEADERS` to pass in",
+			"headers like `Authorization=Bearer <token>`. Or set `SST_TRACE_FILE` to a path to",
+			"append the traces to a file instead, as OTLP JSON. These are separate from telemetry",
+			"and are never sent anywhere unless set.",
 		}, "\n"),
 	},
 	Flags: []cli.Flag{
@@ -111,6 +215,14 @@ var CmdDeploy = &cli.Command{
 				Long:  "Continue on error and try to deploy as many resources as possible.",
 			},
 		},
+		{
+			Name: "resume",
+			Type: "bool",
+			Description: cli.Description{
+				Short: "Resume the last deploy",
+				Long:  "Only deploy the resources that failed or did not finish in the last deploy, and the ones that depend on them.",
+			},
+		},
 		{
 			Name: "dev",
 			Type: "bool",
@@ -119,6 +231,46 @@ var CmdDeploy = &cli.Command{
 				Long:  "Deploy resources like `sst dev` would.",
 			},
 		},
+		{
+			Name: "stages",
+			Type: "string",
+			Description: cli.Description{
+				Short: "Deploy several stages",
+				Long:  "Deploy each of the given comma-separated stages.",
+			},
+		},
+		{
+			Name: "workspace",
+			Type: "bool",
+			Description: cli.Description{
+				Short: "Deploy every app in the workspace",
+				Long:  "Deploy every app with a `sst.config.ts` in the current directory and its subdirectories.",
+			},
+		},
+		{
+			Name: "concurrency",
+			Type: "string",
+			Description: cli.Description{
+				Short: "Deploys to run at a time",
+				Long:  "The number of apps to deploy at the same time with `--workspace`. The stages of an app are deployed one at a time. Defaults to 2.",
+			},
+		},
+		{
+			Name: "ttl",
+			Type: "string",
+			Description: cli.Description{
+				Short: "Expire the stage after this long",
+				Long:  "Set the stage to expire this long after the deploy, like `3d` or `12h`. Expired stages are removed by `sst stage gc`.",
+			},
+		},
+		{
+			Name: "approve",
+			Type: "string",
+			Description: cli.Description{
+				Short: "Approve the changes",
+				Long:  "Approve changes that need approval because of the `approval` in your config, with the token that's printed for them. It can't be used with `--stages` or `--workspace`.",
+			},
+		},
 		{
 			Name: "policy",
 			Type: "string",
@@ -127,6 +279,14 @@ var CmdDeploy = &cli.Command{
 				Long:  "Run policy pack validation against the preview changes.",
 			},
 		},
+		{
+			Name: "frozen",
+			Type: "bool",
+			Description: cli.Description{
+				Short: "Fail if the provider lock is out of date",
+				Long:  "Install providers exactly as recorded in the provider lock and fail if it is missing or out of date.",
+			},
+		},
 	},
 	Examples: []cli.Example{
 		{
@@ -135,6 +295,18 @@ var CmdDeploy = &cli.Command{
 				Short: "Deploy to production",
 			},
 		},
+		{
+			Content: "sst deploy --stage production --resume",
+			Description: cli.Description{
+				Short: "Resume a deploy to production that failed",
+			},
+		},
+		{
+			Content: "sst deploy --stage pr-123 --ttl 3d",
+			Description: cli.Description{
+				Short: "Deploy a preview stage that expires in 3 days",
+			},
+		},
 		{
 			Content: "sst deploy --stage production --policy ./policies/production",
 			Description: cli.Description{
@@ -143,6 +315,26 @@ var CmdDeploy = &cli.Command{
 		},
 	},
 	Run: func(c *cli.Cli) error {
+		if c.String("stages") != "" || c.Bool("workspace") {
+			if c.String("target") != "" || c.String("exclude") != "" || c.Bool("resume") {
+				return util.NewReadableError(nil, "The --target, --exclude, and --resume flags can't be used with --stages or --workspace").WithCode("deploy_many_with_target")
+			}
+			// the token approves the changes of a single stage
+			if c.String("approve") != "" {
+				return util.NewReadableError(nil, "The --approve flag approves the changes of one stage, it can't be used with --stages or --workspace. Deploy the stage on its own to approve its changes.").WithCode("deploy_many_with_approve")
+			}
+			return deployMany(c)
+		}
+
+		ttl := time.Duration(0)
+		if c.String("ttl") != "" {
+			var err error
+			ttl, err = parseAge(c.String("ttl"))
+			if err != nil {
+				return err
+			}
+		}
+
 		p, err := c.InitProject()
 		if err != nil {
 			return err
@@ -159,11 +351,15 @@ var CmdDeploy = &cli.Command{
 			exclude = strings.Split(c.String("exclude"), ",")
 		}
 
+		if c.Bool("resume") && (len(target) > 0 || len(exclude) > 0) {
+			return util.NewReadableError(nil, "The --resume flag picks the resources to deploy, it can't be used with --target or --exclude").WithCode("resume_with_target")
+		}
+
 		var wg errgroup.Group
 		defer wg.Wait()
 		out := make(chan interface{})
 		defer close(out)
-		ui := ui.New(c.Context)
+		ui := ui.New(c.Context, stackUIOptions(c)...)
 		s, err := server.New()
 		if err != nil {
 			return err
@@ -176,13 +372,17 @@ var CmdDeploy = &cli.Command{
 		defer close(events)
 		wg.Go(func() error {
 			for evt := range events {
+				if c.JSON() {
+					stackProgress(c, evt)
+					continue
+				}
 				ui.Event(evt)
 			}
 			return nil
 		})
 		defer ui.Destroy()
 		defer c.Cancel()
-		err = p.Run(c.Context, &project.StackInput{
+		input := &project.StackInput{
 			Command:    "deploy",
 			Target:     target,
 			Exclude:    exclude,
@@ -191,10 +391,44 @@ var CmdDeploy = &cli.Command{
 			Verbose:    c.Bool("verbose"),
 			Continue:   c.Bool("continue"),
 			PolicyPath: c.String("policy"),
-		})
+			Resume:     c.Bool("resume"),
+			Approve:    c.String("approve"),
+			TTL:        ttl,
+			ApproveChanges: func(violations []project.ChangeViolation, token string) bool {
+				return approveChanges(c, ui, violations)
+			},
+		}
+		err = p.Run(c.Context, input)
 		if err != nil {
 			return err
 		}
 		return nil
 	},
 }
+
+// approveChanges asks to approve the changes of a deploy that were not
+// approved, when it's run in a terminal.
+func approveChanges(c *cli.Cli, u *ui.UI, violations []project.ChangeViolation) bool {
+	if c.JSON() {
+		return false
+	}
+	if !term.IsTerminal(int(os.Stdin.Fd())) || !term.IsTerminal(int(os.Stdout.Fd())) {
+		return false
+	}
+	resume := u.Pause()
+	defer resume()
+	messages := make([]string, 0, len(violations))
+	for _, violation := range violations {
+		messages = append(messages, violation.Message)
+	}
+	approved := false
+	err := huh.NewForm(
+		huh.NewGroup(
+			huh.NewConfirm().
+				Title(fmt.Sprintf(" Deploy the %d changes that need approval?", len(violations))).
+				Description(strings.Join(messages, "\n")).
+				Value(&approved),
+		),
+	).WithTheme(huh.ThemeCatppuccin()).Run()
+	return err == nil && approved
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/sst/sst/v3/cmd/sst/mosaic/ui"
 	"github.com/sst/sst/v3/pkg/id"
 	"github.com/sst/sst/v3/pkg/project"
+	"github.com/sst/sst/v3/pkg/project/provider"
 )
 
 var CmdDiagnostic = &cli.Command{
@@ -22,6 +23,10 @@ var CmdDiagnostic = &cli.Command{
 			"Generates a diagnostic report based on the last command that was run.",
 			"",
 			"This takes the state of your app, its log files, and generates a zip file in the `.sst/` directory. This is for debugging purposes.",
+			"",
+			"It also lists the homes and providers this version of the CLI supports, and where the",
+			"stage and the environment variables come from. See `sst profile` for the order they",
+			"are picked in.",
 		}, "\n"),
 	},
 	Run: func(c *cli.Cli) error {
@@ -32,7 +37,15 @@ var CmdDiagnostic = &cli.Command{
 		workingDir := project.ResolveWorkingDir(cfg)
 		logDir := project.ResolveLogDir(cfg)
 		logFiles, err := os.ReadDir(logDir)
-		fmt.Println(ui.TEXT_DIM.Render("Generating diagnostic report from last run..."))
+		printLine := func(line string) {
+			if !c.JSON() {
+				fmt.Println(line)
+			}
+		}
+		printLine(ui.TEXT_DIM.Render("Homes:     " + strings.Join(provider.Homes(), ", ")))
+		printLine(ui.TEXT_DIM.Render("Providers: " + strings.Join(provider.Providers(), ", ")))
+		printLine("")
+		printLine(ui.TEXT_DIM.Render("Generating diagnostic report from last run..."))
 		zipFile, err := os.Create(filepath.Join(workingDir, "report.zip"))
 		if err != nil {
 			return err
@@ -42,7 +55,7 @@ var CmdDiagnostic = &cli.Command{
 		defer archive.Close()
 
 		addFile := func(path string, name string) error {
-			fmt.Println(ui.TEXT_DIM.Render("-  " + name))
+			printLine(ui.TEXT_DIM.Render("-  " + name))
 			fileToZip, err := os.Open(path)
 			if err != nil {
 				return err
@@ -85,6 +98,9 @@ var CmdDiagnostic = &cli.Command{
 		if err != nil {
 			return err
 		}
+		if !c.JSON() {
+			printEnvReport(c.EnvReport())
+		}
 		workdir, err := p.NewWorkdir(id.Descending())
 		if err != nil {
 			return err
@@ -99,8 +115,56 @@ var CmdDiagnostic = &cli.Command{
 		if err != nil {
 			return err
 		}
+		if c.JSON() {
+			c.SetResult(map[string]interface{}{
+				"report":    zipFile.Name(),
+				"homes":     provider.Homes(),
+				"providers": provider.Providers(),
+				"env":       c.EnvReport(),
+			})
+			return nil
+		}
 		fmt.Println()
 		ui.Success("Report generated: " + zipFile.Name())
 		return nil
 	},
 }
+
+// printEnvReport lists where the stage and each environment variable come
+// from, the layers with the highest precedence first.
+func printEnvReport(report *cli.EnvReport) {
+	if report == nil {
+		return
+	}
+	fmt.Println()
+	profile := "none"
+	if report.Profile != "" {
+		profile = report.Profile
+	}
+	fmt.Println(ui.TEXT_DIM.Render("Profile:   " + profile))
+	fmt.Println(ui.TEXT_DIM.Render("Stage:     " + report.Stage + " (from " + report.StageSource + ")"))
+	fmt.Println(ui.TEXT_DIM.Render("Env:       highest precedence first"))
+	printLayer := func(layer project.EnvLayer) {
+		keys := []string{}
+		for _, key := range layer.Keys {
+			if report.Sources[key] == layer.Name {
+				keys = append(keys, key)
+			} else {
+				keys = append(keys, key+" (replaced)")
+			}
+		}
+		fmt.Println(ui.TEXT_DIM.Render("-  " + layer.Name + ": " + strings.Join(keys, ", ")))
+	}
+	shell := false
+	for i := len(report.Layers) - 1; i >= 0; i-- {
+		layer := report.Layers[i]
+		if !layer.Override && !shell {
+			fmt.Println(ui.TEXT_DIM.Render("-  shell"))
+			shell = true
+		}
+		printLayer(layer)
+	}
+	if !shell {
+		fmt.Println(ui.TEXT_DIM.Render("-  shell"))
+	}
+}
@@ -10,7 +10,9 @@ import (
 	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
 	"github.com/sst/sst/v3/cmd/sst/cli"
 	"github.com/sst/sst/v3/cmd/sst/mosaic/ui"
+	"github.com/sst/sst/v3/internal/util"
 	"github.com/sst/sst/v3/pkg/bus"
+	"github.com/sst/sst/v3/pkg/cost"
 	"github.com/sst/sst/v3/pkg/project"
 	"github.com/sst/sst/v3/pkg/server"
 	"github.com/yalp/jsonpath"
@@ -55,6 +57,17 @@ var CmdDiff = &cli.Command{
 			"```",
 			"",
 			"This is useful because in dev mode, you app is deployed a little differently.",
+			"",
+			"To get a rough idea of how the changes affect your bill, use the `--cost` flag.",
+			"",
+			"```bash frame=\"none\"",
+			"sst diff --cost",
+			"```",
+			"",
+			"This estimates the monthly cost of the resources that are being created, replaced,",
+			"or deleted using a local price table and some assumed usage. You can override the",
+			"bundled table by placing a `prices.json` in the SST config directory, or by setting",
+			"the `SST_COST_TABLE` environment variable to its path.",
 		}, "\n"),
 	},
 	Flags: []cli.Flag{
@@ -99,6 +112,14 @@ var CmdDiff = &cli.Command{
 				Long:  "Output the diff result as JSON to stdout. Useful for CI pipelines and scripting.",
 			},
 		},
+		{
+			Name: "cost",
+			Type: "bool",
+			Description: cli.Description{
+				Short: "Estimate the cost of the changes",
+				Long:  "Estimate the monthly cost delta of the resources that are being created, replaced, or deleted. With `--json`, the changes are printed as `{\"changes\": [...], \"cost\": {...}}`.",
+			},
+		},
 	},
 	Examples: []cli.Example{
 		{
@@ -121,7 +142,7 @@ var CmdDiff = &cli.Command{
 		},
 	},
 	Run: func(c *cli.Cli) error {
-		jsonOutput := c.Bool("json")
+		jsonOutput := c.Bool("json") || c.JSON()
 
 		p, err := c.InitProject()
 		if err != nil {
@@ -141,6 +162,7 @@ var CmdDiff = &cli.Command{
 
 		var wg errgroup.Group
 		outputs := []*apitype.ResOutputsEvent{}
+		var approval *project.ChangePolicyEvent
 		uiOptions := []ui.Option{}
 		if jsonOutput {
 			// Keep stdout machine-readable when attached to a TTY.
@@ -159,12 +181,16 @@ var CmdDiff = &cli.Command{
 		events := bus.SubscribeAll()
 		wg.Go(func() error {
 			for evt := range events {
-				if !jsonOutput {
+				if c.JSON() {
+					stackProgress(c, evt)
+				} else if !jsonOutput {
 					u.Event(evt)
 				}
 				switch evt := evt.(type) {
 				case *apitype.ResOutputsEvent:
 					outputs = append(outputs, evt)
+				case *project.ChangePolicyEvent:
+					approval = evt
 				}
 			}
 			return nil
@@ -186,8 +212,33 @@ var CmdDiff = &cli.Command{
 			err = waitErr
 		}
 
+		var summary *cost.Summary
+		if c.Bool("cost") && err == nil {
+			var costErr error
+			summary, costErr = estimateDiffCost(outputs)
+			if costErr != nil {
+				return costErr
+			}
+		}
+
+		if c.JSON() {
+			result := map[string]interface{}{
+				"changes": diffChanges(outputs),
+			}
+			if summary != nil {
+				result["cost"] = summary
+			}
+			if approval != nil {
+				result["approval"] = map[string]interface{}{
+					"violations": approval.Violations,
+					"token":      approval.Token,
+				}
+			}
+			c.SetResult(result)
+			return err
+		}
 		if jsonOutput {
-			if jsonErr := renderDiffJSON(outputs); jsonErr != nil {
+			if jsonErr := renderDiffJSON(outputs, summary); jsonErr != nil {
 				return jsonErr
 			}
 			return err
@@ -195,10 +246,29 @@ var CmdDiff = &cli.Command{
 		if err != nil {
 			return err
 		}
-		return renderDiffText(outputs, u)
+		err = renderDiffText(outputs, u)
+		if err != nil {
+			return err
+		}
+		if summary != nil {
+			renderDiffCost(summary, u)
+		}
+		return nil
 	},
 }
 
+func estimateDiffCost(outputs []*apitype.ResOutputsEvent) (*cost.Summary, error) {
+	table, err := cost.LoadTable()
+	if err != nil {
+		return nil, util.NewReadableError(err, "Could not load price table from "+cost.TablePath())
+	}
+	steps := make([]apitype.StepEventMetadata, 0, len(outputs))
+	for _, output := range outputs {
+		steps = append(steps, output.Metadata)
+	}
+	return cost.Estimate(table, steps), nil
+}
+
 func textDiffIcon(op apitype.OpType) string {
 	switch op {
 	case apitype.OpImport, apitype.OpReplace, apitype.OpCreate:
@@ -212,7 +282,7 @@ func textDiffIcon(op apitype.OpType) string {
 	}
 }
 
-func renderDiffJSON(outputs []*apitype.ResOutputsEvent) error {
+func diffChanges(outputs []*apitype.ResOutputsEvent) []apitype.StepEventMetadata {
 	filtered := make([]apitype.StepEventMetadata, 0, len(outputs))
 	for _, output := range outputs {
 		if output.Metadata.Op == apitype.OpSame {
@@ -220,9 +290,21 @@ func renderDiffJSON(outputs []*apitype.ResOutputsEvent) error {
 		}
 		filtered = append(filtered, output.Metadata)
 	}
+	return filtered
+}
+
+// renderDiffJSON prints the changes, or an object with the changes and their
+// cost when it was estimated.
+func renderDiffJSON(outputs []*apitype.ResOutputsEvent, summary *cost.Summary) error {
 	encoder := json.NewEncoder(os.Stdout)
 	encoder.SetIndent("", "  ")
-	return encoder.Encode(filtered)
+	if summary != nil {
+		return encoder.Encode(map[string]interface{}{
+			"changes": diffChanges(outputs),
+			"cost":    summary,
+		})
+	}
+	return encoder.Encode(diffChanges(outputs))
 }
 
 func renderDiffText(outputs []*apitype.ResOutputsEvent, u *ui.UI) error {
@@ -300,3 +382,31 @@ func renderDiffText(outputs []*apitype.ResOutputsEvent, u *ui.UI) error {
 	}
 	return nil
 }
+
+func formatCost(currency string, amount float64) string {
+	sign := "+"
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+	return fmt.Sprintf("%s%.2f %s/mo", sign, amount, currency)
+}
+
+func renderDiffCost(summary *cost.Summary, u *ui.UI) {
+	fmt.Println(
+		ui.TEXT_HIGHLIGHT_BOLD.Render("➜"),
+		ui.TEXT_NORMAL_BOLD.Render(" Estimated cost"),
+	)
+	for _, delta := range summary.Deltas {
+		style := ui.TEXT_SUCCESS
+		if delta.Monthly() > 0 {
+			style = ui.TEXT_WARNING
+		}
+		fmt.Println("  ", ui.TEXT_NORMAL.Render(u.FormatURN(delta.URN)), style.Render(formatCost(summary.Currency, delta.Monthly())))
+	}
+	if len(summary.Unknown) > 0 {
+		fmt.Println("  ", ui.TEXT_DIM.Render(fmt.Sprintf("%d changed resources could not be estimated", len(summary.Unknown))))
+	}
+	fmt.Println("  ", ui.TEXT_NORMAL_BOLD.Render("Total"), ui.TEXT_NORMAL_BOLD.Render(formatCost(summary.Currency, summary.Total)))
+	fmt.Println()
+}
@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/sst/sst/v3/pkg/cost"
 )
 
 func captureStdout(t *testing.T, fn func()) string {
@@ -31,7 +32,7 @@ func captureStdout(t *testing.T, fn func()) string {
 
 func TestRenderDiffJSON_NoChanges(t *testing.T) {
 	out := captureStdout(t, func() {
-		if err := renderDiffJSON(nil); err != nil {
+		if err := renderDiffJSON(nil, nil); err != nil {
 			t.Fatal(err)
 		}
 	})
@@ -88,7 +89,7 @@ func TestRenderDiffJSON_WithChanges(t *testing.T) {
 	}
 
 	out := captureStdout(t, func() {
-		if err := renderDiffJSON(outputs); err != nil {
+		if err := renderDiffJSON(outputs, nil); err != nil {
 			t.Fatal(err)
 		}
 	})
@@ -133,3 +134,26 @@ func TestRenderDiffJSON_WithChanges(t *testing.T) {
 		t.Errorf("expected op 'create-replacement', got %q", replacement.Op)
 	}
 }
+
+func TestRenderDiffJSON_WithCost(t *testing.T) {
+	summary := &cost.Summary{Currency: "USD", Deltas: []cost.Delta{}, Total: 12.5, Unknown: []string{}}
+	out := captureStdout(t, func() {
+		if err := renderDiffJSON(nil, summary); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	var result struct {
+		Changes []apitype.StepEventMetadata `json:"changes"`
+		Cost    *cost.Summary               `json:"cost"`
+	}
+	if err := json.Unmarshal([]byte(out), &result); err != nil {
+		t.Fatalf("invalid JSON: %v\noutput: %s", err, out)
+	}
+	if result.Changes == nil || len(result.Changes) != 0 {
+		t.Fatalf("expected empty changes, got %v", result.Changes)
+	}
+	if result.Cost == nil || result.Cost.Total != 12.5 {
+		t.Fatalf("expected the cost, got %v", result.Cost)
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/sst/sst/v3/cmd/sst/cli"
+	"github.com/sst/sst/v3/internal/util"
+	"github.com/sst/sst/v3/pkg/state"
+)
+
+var CmdGraph = &cli.Command{
+	Name: "graph",
+	Description: cli.Description{
+		Short: "Print the resource graph",
+		Long: strings.Join([]string{
+			"Prints the components and resources of your app, how they are nested, and what they",
+			"depend on. It uses the last deploy of the stage, or `sst dev` if it's running.",
+			"",
+			"```bash frame=\"none\"",
+			"sst graph > graph.dot",
+			"```",
+			"",
+			"By default this is in the [DOT](https://graphviz.org/doc/info/lang.html) format that",
+			"Graphviz can render. Use `--format mermaid` to get a Mermaid flowchart you can paste in",
+			"a Markdown file, or `--format json` for the nodes and edges.",
+			"",
+			"```bash frame=\"none\"",
+			"sst graph | dot -Tsvg > graph.svg",
+			"sst graph --format mermaid",
+			"```",
+			"",
+			"Nesting is shown with dashed lines and dependencies with arrows, labeled with the",
+			"properties that use them.",
+			"",
+			"The graph can be limited to a component and everything in it, or to resources of a",
+			"type. Types can use globs.",
+			"",
+			"```bash frame=\"none\"",
+			"sst graph --component MyApi",
+			"sst graph --type \"aws:lambda*\"",
+			"```",
+			"",
+			"To see the blast radius of a deploy with `--target`, pass in the same target here. The",
+			"target and everything that depends on it is highlighted.",
+			"",
+			"```bash frame=\"none\"",
+			"sst graph --target MyBucket",
+			"```",
+		}, "\n"),
+	},
+	Flags: []cli.Flag{
+		{
+			Name: "format",
+			Type: "string",
+			Description: cli.Description{
+				Short: "dot, mermaid, or json",
+				Long:  "The format to print the graph in. One of `dot`, `mermaid`, or `json`. Defaults to `dot`.",
+			},
+		},
+		{
+			Name: "component",
+			Type: "string",
+			Description: cli.Description{
+				Short: "Only show a component",
+				Long:  "Only show the given components and the resources in them. Separate multiple with a comma.",
+			},
+		},
+		{
+			Name: "type",
+			Type: "string",
+			Description: cli.Description{
+				Short: "Only show a type",
+				Long:  "Only show resources of the given types, like `sst:aws:Function` or `aws:s3*`. Separate multiple with a comma.",
+			},
+		},
+		{
+			Name: "target",
+			Type: "string",
+			Description: cli.Description{
+				Short: "Highlight what a target affects",
+				Long:  "Highlight the given components and everything that depends on them. Separate multiple with a comma.",
+			},
+		},
+	},
+	Examples: []cli.Example{
+		{
+			Content: "sst graph --format mermaid --target MyBucket",
+			Description: cli.Description{
+				Short: "See what a deploy of MyBucket affects",
+			},
+		},
+	},
+	Run: func(c *cli.Cli) error {
+		format := c.String("format")
+		if format == "" {
+			format = "dot"
+		}
+		if format != "dot" && format != "mermaid" && format != "json" {
+			return util.NewReadableError(nil, fmt.Sprintf("Unknown format \"%s\", use dot, mermaid, or json", format))
+		}
+		complete, err := readCompleted(c)
+		if err != nil {
+			return err
+		}
+		graph, err := state.NewGraph(complete.Resources, state.GraphOptions{
+			Components: splitFlag(c.String("component")),
+			Types:      splitFlag(c.String("type")),
+			Targets:    splitFlag(c.String("target")),
+		})
+		if err != nil {
+			return util.NewReadableError(err, err.Error())
+		}
+		if c.JSON() {
+			c.SetResult(graph)
+			return nil
+		}
+		switch format {
+		case "json":
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(graph)
+		case "mermaid":
+			fmt.Print(graph.Mermaid())
+		default:
+			fmt.Print(graph.Dot())
+		}
+		return nil
+	},
+}
+
+func splitFlag(value string) []string {
+	if value == "" {
+		return nil
+	}
+	result := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
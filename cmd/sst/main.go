@@ -9,6 +9,7 @@ import (
 	"os/signal"
 	"os/user"
 	"path/filepath"
+	"sort"
 	"strings"
 	"syscall"
 	"time"
@@ -52,8 +53,16 @@ func main() {
 	telemetry.Track("cli.start", map[string]interface{}{
 		"args": os.Args[1:],
 	})
-	err := run()
+	c, err := run()
+	if c != nil && c.JSON() && err != cli.ErrHelp {
+		printResult(c, err)
+	}
+	if exit, ok := err.(*cli.ExitError); ok {
+		telemetry.Close()
+		os.Exit(exit.Code)
+	}
 	if err != nil {
+		code := errors.Code(err)
 		err := errors.Transform(err)
 		errorMessage := err.Error()
 		truncated := errorMessage
@@ -62,8 +71,11 @@ func main() {
 		}
 		telemetry.Track("cli.error", map[string]interface{}{
 			"error": truncated,
+			"code":  code,
 		})
-		if readableErr, ok := err.(*util.ReadableError); ok {
+		if c != nil && c.JSON() {
+			slog.Error("exited with error", "err", err)
+		} else if readableErr, ok := err.(*util.ReadableError); ok {
 			slog.Error("exited with error", "err", readableErr.Unwrap())
 			msg := readableErr.Error()
 			if msg != "" {
@@ -86,7 +98,7 @@ func main() {
 	telemetry.Track("cli.success", map[string]interface{}{})
 }
 
-func run() error {
+func run() (*cli.Cli, error) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 	interruptChannel := make(chan os.Signal, 1)
@@ -98,15 +110,18 @@ func run() error {
 	}()
 	c, err := cli.New(ctx, cancel, root, version)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	_, err = user.Current()
 	if err != nil {
-		return err
+		return c, err
 	}
 
 	if !flag.SST_SKIP_DEPENDENCY_CHECK {
 		spin := spinner.New(spinner.CharSets[14], 100*time.Millisecond)
+		if c.JSON() {
+			spin.Disable()
+		}
 		spin.Color("cyan")
 		spin.Suffix = "  Download dependencies..."
 		if global.NeedsPulumi() {
@@ -115,7 +130,7 @@ func run() error {
 			err := global.InstallPulumi(ctx)
 			if err != nil {
 				spin.Stop()
-				return util.NewHintedError(err, "Could not install pulumi")
+				return c, util.NewHintedError(err, "Could not install pulumi")
 			}
 		}
 		if global.NeedsBun() {
@@ -124,12 +139,35 @@ func run() error {
 			err := global.InstallBun(ctx)
 			if err != nil {
 				spin.Stop()
-				return util.NewHintedError(err, "Could not install bun")
+				return c, util.NewHintedError(err, "Could not install bun")
 			}
 		}
 		spin.Stop()
 	}
-	return c.Run()
+	return c, c.Run()
+}
+
+// printResult writes the --output json document, with the code and message
+// of the error if there is one.
+func printResult(c *cli.Cli, err error) {
+	if err == nil {
+		c.PrintResult(nil)
+		return
+	}
+	result := &cli.ResultError{
+		Code:    errors.Code(err),
+		Message: err.Error(),
+	}
+	transformed := errors.Transform(err)
+	if readableErr, ok := transformed.(*util.ReadableError); ok {
+		if msg := readableErr.Error(); msg != "" {
+			result.Message = msg
+		}
+		if readableErr.IsHinted() {
+			result.Detail = readableErr.Unwrap().Error()
+		}
+	}
+	c.PrintResult(result)
 }
 
 var root = &cli.Command{
@@ -302,6 +340,33 @@ var root = &cli.Command{
 				}, "\n"),
 			},
 		},
+		{
+			Name: "output",
+			Type: "string",
+			Description: cli.Description{
+				Short: "Print the result as text or json",
+				Long: strings.Join([]string{
+					"",
+					"Print the result of the command as a JSON document instead of text.",
+					"",
+					"```bash",
+					"sst [command] --output json",
+					"```",
+					"",
+					"Every command writes a single document to stdout, with `ok` and its `result`. If it",
+					"fails, there's an `error` with a `code` that doesn't change between versions, a",
+					"`message`, and sometimes more `detail`.",
+					"",
+					"```json",
+					"{ \"command\": \"deploy\", \"ok\": false, \"error\": { \"code\": \"lock_exists\", \"message\": \"...\" } }",
+					"```",
+					"",
+					"While commands like `sst deploy` run, their progress is written to stderr as one JSON",
+					"object per line. This is useful for tools that wrap the CLI.",
+					"",
+				}, "\n"),
+			},
+		},
 		{
 			Name: "help",
 			Type: "bool",
@@ -386,6 +451,11 @@ var root = &cli.Command{
 					"The multiplexer makes it so that you won't have to start your frontend or",
 					"your container applications separately.",
 					"",
+					"In the sidebar, press `/` to search the scrollback of a tab, and `n` or `N` to jump",
+					"to the next or previous match. Press `w` to save the full scrollback of a tab to",
+					"`.sst/log/`. To watch two tabs at once, press `|` or `-` to pin the selected tab to",
+					"the right or to the bottom, and select another one. Press it again to close the split.",
+					"",
 					"<VideoAside title=\"Watch a video about dev mode\" href=\"https://youtu.be/mefLc137EB0\" />",
 					"",
 					"Here's what happens when you run `sst dev`.",
@@ -448,6 +518,44 @@ var root = &cli.Command{
 					"a tabbed UI it'll show their outputs in a single stream.",
 					"",
 					"This is used by default in Windows.",
+					"",
+					"To drive dev mode from a script, a CI job or an editor, run it in headless mode.",
+					"",
+					"```bash frame=\"none\"",
+					"sst dev --mode=headless",
+					"```",
+					"",
+					"This runs everything `mono` mode does without a terminal UI and writes one JSON",
+					"object per line to stdout instead.",
+					"",
+					"```json frame=\"none\"",
+					"{\"version\":1,\"type\":\"deploy.start\",\"time\":\"2025-01-01T00:00:00Z\",\"data\":{\"app\":\"my-app\",\"stage\":\"dev\",\"command\":\"deploy\",\"version\":\"3.0.0\"}}",
+					"```",
+					"",
+					"The `type` says what is in `data`.",
+					"",
+					"| Type | Data |",
+					"|------|------|",
+					"| `deploy.start` | `app`, `stage`, `command`, `version` |",
+					"| `deploy.resource` | `urn`, `type`, `op`, `status` of `pending`, `done` or `failed` |",
+					"| `deploy.complete` | `updateID`, `ok`, `old`, `errors`, `outputs`, `hints` |",
+					"| `deploy.skipped` | Nothing, no changes were found |",
+					"| `error` | `source` of `build`, `deploy`, `resource` or `concurrent-update`, `message`, `urn` |",
+					"| `function.build` | `functionID`, `errors` |",
+					"| `function.invoke` | `functionID`, `requestID`, `workerID` |",
+					"| `function.response` | `functionID`, `requestID` |",
+					"| `function.error` | `functionID`, `requestID`, `errorType`, `errorMessage`, `trace` |",
+					"| `function.log` | `functionID`, `requestID`, `line` |",
+					"| `worker.build` | `workerID`, `errors` |",
+					"| `worker.invoke` | `workerID`, `method`, `url`, `outcome`, `logs` |",
+					"| `task.start` | `taskID`, `workerID`, `command` |",
+					"| `task.log` | `taskID`, `workerID`, `line` |",
+					"| `task.complete` | `taskID`, `workerID` |",
+					"| `process.status` | `name`, `status` of `waiting`, `started`, `ready`, `exited` or `restarting`, `code` |",
+					"| `process.log` | `name`, `line` |",
+					"",
+					"New fields and types can be added over time, so ignore the ones you don't know.",
+					"The `version` is only bumped if an existing field is removed or changes meaning.",
 				}, "\n"),
 			},
 			Flags: []cli.Flag{
@@ -455,8 +563,8 @@ var root = &cli.Command{
 					Name: "mode",
 					Type: "string",
 					Description: cli.Description{
-						Short: "mode=mono to turn off multiplexer. mode=basic to not spawn any child processes",
-						Long:  "Defaults to using `multi` mode. Use `mono` to get a single stream of all child process logs or `basic` to not spawn any child processes.",
+						Short: "mode=mono to turn off multiplexer. mode=basic to not spawn any child processes. mode=headless for a JSON event stream",
+						Long:  "Defaults to using `multi` mode. Use `mono` to get a single stream of all child process logs, `basic` to not spawn any child processes, or `headless` to write newline delimited JSON events instead of a UI.",
 					},
 				},
 				{
@@ -500,6 +608,8 @@ var root = &cli.Command{
 		},
 		CmdDeploy,
 		CmdDiff,
+		CmdOutput,
+		CmdGraph,
 		{
 			Name: "add",
 			Description: cli.Description{
@@ -545,6 +655,15 @@ var root = &cli.Command{
 					"",
 					"You'll need to run `sst install` if you update the `providers` in your config.",
 					"",
+					"To upgrade a provider that's already in your config to its latest version, use the `--upgrade` flag.",
+					"This updates the version in your `sst.config.ts` and reinstalls it.",
+					"",
+					"```bash frame=\"none\"",
+					"sst add aws --upgrade",
+					"```",
+					"",
+					"You can check which providers have updates with [`sst outdated`](#outdated).",
+					"",
 					"By default, these packages are fetched from the NPM registry. If you want to use a different registry, you can set the `NPM_REGISTRY` environment variable.",
 					"",
 					"```bash frame=\"none\"",
@@ -552,8 +671,28 @@ var root = &cli.Command{
 					"```",
 					"",
 					"You can also set the registry in your `.npmrc` file. If your registry requires authentication, SST supports `_authToken`, `_auth`, and `username`/`_password` from `.npmrc`.",
+					"",
+					"Scoped registries like `@myorg:registry=https://npm.myorg.com`, as well as the `cafile`, `strict-ssl`, `proxy`, `https-proxy`, `noproxy`, and `fetch-retries` settings are honored too.",
+					"",
+					"To install providers without network access, point `SST_NPM_MIRROR` to a directory of package tarballs created with `npm pack`.",
+					"",
+					"```bash frame=\"none\"",
+					"SST_NPM_MIRROR=/path/to/tarballs sst install",
+					"```",
+					"",
+					"Packages that are not in the mirror are fetched from the registry. Set `SST_NPM_OFFLINE=1` to fail instead, in air-gapped environments.",
 				}, "\n"),
 			},
+			Flags: []cli.Flag{
+				{
+					Name: "upgrade",
+					Type: "bool",
+					Description: cli.Description{
+						Short: "Upgrade an existing provider",
+						Long:  "Upgrade a provider that's already in your config to its latest version.",
+					},
+				},
+			},
 			Args: []cli.Argument{
 				{
 					Name:     "provider",
@@ -568,6 +707,9 @@ var root = &cli.Command{
 				pkg := cli.Positional(0)
 				spin := spinner.New(spinner.CharSets[14], 100*time.Millisecond)
 				spin.Color("cyan")
+				if cli.JSON() {
+					spin.Disable()
+				}
 				spin.Suffix = "  Adding provider..."
 				spin.Start()
 				defer spin.Stop()
@@ -580,9 +722,10 @@ var root = &cli.Command{
 					return err
 				}
 				p, err := project.New(&project.ProjectConfig{
-					Version: version,
-					Config:  cfgPath,
-					Stage:   stage,
+					Version:          version,
+					Config:           cfgPath,
+					Stage:            stage,
+					AllowUnversioned: cli.Bool("upgrade"),
 				})
 				if err != nil {
 					return err
@@ -593,6 +736,9 @@ var root = &cli.Command{
 						return err
 					}
 				}
+				if cli.Bool("upgrade") {
+					return upgradeProvider(cli, p, pkg, cfgPath, stage, spin)
+				}
 				entry, err := project.FindProvider(pkg, "latest", pkg)
 				if err != nil {
 					return util.NewReadableError(err, "Could not find provider "+pkg)
@@ -623,6 +769,14 @@ var root = &cli.Command{
 					return err
 				}
 				spin.Stop()
+				if cli.JSON() {
+					cli.SetResult(map[string]interface{}{
+						"provider": entry.Alias,
+						"package":  entry.Package,
+						"version":  entry.Version,
+					})
+					return nil
+				}
 				ui.Success(fmt.Sprintf("Added provider \"%s\". You can create resources with `new %s.SomeResource()`.", entry.Alias, entry.Alias))
 				return nil
 			},
@@ -644,8 +798,33 @@ var root = &cli.Command{
 					"Behind the scenes, it installs the packages for your providers and adds the providers to your globals.",
 					"",
 					"If you don't have a version specified for your providers in your `sst.config.ts`, it'll install their latest versions.",
+					"Once installed, the resolved version is kept in the provider lock and reused on the next install.",
+					"",
+					"The provider lock in `sst.lock.json`, next to your `sst.config.ts`, also records the registry and the",
+					"integrity hash of each provider package. The packages are verified against it when they are downloaded.",
+					"Packages from the `SST_NPM_MIRROR` are locked by their version and hash, and found in the mirror again",
+					"on each install.",
+					"",
+					"To make sure everyone on your team, and your CI, installs the exact same providers; commit the lock file",
+					"and run the install with `--frozen`.",
+					"",
+					"```bash frame=\"none\"",
+					"sst install --frozen",
+					"```",
+					"",
+					"This fails if the lock is missing or out of date instead of updating it.",
 				}, "\n"),
 			},
+			Flags: []cli.Flag{
+				{
+					Name: "frozen",
+					Type: "bool",
+					Description: cli.Description{
+						Short: "Fail if the provider lock is out of date",
+						Long:  "Install providers exactly as recorded in the provider lock and fail if it is missing or out of date.",
+					},
+				},
+			},
 			Run: func(cli *cli.Cli) error {
 				cfgPath, err := cli.Discover()
 				if err != nil {
@@ -668,6 +847,9 @@ var root = &cli.Command{
 
 				spin := spinner.New(spinner.CharSets[14], 100*time.Millisecond)
 				spin.Color("cyan")
+				if cli.JSON() {
+					spin.Disable()
+				}
 				defer spin.Stop()
 				spin.Suffix = "  Installing providers..."
 				spin.Start()
@@ -678,11 +860,28 @@ var root = &cli.Command{
 					}
 				}
 
-				err = p.Install()
+				if cli.Bool("frozen") {
+					err = p.InstallFrozen()
+				} else {
+					err = p.Install()
+				}
 				if err != nil {
 					return err
 				}
 				spin.Stop()
+				if cli.JSON() {
+					providers := []*project.ProviderLockEntry{}
+					for name := range p.App().Providers {
+						if entry, ok := p.LockedProvider(name); ok {
+							providers = append(providers, entry)
+						}
+					}
+					sort.Slice(providers, func(i, j int) bool {
+						return providers[i].Name < providers[j].Name
+					})
+					cli.SetResult(map[string]interface{}{"providers": providers})
+					return nil
+				}
 				ui.Success("Installed providers")
 				return nil
 			},
@@ -956,18 +1155,6 @@ var root = &cli.Command{
 				return nil
 			},
 		},
-		{
-			Name:   "common-errors",
-			Hidden: true,
-			Run: func(cli *cli.Cli) error {
-				data, err := json.MarshalIndent(project.CommonErrors, "", "  ")
-				if err != nil {
-					return err
-				}
-				fmt.Println(string(data))
-				return nil
-			},
-		},
 		{
 			Name: "refresh",
 			Description: cli.Description{
@@ -1033,9 +1220,13 @@ var root = &cli.Command{
 			},
 			Run: CmdRefresh,
 		},
+		CmdOutdated,
 		CmdState,
+		CmdStage,
+		CmdProfile,
 		CmdCert,
 		CmdTunnel,
 		CmdDiagnostic,
+		CmdCommonErrors,
 	},
 }
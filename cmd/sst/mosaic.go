@@ -8,8 +8,8 @@ import (
 	"os/exec"
 	"path/filepath"
 	goruntime "runtime"
+	"slices"
 	"strings"
-	"time"
 
 	"github.com/kballard/go-shellquote"
 	"github.com/sst/sst/v3/cmd/sst/cli"
@@ -17,6 +17,8 @@ import (
 	"github.com/sst/sst/v3/cmd/sst/mosaic/cloudflare"
 	"github.com/sst/sst/v3/cmd/sst/mosaic/deployer"
 	"github.com/sst/sst/v3/cmd/sst/mosaic/dev"
+	"github.com/sst/sst/v3/cmd/sst/mosaic/headless"
+	"github.com/sst/sst/v3/cmd/sst/mosaic/lifecycle"
 	"github.com/sst/sst/v3/cmd/sst/mosaic/monoplexer"
 	"github.com/sst/sst/v3/cmd/sst/mosaic/multiplexer"
 	"github.com/sst/sst/v3/cmd/sst/mosaic/socket"
@@ -70,26 +72,21 @@ func CmdMosaic(c *cli.Cli) error {
 		}
 		var cmd *exec.Cmd
 		var last *dev.EnvResponse
-		processExited := make(chan bool)
-		timeout := time.Minute * 50
-		timer := time.NewTimer(timeout)
-		defer timer.Stop()
+		processExited := make(chan int)
+		exitCode := 0
 
 		for {
 			select {
 			case <-c.Context.Done():
+				if exitCode != 0 {
+					// lets the multiplexer apply the restart policy
+					return &cli.ExitError{Code: exitCode}
+				}
 				return nil
-			case <-processExited:
+			case code := <-processExited:
+				exitCode = code
 				c.Cancel()
 				continue
-			case <-timer.C:
-				last = nil
-				go func() {
-					evts <- true
-				}()
-				fmt.Println("\n" + ui.TEXT_DIM.Render("[timeout]"))
-				timer.Reset(timeout)
-				continue
 			case _, ok := <-evts:
 				if !ok {
 					return nil
@@ -102,9 +99,6 @@ func CmdMosaic(c *cli.Cli) error {
 				if err != nil {
 					return err
 				}
-				if _, ok := nextEnv.Env["AWS_ACCESS_KEY_ID"]; ok {
-					timeout = time.Minute * 45
-				}
 				if last == nil || diff(last.Env, nextEnv.Env) || last.Command != nextEnv.Command {
 					if cmd != nil && cmd.Process != nil {
 						process.Kill(cmd.Process)
@@ -120,6 +114,14 @@ func CmdMosaic(c *cli.Cli) error {
 						fields[1:]...,
 					)
 					cmd.Env = os.Environ()
+					// credentials come from the dev server and are refreshed by the SDK,
+					// so they can't be shadowed by the ones sst dev was started with
+					if _, ok := nextEnv.Env["AWS_CONTAINER_CREDENTIALS_FULL_URI"]; ok {
+						cmd.Env = slices.DeleteFunc(cmd.Env, func(entry string) bool {
+							key, _, _ := strings.Cut(entry, "=")
+							return slices.Contains([]string{"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "AWS_SESSION_TOKEN", "AWS_PROFILE", "AWS_DEFAULT_PROFILE"}, key)
+						})
+					}
 					cmd.Env = append(cmd.Env, "FORCE_COLOR=1")
 					for k, v := range nextEnv.Env {
 						cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", k, v))
@@ -140,7 +142,7 @@ func CmdMosaic(c *cli.Cli) error {
 					cmd.Start()
 					go func() {
 						cmd.Wait()
-						processExited <- true
+						processExited <- cmd.ProcessState.ExitCode()
 					}()
 				}
 				last = nextEnv
@@ -250,7 +252,7 @@ func CmdMosaic(c *cli.Cli) error {
 	})
 
 	if mode == "multi" {
-		multi, err := multiplexer.New()
+		multi, err := multiplexer.New(p.PathLog(""))
 		if err != nil {
 			return err
 		}
@@ -329,6 +331,9 @@ func CmdMosaic(c *cli.Cli) error {
 				case unknown := <-evts:
 					switch evt := unknown.(type) {
 					case *project.CompleteEvent:
+						if err := validateDevs(evt.Devs); err != nil {
+							return err
+						}
 						for _, d := range evt.Devs {
 							if d.Command == "" {
 								continue
@@ -338,6 +343,7 @@ func CmdMosaic(c *cli.Cli) error {
 							if title == "" {
 								title = d.Name
 							}
+							config := devLifecycle(d, evt.Devs)
 							multi.AddProcess(multiplexer.PaneConfig{
 								Key:       d.Name,
 								Args:      []string{currentExecutable, "dev"},
@@ -347,9 +353,13 @@ func CmdMosaic(c *cli.Cli) error {
 								Killable:  true,
 								Autostart: d.Autostart,
 								Env:       append([]string{"SST_CHILD=" + d.Name}, multiEnv...),
+								DependsOn: config.DependsOn,
+								Ready:     config.Ready,
+								Restart:   config.Restart,
 							})
 						}
-						for name := range evt.Tunnels {
+						// a single tunnel process connects to every bastion
+						if len(evt.Tunnels) > 0 {
 							multi.AddProcess(multiplexer.PaneConfig{
 								Key:       "tunnel",
 								Args:      []string{currentExecutable, "tunnel", "--stage", p.App().Stage},
@@ -357,7 +367,15 @@ func CmdMosaic(c *cli.Cli) error {
 								Title:     "Tunnel",
 								Killable:  true,
 								Autostart: true,
-								Env:       append(multiEnv, "SST_LOG="+p.PathLog("tunnel_"+name)),
+								Env:       append(multiEnv, "SST_LOG="+p.PathLog("tunnel")),
+							})
+							multi.AddProcess(multiplexer.PaneConfig{
+								Key:       "tunnel-status",
+								Args:      []string{currentExecutable, "tunnel", "status", "--watch"},
+								Icon:      "⇌",
+								Title:     "Tunnel Status",
+								Autostart: true,
+								Env:       multiEnv,
 							})
 						}
 						if len(evt.Tasks) > 0 {
@@ -421,8 +439,27 @@ func CmdMosaic(c *cli.Cli) error {
 		})
 	}
 
-	if mode == "mono" {
+	if mode == "headless" {
+		stream := bus.Subscribe(headless.Types...)
+		wg.Go(func() error {
+			defer c.Cancel()
+			return headless.Start(c.Context, os.Stdout, stream)
+		})
+		wg.Go(func() error {
+			select {
+			case <-server.Ready:
+				bus.Publish(&deployer.DeployRequestedEvent{})
+			case <-c.Context.Done():
+			}
+			return nil
+		})
+	}
+
+	if mode == "mono" || mode == "headless" {
 		mono := monoplexer.New()
+		if mode == "headless" {
+			mono = monoplexer.NewHeadless()
+		}
 		_, hasAWS := p.App().Providers["aws"]
 		fnTitle := "Function"
 		fnFilter := "function"
@@ -430,8 +467,11 @@ func CmdMosaic(c *cli.Cli) error {
 			fnTitle = "Worker"
 			fnFilter = "worker"
 		}
-		mono.AddProcess("deploy", []string{currentExecutable, "ui", "--filter=sst"}, "", "SST")
-		mono.AddProcess("function", []string{currentExecutable, "ui", "--filter=" + fnFilter}, "", fnTitle)
+		// headless mode writes these events itself
+		if mode == "mono" {
+			mono.AddProcess("deploy", []string{currentExecutable, "ui", "--filter=sst"}, "", "SST", lifecycle.Config{})
+			mono.AddProcess("function", []string{currentExecutable, "ui", "--filter=" + fnFilter}, "", fnTitle, lifecycle.Config{})
+		}
 
 		wg.Go(func() error {
 			defer c.Cancel()
@@ -447,6 +487,9 @@ func CmdMosaic(c *cli.Cli) error {
 				case unknown := <-evts:
 					switch evt := unknown.(type) {
 					case *project.CompleteEvent:
+						if err := validateDevs(evt.Devs); err != nil {
+							return err
+						}
 						for _, d := range evt.Devs {
 							if d.Command == "" {
 								continue
@@ -462,11 +505,12 @@ func CmdMosaic(c *cli.Cli) error {
 								append([]string{currentExecutable, "dev", "--"}, words...),
 								dir,
 								title,
+								devLifecycle(d, evt.Devs),
 								"SST_CHILD="+d.Name,
 							)
 						}
-						for range evt.Tunnels {
-							mono.AddProcess("tunnel", []string{currentExecutable, "tunnel", "--stage", p.App().Stage}, "", "Tunnel")
+						if len(evt.Tunnels) > 0 {
+							mono.AddProcess("tunnel", []string{currentExecutable, "tunnel", "--stage", p.App().Stage}, "", "Tunnel", lifecycle.Config{})
 						}
 						break
 					}
@@ -480,6 +524,43 @@ func CmdMosaic(c *cli.Cli) error {
 	return err
 }
 
+// validateDevs checks the dependsOn of the dev processes before they are
+// registered, since a process waiting on one that never starts is stuck.
+func validateDevs(devs project.Devs) error {
+	nodes := map[string]lifecycle.Node{}
+	for name, d := range devs {
+		if d.Command == "" {
+			continue
+		}
+		nodes[name] = lifecycle.Node{DependsOn: d.DependsOn, Autostart: d.Autostart}
+	}
+	if err := lifecycle.ValidateGraph(nodes); err != nil {
+		return util.NewReadableError(err, "Invalid dev dependsOn: "+err.Error()).WithCode("dev_depends_on_invalid")
+	}
+	return nil
+}
+
+// devLifecycle maps the dev config of a component to how it is started and
+// kept running. The dependencies are checked with validateDevs first.
+func devLifecycle(d project.Dev, devs project.Devs) lifecycle.Config {
+	config := lifecycle.Config{
+		Restart:   lifecycle.Restart(d.Restart),
+		DependsOn: d.DependsOn,
+	}
+	if d.Ready != nil {
+		config.Ready = &lifecycle.Probe{
+			Port: d.Ready.Port,
+			URL:  d.Ready.URL,
+			Log:  d.Ready.Log,
+		}
+		if err := config.Ready.Validate(); err != nil {
+			slog.Warn("ignoring invalid ready log pattern", "dev", d.Name, "err", err)
+			config.Ready.Log = ""
+		}
+	}
+	return config
+}
+
 func diff(a map[string]string, b map[string]string) bool {
 	if len(a) != len(b) {
 		return true
@@ -11,6 +11,7 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
@@ -81,7 +82,7 @@ func function(ctx context.Context, input input) {
 	workerShutdownChan := make(chan *WorkerInfo, 1000)
 	nextChan := map[string]chan io.Reader{}
 	workers := map[string]*WorkerInfo{}
-	evts := bus.Subscribe(&watcher.FileChangedEvent{}, &project.CompleteEvent{}, &runtime.BuildInput{}, &FunctionInvokedEvent{})
+	evts := bus.Subscribe(&watcher.ChangeSetEvent{}, &project.CompleteEvent{}, &runtime.BuildInput{}, &FunctionInvokedEvent{})
 	go fileLogger(input.project)
 
 	input.server.Mux.HandleFunc(`/lambda/{workerID}/2018-06-01/runtime/invocation/next`, func(w http.ResponseWriter, r *http.Request) {
@@ -389,8 +390,8 @@ func function(ctx context.Context, input input) {
 				}
 			case *runtime.BuildInput:
 				targets[evt.FunctionID] = evt
-			case *watcher.FileChangedEvent:
-				log.Info("checking if code needs to be rebuilt", "file", evt.Path)
+			case *watcher.ChangeSetEvent:
+				log.Info("checking if code needs to be rebuilt", "files", len(evt.Paths))
 				toBuild := map[string]bool{}
 
 				for functionID := range builds {
@@ -398,7 +399,9 @@ func function(ctx context.Context, input input) {
 					if !ok {
 						continue
 					}
-					if input.project.Runtime.ShouldRebuild(target.Runtime, target.FunctionID, evt.Path) {
+					if slices.ContainsFunc(evt.Paths, func(path string) bool {
+						return input.project.Runtime.ShouldRebuild(target.Runtime, target.FunctionID, path)
+					}) {
 						for _, worker := range workers {
 							if worker.FunctionID == functionID {
 								log.Info("stopping", "workerID", worker.WorkerID, "functionID", worker.FunctionID)
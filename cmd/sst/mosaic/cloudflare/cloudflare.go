@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
 
 	"github.com/cloudflare/cloudflare-go"
 	"github.com/gorilla/websocket"
@@ -39,7 +40,7 @@ func Start(ctx context.Context, proj *project.Project, args map[string]interface
 		return util.NewReadableError(nil, "Cloudflare provider not found in project configuration")
 	}
 	api := prov.(*provider.CloudflareProvider).Api()
-	evts := bus.Subscribe(&project.CompleteEvent{}, &watcher.FileChangedEvent{}, &runtime.BuildInput{})
+	evts := bus.Subscribe(&project.CompleteEvent{}, &watcher.ChangeSetEvent{}, &runtime.BuildInput{})
 	builds := map[string]*runtime.BuildOutput{}
 	targets := map[string]*runtime.BuildInput{}
 	type tailRef struct {
@@ -108,9 +109,11 @@ exit:
 					continue
 				}
 				builds[target.FunctionID] = output
-			case *watcher.FileChangedEvent:
+			case *watcher.ChangeSetEvent:
 				for workerID, target := range targets {
-					if proj.Runtime.ShouldRebuild(target.Runtime, workerID, evt.Path) {
+					if slices.ContainsFunc(evt.Paths, func(path string) bool {
+						return proj.Runtime.ShouldRebuild(target.Runtime, workerID, path)
+					}) {
 						output, err := proj.Runtime.Build(ctx, target)
 						if err != nil {
 							continue
@@ -4,6 +4,7 @@ import (
 	"context"
 	"log/slog"
 	"reflect"
+	"slices"
 
 	"github.com/sst/sst/v3/cmd/sst/mosaic/errors"
 	"github.com/sst/sst/v3/cmd/sst/mosaic/watcher"
@@ -23,7 +24,7 @@ func Start(ctx context.Context, p *project.Project, server *server.Server, polic
 	log.Info("starting")
 	defer log.Info("done")
 	watchedFiles := make(map[string]bool)
-	events := bus.Subscribe(ctx, &watcher.FileChangedEvent{}, &DeployRequestedEvent{}, &project.BuildSuccessEvent{})
+	events := bus.Subscribe(ctx, &watcher.ChangeSetEvent{}, &DeployRequestedEvent{}, &project.BuildSuccessEvent{})
 	lastBuildHash := ""
 	for {
 		log.Info("waiting for trigger")
@@ -39,8 +40,8 @@ func Start(ctx context.Context, p *project.Project, server *server.Server, polic
 					watchedFiles[file] = true
 				}
 				continue
-			case *watcher.FileChangedEvent, *DeployRequestedEvent:
-				if evt, ok := evt.(*watcher.FileChangedEvent); !ok || watchedFiles[evt.Path] {
+			case *watcher.ChangeSetEvent, *DeployRequestedEvent:
+				if evt, ok := evt.(*watcher.ChangeSetEvent); !ok || slices.ContainsFunc(evt.Paths, func(path string) bool { return watchedFiles[path] }) {
 					log.Info("deploying")
 					err := p.Run(ctx, &project.StackInput{
 						Command:    "deploy",
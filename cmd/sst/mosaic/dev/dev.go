@@ -2,12 +2,17 @@ package dev
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
 	"encoding/json"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
 	"path/filepath"
 	"reflect"
+	"time"
 
 	"github.com/sst/sst/v3/cmd/sst/mosaic/deployer"
 	"github.com/sst/sst/v3/pkg/bus"
@@ -80,6 +85,45 @@ func Start(ctx context.Context, p *project.Project, server *server.Server) error
 		bus.Publish(&deployer.DeployRequestedEvent{})
 	})
 
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return err
+	}
+	endpoint := &project.CredentialsEndpoint{
+		// SDKs only allow plain http for loopback addresses
+		URL:   fmt.Sprintf("http://127.0.0.1:%d/api/credentials", server.Port),
+		Token: hex.EncodeToString(secret),
+	}
+	server.Mux.HandleFunc("/api/credentials", func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte(endpoint.Token)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		name := r.URL.Query().Get("name")
+		if complete == nil {
+			http.Error(w, "not deployed yet", http.StatusServiceUnavailable)
+			return
+		}
+		d, ok := complete.Devs[name]
+		if !ok || d.Aws == nil || d.Aws.Role == "" {
+			http.Error(w, "dev not found", http.StatusNotFound)
+			return
+		}
+		credentials, err := p.DevCredentials(r.Context(), d.Aws.Role)
+		if err != nil {
+			log.Error("failed to load aws credentials", "name", name, "err", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{
+			"AccessKeyId":     credentials.AccessKeyID,
+			"SecretAccessKey": credentials.SecretAccessKey,
+			"Token":           credentials.SessionToken,
+			"Expiration":      credentials.Expires.UTC().Format(time.RFC3339),
+		})
+	})
+
 	server.Mux.HandleFunc("/api/env", func(w http.ResponseWriter, r *http.Request) {
 		directory := r.URL.Query().Get("directory")
 		name := r.URL.Query().Get("name")
@@ -88,7 +132,7 @@ func Start(ctx context.Context, p *project.Project, server *server.Server) error
 			full := filepath.Join(cwd, d.Directory)
 			log.Info("matching dev", "full", full, "directory", directory)
 			if (directory != "" && full == directory) || (name != "" && d.Name == name) {
-				env, err := p.EnvFor(ctx, complete, d.Name)
+				env, err := p.EnvFor(ctx, complete, d.Name, endpoint)
 				if err != nil {
 					http.Error(w, err.Error(), http.StatusInternalServerError)
 					return
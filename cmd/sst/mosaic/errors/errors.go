@@ -1,6 +1,7 @@
 package errors
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"strings"
@@ -16,29 +17,31 @@ import (
 
 type ErrorTransformer = func(err error) (bool, error)
 
-var transformers = []ErrorTransformer{
-	exact(appsync.ErrSubscriptionFailed, "Failed to subscribe to appsync websocket endpoint which powers live lambda. Check to see if you have proper appsync permissions."),
-	exact(project.ErrInvalidStageName, "The stage name is invalid. It must start with a letter and can only contain alphanumeric characters and hyphens."),
-	exact(project.ErrInvalidAppName, "The app name is invalid. It must start with a letter and can only contain alphanumeric characters and hyphens."),
-	exact(project.ErrAppNameChanged, "The app name has changed.\n\nIf you want to rename the app, make sure to run `sst remove` to remove the old app first. Alternatively, remove the \".sst\" folder and try again.\n"),
-	exact(project.ErrV2Config, "You are using sst v3 and this looks like an sst v2 config"),
-	exact(project.ErrStageNotFound, "Stage not found"),
-	exact(project.ErrPassphraseInvalid, "The passphrase for this app / stage is missing or invalid"),
-	exact(aws.ErrIoTDelay, "This aws account has not had iot initialized in it before which sst depends on. It may take a few minutes before it is ready."),
-	exact(project.ErrStackRunFailed, ""),
-	exact(project.ErrPolicyViolation, ""),
-	exact(project.ErrPolicyConfigError, ""),
-	exact(provider.ErrLockExists, ""),
-	exact(project.ErrVersionInvalid, "The version range defined in the config is invalid"),
-	exact(provider.ErrCloudflareMissingAccount, "The Cloudflare Account ID was not able to be determined from this token. Make sure it has permissions to fetch account information or you can set the CLOUDFLARE_DEFAULT_ACCOUNT_ID environment variable to the account id you want to use."),
-	exact(server.ErrServerNotFound, "Could not find an `sst dev` session to connect to. Since you are running a command outside of the multiplexer be sure to start `sst dev` first."),
-	exact(provider.ErrBucketMissing, "The state bucket is missing, it may have been accidentally deleted. Go to https://console.aws.amazon.com/systems-manager/parameters/%252Fsst%252Fbootstrap/description?tab=Table and check if the state bucket mentioned there exists. If it doesn't you can recreate it or delete the `/sst/bootstrap` key to force recreation."),
-	exact(project.ErrProtectedStage, "Cannot remove protected stage. To remove a protected stage edit your sst.config.ts and remove the `protect` property."),
-	exact(project.ErrProtectedDevStage, "Cannot run `sst dev` on a protected stage."),
-	exact(provider.ErrLockNotFound, "This app / stage is not locked"),
-	exact(aws.ErrAppsyncNotReady, "SST creates an appsync event api to power live lambda. After 10 seconds of waiting this cli could not connect to it."),
-	exact(js.ErrTopLevelImport, "Your sst.config.ts has top level imports - this is not allowed. Move imports inside the function they are used and do a dynamic import: `const mod = await import(\"./mod\")`"),
-	match(func(err *project.ErrBuildFailed) string {
+var transformers = []transformer{
+	exact("appsync_subscription_failed", appsync.ErrSubscriptionFailed, "Failed to subscribe to appsync websocket endpoint which powers live lambda. Check to see if you have proper appsync permissions."),
+	exact("invalid_stage_name", project.ErrInvalidStageName, "The stage name is invalid. It must start with a letter and can only contain alphanumeric characters and hyphens."),
+	exact("invalid_app_name", project.ErrInvalidAppName, "The app name is invalid. It must start with a letter and can only contain alphanumeric characters and hyphens."),
+	exact("app_name_changed", project.ErrAppNameChanged, "The app name has changed.\n\nIf you want to rename the app, make sure to run `sst remove` to remove the old app first. Alternatively, remove the \".sst\" folder and try again.\n"),
+	exact("v2_config", project.ErrV2Config, "You are using sst v3 and this looks like an sst v2 config"),
+	exact("stage_not_found", project.ErrStageNotFound, "Stage not found"),
+	exact("passphrase_invalid", project.ErrPassphraseInvalid, "The passphrase for this app / stage is missing or invalid"),
+	exact("iot_not_ready", aws.ErrIoTDelay, "This aws account has not had iot initialized in it before which sst depends on. It may take a few minutes before it is ready."),
+	exact("deploy_failed", project.ErrStackRunFailed, ""),
+	exact("policy_violation", project.ErrPolicyViolation, ""),
+	exact("policy_config_error", project.ErrPolicyConfigError, ""),
+	exact("lock_exists", provider.ErrLockExists, ""),
+	exact("provider_lock_missing", project.ErrProviderLockMissing, "The provider lock is missing. Run `sst install` without `--frozen` to create it and commit `sst.lock.json`."),
+	exact("provider_lock_outdated", project.ErrProviderLockOutdated, "The provider lock does not match the providers in your sst.config.ts. Run `sst install` without `--frozen` to update it."),
+	exact("version_invalid", project.ErrVersionInvalid, "The version range defined in the config is invalid"),
+	exact("cloudflare_missing_account", provider.ErrCloudflareMissingAccount, "The Cloudflare Account ID was not able to be determined from this token. Make sure it has permissions to fetch account information or you can set the CLOUDFLARE_DEFAULT_ACCOUNT_ID environment variable to the account id you want to use."),
+	exact("dev_server_not_found", server.ErrServerNotFound, "Could not find an `sst dev` session to connect to. Since you are running a command outside of the multiplexer be sure to start `sst dev` first."),
+	exact("state_bucket_missing", provider.ErrBucketMissing, "The state bucket is missing, it may have been accidentally deleted. Go to https://console.aws.amazon.com/systems-manager/parameters/%252Fsst%252Fbootstrap/description?tab=Table and check if the state bucket mentioned there exists. If it doesn't you can recreate it or delete the `/sst/bootstrap` key to force recreation."),
+	exact("protected_stage", project.ErrProtectedStage, "Cannot remove protected stage. To remove a protected stage edit your sst.config.ts and remove the `protect` property."),
+	exact("protected_dev_stage", project.ErrProtectedDevStage, "Cannot run `sst dev` on a protected stage."),
+	exact("lock_not_found", provider.ErrLockNotFound, "This app / stage is not locked"),
+	exact("appsync_not_ready", aws.ErrAppsyncNotReady, "SST creates an appsync event api to power live lambda. After 10 seconds of waiting this cli could not connect to it."),
+	exact("config_top_level_import", js.ErrTopLevelImport, "Your sst.config.ts has top level imports - this is not allowed. Move imports inside the function they are used and do a dynamic import: `const mod = await import(\"./mod\")`"),
+	match("config_build_failed", func(err *project.ErrBuildFailed) string {
 		result := "Failed to build sst.config.ts"
 		for _, msg := range err.Errors {
 			result += "\n   - "
@@ -49,61 +52,111 @@ var transformers = []ErrorTransformer{
 		}
 		return result
 	}),
-	match(func(err *project.ErrProviderVersionTooLow) string {
+	match("provider_version_too_low", func(err *project.ErrProviderVersionTooLow) string {
 		return fmt.Sprintf("You specified version %s of the \"%s\" provider. SST needs %s or higher.", err.Version, err.Name, err.Needed)
 	}),
-	match(func(err *project.ErrVersionMismatch) string {
+	match("change_not_approved", func(err *project.ErrChangeNotApproved) string {
+		return fmt.Sprintf("%d of the changes need to be approved. Run `sst deploy` again with `--approve %s` to deploy them.", len(err.Violations), err.Token)
+	}),
+	match("version_mismatch", func(err *project.ErrVersionMismatch) string {
 		return fmt.Sprintf("You are using v%s which does not match v%s in your \"sst.config.ts\".", err.Needed, err.Received)
 	}),
-	func(err error) (bool, error) {
-		msg := err.Error()
-		if !strings.HasPrefix(msg, "aws:") {
-			return false, nil
-		}
-		if strings.Contains(msg, "cached SSO token is expired") {
-			return true, util.NewHintedError(err, "It looks like you are using AWS SSO but your credentials have expired. Try running `aws sso login` to refresh your credentials.")
-		}
-		if strings.Contains(msg, "no EC2 IMDS role found") {
-			return true, util.NewHintedError(err, "AWS credentials are not configured. Try configuring your profile in `~/.aws/config` and setting the `AWS_PROFILE` environment variable or specifying `providers.aws.profile` in your sst.config.ts")
-		}
-		return false, nil
-	},
+}
+
+// Codes for errors that aren't in the table. The codes are part of the
+// `--output json` result, so they shouldn't change once released.
+const (
+	CodeCancelled  = "cancelled"
+	CodeError      = "error"
+	CodeUnexpected = "unexpected"
+)
+
+// transformer pairs an ErrorTransformer with the code of the errors it
+// matches.
+type transformer struct {
+	code      string
+	transform ErrorTransformer
 }
 
 func Transform(err error) error {
+	_, result := transform(err)
+	return result
+}
+
+// Code returns the stable code of an error. Readable errors without a code of
+// their own are CodeError and everything else is CodeUnexpected.
+func Code(err error) string {
+	code, result := transform(err)
+	if code != "" {
+		return code
+	}
+	if errors.Is(err, context.Canceled) {
+		return CodeCancelled
+	}
+	var readable *util.ReadableError
+	if errors.As(result, &readable) {
+		if readable.Code() != "" {
+			return readable.Code()
+		}
+		return CodeError
+	}
+	return CodeUnexpected
+}
+
+// transform checks the table first and then the common errors catalog, which
+// adds a hint to the original error.
+func transform(err error) (string, error) {
 	for _, t := range transformers {
-		if ok, err := t(err); ok {
-			return err
+		if ok, result := t.transform(err); ok {
+			return t.code, result
+		}
+	}
+	messages := []string{err.Error()}
+	var readable *util.ReadableError
+	if errors.As(err, &readable) {
+		if readable.IsHinted() {
+			return "", err
+		}
+		// readable errors like a provider that failed to initialize wrap the
+		// error that the catalog knows about
+		if cause := readable.Unwrap(); cause != nil {
+			messages = append(messages, cause.Error())
+		}
+	}
+	for _, message := range messages {
+		if matches := project.MatchCommonErrors(project.CommonErrors, message); len(matches) > 0 {
+			match := matches[0]
+			return match.Code, util.NewHintedError(err, strings.Join(match.Help(), "\n")).WithCode(match.Code)
 		}
 	}
-	return err
+	return "", err
 }
 
-func match[T error](transformer func(T) string) ErrorTransformer {
-	return func(err error) (bool, error) {
+func match[T error](code string, fn func(T) string) transformer {
+	return transformer{code, func(err error) (bool, error) {
 		var match T
 		if errors.As(err, &match) {
-			str := transformer(match)
-			return true, util.NewReadableError(err, str)
+			str := fn(match)
+			return true, util.NewReadableError(err, str).WithCode(code)
 		}
 		return false, nil
-	}
+	}}
 }
 
-func exact(compare error, msg string) ErrorTransformer {
-	return func(err error) (bool, error) {
+func exact(code string, compare error, msg string) transformer {
+	return transformer{code, func(err error) (bool, error) {
 		if errors.Is(err, compare) {
-			return true, util.NewReadableError(err, msg)
+			return true, util.NewReadableError(err, msg).WithCode(code)
 		}
 		return false, nil
-	}
+	}}
 }
 
-func passthrough(compare error) ErrorTransformer {
-	return func(err error) (bool, error) {
+func passthrough(code string, compare error) transformer {
+	return transformer{code, func(err error) (bool, error) {
 		if errors.Is(err, compare) {
-			return true, util.NewReadableError(err, err.Error())
+			return true, util.NewReadableError(err, err.Error()).WithCode(code)
 		}
 		return false, nil
-	}
+	}}
 }
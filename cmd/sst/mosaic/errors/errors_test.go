@@ -0,0 +1,120 @@
+package errors
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/sst/sst/v3/internal/util"
+	"github.com/sst/sst/v3/pkg/project"
+	"github.com/sst/sst/v3/pkg/project/provider"
+)
+
+func TestCodesAreUnique(t *testing.T) {
+	valid := regexp.MustCompile(`^[a-z0-9]+(_[a-z0-9]+)*$`)
+	seen := map[string]bool{CodeCancelled: true, CodeError: true, CodeUnexpected: true}
+	for _, item := range transformers {
+		if !valid.MatchString(item.code) {
+			t.Errorf("code %q should be snake_case", item.code)
+		}
+		if seen[item.code] {
+			t.Errorf("code %q is used twice", item.code)
+		}
+		seen[item.code] = true
+	}
+}
+
+func TestCode(t *testing.T) {
+	cases := []struct {
+		err  error
+		code string
+	}{
+		{provider.ErrLockExists, "lock_exists"},
+		{fmt.Errorf("wrapped: %w", project.ErrStageNotFound), "stage_not_found"},
+		{&project.ErrProviderVersionTooLow{Name: "aws", Version: "1.0.0", Needed: "6.0.0"}, "provider_version_too_low"},
+		{errors.New("aws: cached SSO token is expired"), "aws_sso_expired"},
+		{&project.ErrChangeNotApproved{Token: "abc"}, "change_not_approved"},
+		{context.Canceled, CodeCancelled},
+		{util.NewReadableError(nil, "Target not found").WithCode("target_not_found"), "target_not_found"},
+		{fmt.Errorf("wrapped: %w", util.NewReadableError(nil, "Target not found").WithCode("target_not_found")), "target_not_found"},
+		{util.NewReadableError(nil, "Something went wrong"), CodeError},
+		{errors.New("boom"), CodeUnexpected},
+	}
+	for _, item := range cases {
+		if got := Code(item.err); got != item.code {
+			t.Errorf("Code(%v) = %s, expected %s", item.err, got, item.code)
+		}
+	}
+}
+
+func TestTransformCommonError(t *testing.T) {
+	err := errors.New("aws: no EC2 IMDS role found")
+	transformed, ok := Transform(err).(*util.ReadableError)
+	if !ok || !transformed.IsHinted() || transformed.Unwrap() != err {
+		t.Fatalf("expected a hinted error, got %v", transformed)
+	}
+	if transformed.Code() != "aws_credentials_missing" {
+		t.Fatalf("got %s", transformed.Code())
+	}
+}
+
+// TestTransformLoadHome goes through the error LoadHome returns when the aws
+// provider can't find credentials, which wraps the error of the sdk.
+func TestTransformLoadHome(t *testing.T) {
+	root := t.TempDir()
+	home := filepath.Join(root, "home")
+	cache := filepath.Join(home, ".aws", "sso", "cache")
+	if err := os.MkdirAll(cache, 0755); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(home, ".aws", "config"), []byte(strings.Join([]string{
+		"[profile expired]",
+		"sso_session = expired",
+		"sso_account_id = 123456789012",
+		"sso_role_name = Admin",
+		"region = us-east-1",
+		"",
+		"[sso-session expired]",
+		"sso_start_url = https://example.awsapps.com/start",
+		"sso_region = us-east-1",
+	}, "\n")), 0644)
+	key := sha1.Sum([]byte("expired"))
+	os.WriteFile(filepath.Join(cache, hex.EncodeToString(key[:])+".json"), []byte(`{"accessToken":"token","expiresAt":"2020-01-01T00:00:00Z"}`), 0644)
+	for _, key := range []string{"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "AWS_SESSION_TOKEN", "AWS_CONTAINER_CREDENTIALS_FULL_URI", "AWS_WEB_IDENTITY_TOKEN_FILE", "SST_AWS_ROLE_ARN", "SST_AWS_NO_PROFILE"} {
+		t.Setenv(key, "")
+	}
+	t.Setenv("HOME", home)
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(home, ".aws", "config"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(home, ".aws", "credentials"))
+	t.Setenv("AWS_PROFILE", "expired")
+
+	cfgPath := filepath.Join(root, "sst.config.ts")
+	os.WriteFile(cfgPath, []byte(`export default $config({
+  app() {
+    return { name: "errors", home: "aws" };
+  },
+});
+`), 0644)
+	p, err := project.New(&project.ProjectConfig{Config: cfgPath, Stage: "test", Version: "dev"})
+	if err != nil {
+		t.Skipf("could not load the config: %v", err)
+	}
+	err = p.LoadHome()
+	if err == nil {
+		t.Fatal("expected LoadHome to fail")
+	}
+	if code := Code(err); code != "aws_sso_expired" {
+		t.Fatalf("Code(%v) = %s, expected aws_sso_expired", err, code)
+	}
+	transformed, ok := Transform(err).(*util.ReadableError)
+	if !ok || !transformed.IsHinted() || transformed.Unwrap() != err {
+		t.Fatalf("expected a hinted error, got %v", transformed)
+	}
+}
@@ -0,0 +1,352 @@
+// Package headless writes what happens in `sst dev` as newline delimited
+// JSON, for tools that drive dev mode without a terminal.
+//
+// Every line is an Event. The type says which of the data structs below is
+// in data. Fields are only ever added to these, so consumers should ignore
+// fields and types they don't know.
+package headless
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/sst/sst/v3/cmd/sst/mosaic/aws"
+	"github.com/sst/sst/v3/cmd/sst/mosaic/cloudflare"
+	"github.com/sst/sst/v3/cmd/sst/mosaic/deployer"
+	"github.com/sst/sst/v3/cmd/sst/mosaic/monoplexer"
+	"github.com/sst/sst/v3/cmd/sst/mosaic/ui"
+	"github.com/sst/sst/v3/pkg/project"
+)
+
+// Version is bumped if a field is ever removed or changes meaning.
+const Version = 1
+
+type Event struct {
+	Version int       `json:"version"`
+	Type    string    `json:"type"`
+	Time    time.Time `json:"time"`
+	Data    any       `json:"data"`
+}
+
+// DeployStart is `deploy.start`, sent when a deploy begins.
+type DeployStart struct {
+	App     string `json:"app"`
+	Stage   string `json:"stage"`
+	Command string `json:"command"`
+	Version string `json:"version"`
+}
+
+// Resource is `deploy.resource`, sent when a resource starts changing and
+// again when it's done or failed. Status is `pending`, `done` or `failed`.
+type Resource struct {
+	URN    string `json:"urn"`
+	Type   string `json:"type"`
+	Op     string `json:"op"`
+	Status string `json:"status"`
+}
+
+// DeployComplete is `deploy.complete`, sent when a deploy finishes. Old is
+// set when it was loaded from the last deploy when dev mode started.
+type DeployComplete struct {
+	UpdateID string            `json:"updateID"`
+	OK       bool              `json:"ok"`
+	Old      bool              `json:"old"`
+	Errors   []project.Error   `json:"errors"`
+	Outputs  map[string]any    `json:"outputs"`
+	Hints    map[string]string `json:"hints"`
+}
+
+// DeploySkipped is `deploy.skipped`, sent when nothing changed.
+type DeploySkipped struct{}
+
+// Error is `error`. Source is `build`, `deploy`, `resource` or
+// `concurrent-update`.
+type Error struct {
+	Source  string `json:"source"`
+	Message string `json:"message"`
+	URN     string `json:"urn,omitempty"`
+}
+
+// FunctionBuild is `function.build`, sent when a function is rebuilt.
+type FunctionBuild struct {
+	FunctionID string   `json:"functionID"`
+	Errors     []string `json:"errors"`
+}
+
+// FunctionInvoke is `function.invoke`, sent when a function is invoked.
+type FunctionInvoke struct {
+	FunctionID string `json:"functionID"`
+	RequestID  string `json:"requestID"`
+	WorkerID   string `json:"workerID"`
+}
+
+// FunctionResponse is `function.response`, sent when an invocation returns.
+type FunctionResponse struct {
+	FunctionID string `json:"functionID"`
+	RequestID  string `json:"requestID"`
+}
+
+// FunctionError is `function.error`, sent when an invocation fails.
+type FunctionError struct {
+	FunctionID   string   `json:"functionID"`
+	RequestID    string   `json:"requestID"`
+	ErrorType    string   `json:"errorType"`
+	ErrorMessage string   `json:"errorMessage"`
+	Trace        []string `json:"trace"`
+}
+
+// FunctionLog is `function.log`, sent for every line an invocation logs.
+type FunctionLog struct {
+	FunctionID string `json:"functionID"`
+	RequestID  string `json:"requestID"`
+	Line       string `json:"line"`
+}
+
+// WorkerBuild is `worker.build`, sent when a worker is rebuilt.
+type WorkerBuild struct {
+	WorkerID string   `json:"workerID"`
+	Errors   []string `json:"errors"`
+}
+
+// WorkerInvoke is `worker.invoke`, sent for every request a worker handles.
+type WorkerInvoke struct {
+	WorkerID string   `json:"workerID"`
+	Method   string   `json:"method"`
+	URL      string   `json:"url"`
+	Outcome  string   `json:"outcome"`
+	Logs     []string `json:"logs"`
+}
+
+// TaskStart is `task.start`, sent when a task runs locally.
+type TaskStart struct {
+	TaskID   string `json:"taskID"`
+	WorkerID string `json:"workerID"`
+	Command  string `json:"command"`
+}
+
+// TaskLog is `task.log`, sent for every line a task writes.
+type TaskLog struct {
+	TaskID   string `json:"taskID"`
+	WorkerID string `json:"workerID"`
+	Line     string `json:"line"`
+}
+
+// TaskComplete is `task.complete`, sent when a task exits.
+type TaskComplete struct {
+	TaskID   string `json:"taskID"`
+	WorkerID string `json:"workerID"`
+}
+
+// ProcessStatus is `process.status`, sent when a dev process is `waiting`
+// for its dependencies, `started`, `ready`, `exited` or `restarting`. Code is
+// the exit code when it exited.
+type ProcessStatus struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Code   int    `json:"code"`
+}
+
+// ProcessLog is `process.log`, sent for every line a dev process writes.
+type ProcessLog struct {
+	Name string `json:"name"`
+	Line string `json:"line"`
+}
+
+// Types are the bus events the stream is made from.
+var Types = []any{
+	&project.StackCommandEvent{},
+	&project.CompleteEvent{},
+	&project.SkipEvent{},
+	&project.BuildFailedEvent{},
+	&project.ConcurrentUpdateEvent{},
+	&deployer.DeployFailedEvent{},
+	&apitype.ResourcePreEvent{},
+	&apitype.ResOutputsEvent{},
+	&apitype.ResOpFailedEvent{},
+	&apitype.DiagnosticEvent{},
+	&aws.FunctionBuildEvent{},
+	&aws.FunctionInvokedEvent{},
+	&aws.FunctionResponseEvent{},
+	&aws.FunctionErrorEvent{},
+	&aws.FunctionLogEvent{},
+	&aws.TaskStartEvent{},
+	&aws.TaskLogEvent{},
+	&aws.TaskCompleteEvent{},
+	&cloudflare.WorkerBuildEvent{},
+	&cloudflare.WorkerInvokedEvent{},
+	&monoplexer.ProcessStatusEvent{},
+	&monoplexer.ProcessLogEvent{},
+}
+
+// Convert maps a bus event to the events written for it. Bus events that
+// aren't part of the stream map to nothing.
+func Convert(unknown any) []Event {
+	switch evt := unknown.(type) {
+	case *project.StackCommandEvent:
+		return event("deploy.start", DeployStart{
+			App:     evt.App,
+			Stage:   evt.Stage,
+			Command: evt.Command,
+			Version: evt.Version,
+		})
+	case *project.CompleteEvent:
+		errors := evt.Errors
+		if errors == nil {
+			errors = []project.Error{}
+		}
+		// the stream ends up in CI logs, so secrets are masked like they are
+		// for `sst output`
+		outputs, _ := evt.Document(false)["outputs"].(map[string]any)
+		return event("deploy.complete", DeployComplete{
+			UpdateID: evt.UpdateID,
+			OK:       evt.Finished && len(evt.Errors) == 0,
+			Old:      evt.Old,
+			Errors:   errors,
+			Outputs:  outputs,
+			Hints:    evt.Hints,
+		})
+	case *project.SkipEvent:
+		return event("deploy.skipped", DeploySkipped{})
+	case *project.BuildFailedEvent:
+		return event("error", Error{Source: "build", Message: evt.Error})
+	case *project.ConcurrentUpdateEvent:
+		return event("error", Error{Source: "concurrent-update", Message: "another deploy is in progress for this stage"})
+	case *deployer.DeployFailedEvent:
+		return event("error", Error{Source: "deploy", Message: evt.Error})
+	case *apitype.ResourcePreEvent:
+		if evt.Metadata.Op == apitype.OpSame || isIgnored(evt.Metadata.Type) {
+			return nil
+		}
+		return event("deploy.resource", Resource{
+			URN:    evt.Metadata.URN,
+			Type:   evt.Metadata.Type,
+			Op:     string(evt.Metadata.Op),
+			Status: "pending",
+		})
+	case *apitype.ResOutputsEvent:
+		if evt.Metadata.Op == apitype.OpSame || isIgnored(evt.Metadata.Type) {
+			return nil
+		}
+		return event("deploy.resource", Resource{
+			URN:    evt.Metadata.URN,
+			Type:   evt.Metadata.Type,
+			Op:     string(evt.Metadata.Op),
+			Status: "done",
+		})
+	case *apitype.ResOpFailedEvent:
+		return event("deploy.resource", Resource{
+			URN:    evt.Metadata.URN,
+			Type:   evt.Metadata.Type,
+			Op:     string(evt.Metadata.Op),
+			Status: "failed",
+		})
+	case *apitype.DiagnosticEvent:
+		if evt.Severity != "error" {
+			return nil
+		}
+		return event("error", Error{Source: "resource", Message: strings.TrimSpace(evt.Message), URN: evt.URN})
+	case *aws.FunctionBuildEvent:
+		return event("function.build", FunctionBuild{FunctionID: evt.FunctionID, Errors: nonNil(evt.Errors)})
+	case *aws.FunctionInvokedEvent:
+		return event("function.invoke", FunctionInvoke{FunctionID: evt.FunctionID, RequestID: evt.RequestID, WorkerID: evt.WorkerID})
+	case *aws.FunctionResponseEvent:
+		return event("function.response", FunctionResponse{FunctionID: evt.FunctionID, RequestID: evt.RequestID})
+	case *aws.FunctionErrorEvent:
+		return event("function.error", FunctionError{
+			FunctionID:   evt.FunctionID,
+			RequestID:    evt.RequestID,
+			ErrorType:    evt.ErrorType,
+			ErrorMessage: evt.ErrorMessage,
+			Trace:        nonNil(evt.Trace),
+		})
+	case *aws.FunctionLogEvent:
+		return event("function.log", FunctionLog{FunctionID: evt.FunctionID, RequestID: evt.RequestID, Line: evt.Line})
+	case *aws.TaskStartEvent:
+		return event("task.start", TaskStart{TaskID: evt.TaskID, WorkerID: evt.WorkerID, Command: evt.Command})
+	case *aws.TaskLogEvent:
+		return event("task.log", TaskLog{TaskID: evt.TaskID, WorkerID: evt.WorkerID, Line: evt.Line})
+	case *aws.TaskCompleteEvent:
+		return event("task.complete", TaskComplete{TaskID: evt.TaskID, WorkerID: evt.WorkerID})
+	case *cloudflare.WorkerBuildEvent:
+		return event("worker.build", WorkerBuild{WorkerID: evt.WorkerID, Errors: nonNil(evt.Errors)})
+	case *cloudflare.WorkerInvokedEvent:
+		result := WorkerInvoke{WorkerID: evt.WorkerID, Logs: []string{}}
+		if evt.TailEvent != nil {
+			result.Method = evt.TailEvent.Event.Request.Method
+			result.URL = evt.TailEvent.Event.Request.URL
+			result.Outcome = evt.TailEvent.Outcome
+			for _, log := range evt.TailEvent.Logs {
+				parts := []string{}
+				for _, part := range log.Message {
+					switch v := part.(type) {
+					case string:
+						parts = append(parts, v)
+					default:
+						data, _ := json.Marshal(v)
+						parts = append(parts, string(data))
+					}
+				}
+				result.Logs = append(result.Logs, strings.Join(parts, " "))
+			}
+		}
+		return event("worker.invoke", result)
+	case *monoplexer.ProcessStatusEvent:
+		return event("process.status", ProcessStatus{Name: evt.Name, Status: evt.Status, Code: evt.Code})
+	case *monoplexer.ProcessLogEvent:
+		return event("process.log", ProcessLog{Name: evt.Name, Line: evt.Line})
+	}
+	return nil
+}
+
+func event(kind string, data any) []Event {
+	return []Event{{
+		Version: Version,
+		Type:    kind,
+		Time:    time.Now().UTC(),
+		Data:    data,
+	}}
+}
+
+func nonNil(input []string) []string {
+	if input == nil {
+		return []string{}
+	}
+	return input
+}
+
+func isIgnored(kind string) bool {
+	for _, ignored := range ui.IGNORED_RESOURCES {
+		if ignored == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// Start writes the events of the stream to w until the context is done. The
+// caller subscribes to Types up front so nothing published in the meantime is
+// missed.
+func Start(ctx context.Context, w io.Writer, events <-chan any) error {
+	log := slog.Default().With("service", "headless")
+	log.Info("starting")
+	defer log.Info("done")
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case unknown := <-events:
+			for _, evt := range Convert(unknown) {
+				if err := encoder.Encode(evt); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package headless
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/sst/sst/v3/cmd/sst/mosaic/aws"
+	"github.com/sst/sst/v3/cmd/sst/mosaic/monoplexer"
+	"github.com/sst/sst/v3/pkg/project"
+)
+
+func TestConvert(t *testing.T) {
+	cases := []struct {
+		input    any
+		expected string
+	}{
+		{
+			&project.StackCommandEvent{App: "app", Stage: "dev", Command: "deploy", Version: "3.0.0"},
+			`{"type":"deploy.start","data":{"app":"app","stage":"dev","command":"deploy","version":"3.0.0"}}`,
+		},
+		{
+			&project.CompleteEvent{UpdateID: "1", Finished: true},
+			`{"type":"deploy.complete","data":{"updateID":"1","ok":true,"old":false,"errors":[],"outputs":null,"hints":null}}`,
+		},
+		{
+			&project.CompleteEvent{
+				UpdateID: "2",
+				Finished: true,
+				Outputs:  map[string]interface{}{"url": "https://example.com", "token": "hunter2"},
+				Secrets:  []string{`$["outputs"]["token"]`},
+			},
+			`{"type":"deploy.complete","data":{"updateID":"2","ok":true,"old":false,"errors":[],"outputs":{"token":"` + project.SecretMask + `","url":"https://example.com"},"hints":null}}`,
+		},
+		{
+			&aws.FunctionLogEvent{FunctionID: "fn", RequestID: "req", WorkerID: "w", Line: "hello"},
+			`{"type":"function.log","data":{"functionID":"fn","requestID":"req","line":"hello"}}`,
+		},
+		{
+			&monoplexer.ProcessStatusEvent{Name: "web", Status: monoplexer.StatusExited, Code: 1},
+			`{"type":"process.status","data":{"name":"web","status":"exited","code":1}}`,
+		},
+		{
+			&apitype.DiagnosticEvent{Severity: "error", Message: "boom\n", URN: "urn"},
+			`{"type":"error","data":{"source":"resource","message":"boom","urn":"urn"}}`,
+		},
+	}
+	for _, c := range cases {
+		events := Convert(c.input)
+		if len(events) != 1 {
+			t.Fatalf("expected one event for %T, got %d", c.input, len(events))
+		}
+		if events[0].Version != Version || events[0].Time.IsZero() {
+			t.Errorf("missing version or time for %T", c.input)
+		}
+		data, _ := json.Marshal(struct {
+			Type string `json:"type"`
+			Data any    `json:"data"`
+		}{events[0].Type, events[0].Data})
+		if string(data) != c.expected {
+			t.Errorf("got %s, want %s", data, c.expected)
+		}
+	}
+}
+
+func TestConvertSkips(t *testing.T) {
+	skipped := []any{
+		&apitype.DiagnosticEvent{Severity: "info", Message: "hi"},
+		&apitype.ResourcePreEvent{Metadata: apitype.StepEventMetadata{Op: apitype.OpSame}},
+		&apitype.ResourcePreEvent{Metadata: apitype.StepEventMetadata{Op: apitype.OpCreate, Type: "pulumi:pulumi:Stack"}},
+		&project.CancelledEvent{},
+	}
+	for _, evt := range skipped {
+		if events := Convert(evt); len(events) != 0 {
+			t.Errorf("expected %T to be skipped, got %v", evt, events)
+		}
+	}
+}
+
+func TestStart(t *testing.T) {
+	events := make(chan any, 2)
+	events <- &project.SkipEvent{}
+	events <- &project.CancelledEvent{}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	output := &strings.Builder{}
+	if err := Start(ctx, output, events); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	if len(lines) != 1 || !strings.Contains(lines[0], `"type":"deploy.skipped"`) {
+		t.Fatalf("unexpected output %q", output.String())
+	}
+}
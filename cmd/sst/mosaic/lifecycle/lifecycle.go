@@ -0,0 +1,261 @@
+// Package lifecycle has the pieces the multiplexer and monoplexer share to
+// order dev processes, wait for them to be ready and restart them.
+package lifecycle
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type Restart string
+
+const (
+	RestartNever     Restart = "never"
+	RestartOnFailure Restart = "on-failure"
+	RestartAlways    Restart = "always"
+)
+
+const (
+	minBackoff = time.Second
+	maxBackoff = 30 * time.Second
+	// a process that stayed up this long starts over with the shortest backoff
+	stableAfter = time.Minute
+	probeEvery  = 500 * time.Millisecond
+)
+
+// Config is how a process is started and kept running.
+type Config struct {
+	// DependsOn are the keys of the processes that have to be ready first.
+	DependsOn []string
+	Ready     *Probe
+	Restart   Restart
+}
+
+// ShouldRestart reports whether a process that exited with the given code
+// should be started again. Processes stopped by the user are never restarted.
+func (c Config) ShouldRestart(code int) bool {
+	switch c.Restart {
+	case RestartAlways:
+		return true
+	case RestartOnFailure:
+		return code != 0
+	}
+	return false
+}
+
+// Backoff returns how long to wait before restarting a process that has
+// already been restarted the given number of times.
+func Backoff(restarts int) time.Duration {
+	delay := minBackoff
+	for i := 0; i < restarts && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, maxBackoff)
+}
+
+// Stable reports whether a process ran long enough for its restart count to
+// be reset.
+func Stable(started time.Time) bool {
+	return time.Since(started) >= stableAfter
+}
+
+// Probe decides when a process is ready. Every check that is set has to
+// pass, with none set a process is ready as soon as it starts.
+type Probe struct {
+	// Port is a TCP port on localhost that accepts connections.
+	Port int
+	// URL answers a GET with a status below 500.
+	URL string
+	// Log is a regular expression matched against each line of output.
+	Log string
+}
+
+func (p *Probe) Validate() error {
+	if p == nil || p.Log == "" {
+		return nil
+	}
+	_, err := regexp.Compile(p.Log)
+	return err
+}
+
+// Check is a single run of a probe. It is an io.Writer so the output of the
+// process can be fed to it for the log check.
+type Check struct {
+	probe   *Probe
+	log     *regexp.Regexp
+	matched atomic.Bool
+
+	mu      sync.Mutex
+	partial []byte
+}
+
+func (p *Probe) Start() *Check {
+	check := &Check{probe: p}
+	if p != nil && p.Log != "" {
+		check.log, _ = regexp.Compile(p.Log)
+	}
+	return check
+}
+
+var ansi = regexp.MustCompile(`\x1b\[[0-9;?]*[ -/]*[@-~]|\x1b\][^\x07]*\x07`)
+
+func (c *Check) Write(data []byte) (int, error) {
+	if c.log == nil || c.matched.Load() {
+		return len(data), nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.partial = append(c.partial, data...)
+	for {
+		index := bytes.IndexAny(c.partial, "\r\n")
+		if index == -1 {
+			break
+		}
+		c.Line(string(c.partial[:index]))
+		c.partial = c.partial[index+1:]
+	}
+	if len(c.partial) > 64*1024 {
+		c.partial = c.partial[len(c.partial)-64*1024:]
+	}
+	// keep an unterminated prompt like "ready> " matchable
+	if len(c.partial) > 0 && c.log.Match(ansi.ReplaceAll(c.partial, nil)) {
+		c.matched.Store(true)
+	}
+	return len(data), nil
+}
+
+// Line feeds a single line of output to the log check.
+func (c *Check) Line(line string) {
+	if c.log == nil || c.matched.Load() {
+		return
+	}
+	if c.log.MatchString(ansi.ReplaceAllString(line, "")) {
+		c.matched.Store(true)
+	}
+}
+
+// Wait blocks until every check of the probe passes or the context is done.
+func (c *Check) Wait(ctx context.Context) error {
+	if c.probe == nil {
+		return nil
+	}
+	client := &http.Client{Timeout: 2 * time.Second}
+	ticker := time.NewTicker(probeEvery)
+	defer ticker.Stop()
+	for {
+		if c.passed(ctx, client) {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *Check) passed(ctx context.Context, client *http.Client) bool {
+	if c.log != nil && !c.matched.Load() {
+		return false
+	}
+	if c.probe.Port != 0 {
+		conn, err := net.DialTimeout("tcp", fmt.Sprintf("127.0.0.1:%d", c.probe.Port), time.Second)
+		if err != nil {
+			return false
+		}
+		conn.Close()
+	}
+	if c.probe.URL != "" {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.probe.URL, nil)
+		if err != nil {
+			return false
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return false
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 500 {
+			return false
+		}
+	}
+	return true
+}
+
+// Node is a process in the dependency graph.
+type Node struct {
+	DependsOn []string
+	Autostart bool
+}
+
+// ValidateGraph checks that every dependency is a process that starts on its
+// own and that no process ends up waiting on itself.
+func ValidateGraph(nodes map[string]Node) error {
+	keys := make([]string, 0, len(nodes))
+	for key := range nodes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		for _, dep := range nodes[key].DependsOn {
+			match, ok := nodes[dep]
+			if !ok {
+				return fmt.Errorf("%q depends on %q, which is not a dev process", key, dep)
+			}
+			if !match.Autostart {
+				return fmt.Errorf("%q depends on %q, which has autostart disabled and would never be ready", key, dep)
+			}
+		}
+	}
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := map[string]int{}
+	var visit func(key string, path []string) error
+	visit = func(key string, path []string) error {
+		path = append(path, key)
+		switch state[key] {
+		case visiting:
+			start := slices.Index(path, key)
+			return fmt.Errorf("there is a cycle: %s", strings.Join(path[start:], " → "))
+		case done:
+			return nil
+		}
+		state[key] = visiting
+		for _, dep := range nodes[key].DependsOn {
+			if err := visit(dep, path); err != nil {
+				return err
+			}
+		}
+		state[key] = done
+		return nil
+	}
+	for _, key := range keys {
+		if err := visit(key, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Waiting returns the dependencies that are not ready yet.
+func Waiting(deps []string, ready func(key string) bool) []string {
+	result := []string{}
+	for _, dep := range deps {
+		if !ready(dep) {
+			result = append(result, dep)
+		}
+	}
+	return result
+}
@@ -0,0 +1,132 @@
+package lifecycle
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	expected := []time.Duration{
+		time.Second,
+		2 * time.Second,
+		4 * time.Second,
+		8 * time.Second,
+		16 * time.Second,
+		30 * time.Second,
+		30 * time.Second,
+	}
+	for restarts, want := range expected {
+		if got := Backoff(restarts); got != want {
+			t.Errorf("Backoff(%d) = %s, want %s", restarts, got, want)
+		}
+	}
+}
+
+func TestShouldRestart(t *testing.T) {
+	cases := []struct {
+		restart Restart
+		code    int
+		want    bool
+	}{
+		{"", 1, false},
+		{RestartNever, 1, false},
+		{RestartOnFailure, 0, false},
+		{RestartOnFailure, 1, true},
+		{RestartOnFailure, -1, true},
+		{RestartAlways, 0, true},
+	}
+	for _, c := range cases {
+		if got := (Config{Restart: c.restart}).ShouldRestart(c.code); got != c.want {
+			t.Errorf("%q with exit code %d: got %v, want %v", c.restart, c.code, got, c.want)
+		}
+	}
+}
+
+func TestLogCheck(t *testing.T) {
+	check := (&Probe{Log: `listening on :\d+`}).Start()
+	check.Write([]byte("starting\n\x1b[32mlisten"))
+	if check.matched.Load() {
+		t.Fatal("matched before the line was complete")
+	}
+	check.Write([]byte("ing on :3000\x1b[0m\n"))
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := check.Wait(ctx); err != nil {
+		t.Fatalf("expected the log check to pass: %v", err)
+	}
+}
+
+func TestPortCheck(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	check := (&Probe{Port: port}).Start()
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if err := check.Wait(ctx); err == nil {
+		t.Fatal("expected the port check to fail while nothing listens")
+	}
+
+	listener, err = net.Listen("tcp", listener.Addr().String())
+	if err != nil {
+		t.Skip("port was taken in the meantime")
+	}
+	defer listener.Close()
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := check.Wait(ctx); err != nil {
+		t.Fatalf("expected the port check to pass: %v", err)
+	}
+}
+
+func TestWaiting(t *testing.T) {
+	ready := map[string]bool{"db": true}
+	got := Waiting([]string{"db", "api"}, func(key string) bool { return ready[key] })
+	if len(got) != 1 || got[0] != "api" {
+		t.Fatalf("got %v, want [api]", got)
+	}
+}
+
+func TestValidateGraph(t *testing.T) {
+	cases := []struct {
+		name  string
+		nodes map[string]Node
+		err   string
+	}{
+		{"valid", map[string]Node{
+			"api": {DependsOn: []string{"db"}, Autostart: true},
+			"db":  {Autostart: true},
+			"web": {DependsOn: []string{"api", "db"}},
+		}, ""},
+		{"missing", map[string]Node{
+			"api": {DependsOn: []string{"db"}, Autostart: true},
+		}, `"api" depends on "db", which is not a dev process`},
+		{"autostart disabled", map[string]Node{
+			"api": {DependsOn: []string{"db"}, Autostart: true},
+			"db":  {},
+		}, `"api" depends on "db", which has autostart disabled and would never be ready`},
+		{"self", map[string]Node{
+			"api": {DependsOn: []string{"api"}, Autostart: true},
+		}, "there is a cycle: api → api"},
+		{"cycle", map[string]Node{
+			"api":    {DependsOn: []string{"worker"}, Autostart: true},
+			"worker": {DependsOn: []string{"queue"}, Autostart: true},
+			"queue":  {DependsOn: []string{"api"}, Autostart: true},
+		}, "there is a cycle: api → worker → queue → api"},
+	}
+	for _, item := range cases {
+		err := ValidateGraph(item.nodes)
+		if item.err == "" && err != nil {
+			t.Errorf("%s: unexpected error %v", item.name, err)
+		}
+		if item.err != "" && (err == nil || err.Error() != item.err) {
+			t.Errorf("%s: expected %q, got %v", item.name, item.err, err)
+		}
+	}
+}
@@ -7,33 +7,78 @@ import (
 	"io"
 	"os"
 	"os/exec"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/sst/sst/v3/cmd/sst/mosaic/lifecycle"
+	"github.com/sst/sst/v3/pkg/bus"
 	"github.com/sst/sst/v3/pkg/process"
 )
 
 type Monoplexer struct {
+	mu        sync.Mutex
 	processes map[string]*Process
 	lines     chan Line
+	headless  bool
 }
 
+// ProcessStatusEvent is published whenever a process is started, becomes
+// ready, exits or is scheduled to restart.
+type ProcessStatusEvent struct {
+	Name   string
+	Status string
+	// Code is the exit code for the exited status
+	Code int
+}
+
+// ProcessLogEvent is published for every line a process writes in headless
+// mode, instead of printing it.
+type ProcessLogEvent struct {
+	Name string
+	Line string
+}
+
+const (
+	StatusWaiting    = "waiting"
+	StatusStarted    = "started"
+	StatusReady      = "ready"
+	StatusExited     = "exited"
+	StatusRestarting = "restarting"
+)
+
 type Line struct {
 	process string
+	title   string
 	line    string
 }
 
 type Process struct {
-	name  string
-	title string
-	cmd   *exec.Cmd
-	dir   string
+	name    string
+	title   string
+	command []string
+	cmd     *exec.Cmd
+	dir     string
+	env     []string
+	config  lifecycle.Config
+
+	started time.Time
+	// pending is set until the process is started for the first time
+	pending  bool
+	running  bool
+	ready    bool
+	waiting  []string
+	stopped  bool
+	restarts int
+	cancel   context.CancelFunc
 }
 
 func (p *Process) IsDifferent(title string, command []string, directory string) bool {
-	if len(command) != len(p.cmd.Args) {
+	if len(command) != len(p.command) {
 		return true
 	}
 	for i := range command {
-		if command[i] != p.cmd.Args[i] {
+		if command[i] != p.command[i] {
 			return true
 		}
 	}
@@ -53,59 +98,194 @@ func New() *Monoplexer {
 	}
 }
 
-func (m *Monoplexer) AddProcess(name string, command []string, directory string, title string, env ...string) {
+// NewHeadless creates a monoplexer that publishes the output of its
+// processes on the bus instead of printing it.
+func NewHeadless() *Monoplexer {
+	result := New()
+	result.headless = true
+	return result
+}
+
+func (m *Monoplexer) AddProcess(name string, command []string, directory string, title string, config lifecycle.Config, env ...string) {
+	m.mu.Lock()
 	exists, ok := m.processes[name]
 	if ok {
 		if !exists.IsDifferent(title, command, directory) {
+			exists.config = config
+			m.mu.Unlock()
 			return
 		}
+		exists.stopped = true
+		m.mu.Unlock()
 		m.lines <- Line{
 			line:    "dev config changed, restarting...",
 			process: name,
+			title:   exists.title,
+		}
+		m.mu.Lock()
+		if exists.running {
+			process.Kill(exists.cmd.Process)
 		}
-		process.Kill(exists.cmd.Process)
 		delete(m.processes, name)
 	}
+	m.processes[name] = &Process{
+		name:    name,
+		title:   title,
+		command: command,
+		dir:     directory,
+		env:     env,
+		config:  config,
+		pending: true,
+	}
+	notices := m.startWaiting()
+	m.mu.Unlock()
+	m.print(notices)
+}
+
+// startWaiting starts every process whose dependencies are ready and returns
+// the lines to print for the ones that have to keep waiting. It has to be
+// called with the lock held.
+func (m *Monoplexer) startWaiting() []Line {
+	notices := []Line{}
+	for _, p := range m.processes {
+		if !p.pending || p.stopped {
+			continue
+		}
+		waiting := lifecycle.Waiting(p.config.DependsOn, m.isReady)
+		if len(waiting) > 0 {
+			if strings.Join(waiting, ",") != strings.Join(p.waiting, ",") {
+				bus.Publish(&ProcessStatusEvent{Name: p.name, Status: StatusWaiting})
+				notices = append(notices, Line{
+					process: p.name,
+					title:   p.title,
+					line:    "waiting for " + strings.Join(waiting, ", ") + " to be ready",
+				})
+			}
+			p.waiting = waiting
+			continue
+		}
+		p.waiting = nil
+		p.pending = false
+		m.start(p)
+	}
+	return notices
+}
+
+func (m *Monoplexer) isReady(name string) bool {
+	p, ok := m.processes[name]
+	return ok && p.running && p.ready
+}
+
+func (m *Monoplexer) print(lines []Line) {
+	for _, line := range lines {
+		m.lines <- line
+	}
+}
 
+func (m *Monoplexer) start(p *Process) {
 	r, w := io.Pipe()
-	cmd := process.Command(command[0], command[1:]...)
+	cmd := process.Command(p.command[0], p.command[1:]...)
 	cmd.SysProcAttr = getProcAttr()
 	cmd.Stdout = w
 	cmd.Stderr = w
-	if directory != "" {
-		cmd.Dir = directory
+	if p.dir != "" {
+		cmd.Dir = p.dir
 	}
-	if len(env) > 0 {
-		cmd.Env = append(os.Environ(), env...)
+	if len(p.env) > 0 {
+		cmd.Env = append(os.Environ(), p.env...)
 	}
+	check := p.config.Ready.Start()
 	go func() {
 		// read r line by line
 		scanner := bufio.NewScanner(r)
 		for scanner.Scan() {
+			check.Line(scanner.Text())
 			m.lines <- Line{
 				line:    scanner.Text(),
-				process: name,
+				process: p.name,
+				title:   p.title,
 			}
 		}
 	}()
-	cmd.Start()
-	m.processes[name] = &Process{
-		name:  name,
-		title: title,
-		cmd:   cmd,
-		dir:   directory,
+	if err := cmd.Start(); err != nil {
+		w.Close()
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cmd = cmd
+	p.cancel = cancel
+	p.running = true
+	p.ready = false
+	p.started = time.Now()
+	bus.Publish(&ProcessStatusEvent{Name: p.name, Status: StatusStarted})
+	go func() {
+		if check.Wait(ctx) != nil {
+			return
+		}
+		m.mu.Lock()
+		if p.cmd == cmd {
+			p.ready = true
+			bus.Publish(&ProcessStatusEvent{Name: p.name, Status: StatusReady})
+		}
+		notices := m.startWaiting()
+		m.mu.Unlock()
+		m.print(notices)
+	}()
+	go func() {
+		cmd.Wait()
+		w.Close()
+		m.exited(p, cmd)
+	}()
+}
+
+// exited applies the restart policy once the process is gone.
+func (m *Monoplexer) exited(p *Process, cmd *exec.Cmd) {
+	m.mu.Lock()
+	p.cancel()
+	p.running = false
+	p.ready = false
+	code := cmd.ProcessState.ExitCode()
+	bus.Publish(&ProcessStatusEvent{Name: p.name, Status: StatusExited, Code: code})
+	if p.stopped || m.processes[p.name] != p || !p.config.ShouldRestart(code) {
+		m.mu.Unlock()
+		return
 	}
+	if lifecycle.Stable(p.started) {
+		p.restarts = 0
+	}
+	delay := lifecycle.Backoff(p.restarts)
+	p.restarts++
+	m.mu.Unlock()
+	bus.Publish(&ProcessStatusEvent{Name: p.name, Status: StatusRestarting})
+	m.lines <- Line{
+		process: p.name,
+		title:   p.title,
+		line:    fmt.Sprintf("exited with code %d, restarting in %s", code, delay),
+	}
+	time.AfterFunc(delay, func() {
+		m.mu.Lock()
+		if !p.stopped && m.processes[p.name] == p && !p.running {
+			m.start(p)
+		}
+		m.mu.Unlock()
+	})
 }
+
 func (m *Monoplexer) Start(ctx context.Context) error {
 	for {
 		select {
 		case line := <-m.lines:
-			match, ok := m.processes[line.process]
-			if !ok {
+			if m.headless {
+				bus.Publish(&ProcessLogEvent{Name: line.process, Line: line.line})
 				continue
 			}
-			fmt.Println("["+match.title+"]", line.line)
+			fmt.Println("["+line.title+"]", line.line)
 		case <-ctx.Done():
+			m.mu.Lock()
+			for _, p := range m.processes {
+				p.stopped = true
+			}
+			m.mu.Unlock()
 			return nil
 		}
 	}
@@ -42,9 +42,19 @@ func (s *Multiplexer) draw() {
 			label = item.filter
 			textStyle = textStyle.Italic(true)
 		}
+		icon := item.Icon
+		if item == s.splitPane && s.split == splitVertical {
+			icon = "▐"
+		}
+		if item == s.splitPane && s.split == splitHorizontal {
+			icon = "▄"
+		}
 		title := views.NewTextBar()
 		title.SetStyle(style)
-		title.SetLeft(item.Icon+" "+label, textStyle)
+		title.SetLeft(icon+" "+label, textStyle)
+		if status, statusStyle := item.status(); status != "" {
+			title.SetRight(status, statusStyle)
+		}
 		s.stack.AddWidget(title, 0)
 	}
 	s.stack.AddWidget(views.NewSpacer(), 1)
@@ -68,6 +78,9 @@ func (s *Multiplexer) draw() {
 			if selected.dead {
 				hotkeys["enter"] = "start"
 			}
+			if selected.dead && (selected.restarting || len(selected.waiting) > 0) {
+				hotkeys["x"] = "cancel"
+			}
 		}
 		if !s.focused {
 			hotkeys["j/k/↓/↑"] = "up/down"
@@ -83,6 +96,21 @@ func (s *Multiplexer) draw() {
 			hotkeys["ctrl-g"] = "bottom"
 		}
 		hotkeys["ctrl-l"] = "clear"
+		if selected != nil && !s.focused {
+			hotkeys["/"] = "search"
+			hotkeys["w"] = "save"
+			hotkeys["|/-"] = "split"
+			if selected.search != "" {
+				hotkeys["n/N"] = "next/prev"
+				hotkeys["esc"] = "clear search"
+			}
+		}
+		if s.searching {
+			hotkeys = map[string]string{
+				"esc":   "cancel",
+				"enter": "confirm",
+			}
+		}
 		if selected != nil && !s.focused && selected.Filterable && selected.filterAvailable {
 			hotkeys["f"] = "filter"
 		}
@@ -117,7 +145,10 @@ func (s *Multiplexer) draw() {
 
 	// render virtual terminal
 	if selected != nil && !s.filtering {
-		selected.vt.Draw()
+		if selected != s.splitPane {
+			selected.vt.Draw()
+		}
+		s.drawSplit(selected)
 		if s.focused {
 			y, x, _, _ := selected.vt.Cursor()
 			s.screen.ShowCursor(s.mainX()+x, y+s.contentY())
@@ -125,6 +156,7 @@ func (s *Multiplexer) draw() {
 		if !s.focused {
 			s.screen.HideCursor()
 		}
+		s.drawStatus(selected)
 	}
 
 	if s.filtering {
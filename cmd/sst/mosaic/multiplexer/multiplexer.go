@@ -48,6 +48,15 @@ type Multiplexer struct {
 	filterScroll    int
 	filterSearching bool
 	filterQuery     string
+
+	searching bool
+	// notice is shown below the main area until the next key press
+	notice string
+	logDir string
+
+	split     split
+	splitPane *pane
+	second    *views.ViewPort
 }
 
 type FilterOption struct {
@@ -56,9 +65,11 @@ type FilterOption struct {
 	Value       string
 }
 
-func New() (*Multiplexer, error) {
+// New creates the multiplexer. Scrollback saved from a pane is written to
+// logDir.
+func New(logDir string) (*Multiplexer, error) {
 	var err error
-	result := &Multiplexer{}
+	result := &Multiplexer{logDir: logDir}
 	result.processes = []*pane{}
 	result.screen, err = tcell.NewScreen()
 	if err != nil {
@@ -72,6 +83,7 @@ func New() (*Multiplexer, error) {
 	result.height = height
 	result.root = views.NewViewPort(result.screen, 0, 0, 0, 0)
 	result.main = views.NewViewPort(result.screen, 0, 0, 0, 0)
+	result.second = views.NewViewPort(result.screen, 0, 0, 0, 0)
 	result.stack = views.NewBoxLayout(views.Vertical)
 	result.stack.SetView(result.root)
 	if os.Getenv("TMUX") != "" {
@@ -108,9 +120,16 @@ func (s *Multiplexer) resize(width int, height int) {
 	s.width = width
 	s.height = height
 	s.root.Resize(PAD_WIDTH, s.contentY(), s.sidebarWidth(), s.mainHeight())
-	s.main.Resize(s.mainX(), s.contentY(), s.mainWidth(), s.mainHeight())
+	pw, ph := s.paneRect()
+	s.main.Resize(s.mainX(), s.contentY(), pw, ph)
+	s.second.Resize(s.secondRect())
 	mw, mh := s.main.Size()
+	sw, sh := s.second.Size()
 	for _, p := range s.processes {
+		if p == s.splitPane {
+			p.vt.Resize(sw, sh)
+			continue
+		}
 		p.vt.Resize(mw, mh)
 	}
 }
@@ -182,8 +201,11 @@ func (s *Multiplexer) Start() {
 			case *EventProcess:
 				for _, p := range s.processes {
 					if p.Key == evt.Key {
-						if p.dead && evt.Autostart {
-							p.start()
+						p.DependsOn = evt.DependsOn
+						p.Ready = evt.Ready
+						p.Restart = evt.Restart
+						if p.dead && evt.Autostart && !p.restarting {
+							s.start(p)
 							s.sort()
 							s.draw()
 						}
@@ -198,7 +220,7 @@ func (s *Multiplexer) Start() {
 				})
 				proc.vt = term
 				if evt.Autostart {
-					proc.start()
+					s.start(proc)
 				}
 				if !evt.Autostart {
 					proc.vt.Start(process.Command("echo", ui.TEXT_DIM.Render(evt.Key+" has auto-start disabled, press enter to start.")))
@@ -278,6 +300,9 @@ func (s *Multiplexer) Start() {
 						if selected == nil {
 							return
 						}
+						if s.split != splitNone && !s.dragging && (!s.inPane(x, y) || selected == s.splitPane) {
+							return
+						}
 						if !s.dragging && (contentY < 0 || contentY > maxContentY) {
 							return
 						}
@@ -327,33 +352,93 @@ func (s *Multiplexer) Start() {
 				if s.filtering {
 					return
 				}
-				if selected != nil && selected.vt == evt.VT() {
+				if selected != nil && selected.vt == evt.VT() && selected != s.splitPane {
 					selected.vt.Draw()
 					s.screen.Show()
 				}
+				if s.splitPane != nil && s.splitPane.vt == evt.VT() {
+					s.splitPane.vt.Draw()
+					s.screen.Show()
+				}
 				return
 
 			case *tcellterm.EventClosed:
-				for index, proc := range s.processes {
-					if proc.vt == evt.VT() {
-						if !proc.dead {
-							proc.vt.Start(process.Command("echo", "\n"+ui.TEXT_DIM.Render("[process exited]")))
-							proc.dead = true
-							s.sort()
-							if index == s.selected {
-								s.blur()
-							}
-						}
+				for _, proc := range s.processes {
+					if proc.vt != evt.VT() || proc.dead || proc.cmd == nil || proc.reaping == proc.cmd {
+						continue
 					}
+					// the output closing can also come from an earlier command of
+					// the pane, so only the exit of this one marks it as dead
+					cmd := proc.cmd
+					run := proc.run
+					proc.reaping = cmd
+					go func() {
+						cmd.Wait()
+						code := -1
+						if cmd.ProcessState != nil {
+							code = cmd.ProcessState.ExitCode()
+						}
+						s.screen.PostEvent(&EventExited{pane: proc, run: run, code: code})
+					}()
+				}
+				return
+
+			case *EventExited:
+				proc := evt.pane
+				if evt.run != proc.run || proc.dead {
+					return
+				}
+				if proc.cancel != nil {
+					proc.cancel()
+				}
+				proc.dead = true
+				proc.ready = false
+				message := "[process exited]"
+				if delay, ok := s.exited(proc, evt.code); ok {
+					message = fmt.Sprintf("[process exited, restarting in %s]", delay)
+				}
+				proc.vt.Start(process.Command("echo", "\n"+ui.TEXT_DIM.Render(message)))
+				s.sort()
+				if proc == s.selectedProcess() {
+					s.blur()
+				}
+				s.draw()
+				return
+
+			case *EventReady:
+				if evt.run != evt.pane.run || evt.pane.dead {
+					return
+				}
+				evt.pane.ready = true
+				s.startWaiting()
+				s.sort()
+				s.draw()
+				return
+
+			case *EventRestart:
+				proc := evt.pane
+				if evt.run != proc.run || !proc.restarting {
+					return
 				}
+				proc.restarting = false
+				s.start(proc)
+				s.sort()
 				s.draw()
 				return
 
 			case *tcell.EventKey:
+				if s.notice != "" {
+					s.notice = ""
+					s.draw()
+				}
 				if s.filtering && evt.Key() != tcell.KeyCtrlC {
 					s.handleFilterKey(evt)
 					return
 				}
+				if s.searching && evt.Key() != tcell.KeyCtrlC {
+					s.handleSearchKey(evt)
+					return
+				}
 				switch evt.Key() {
 				case 256:
 					switch evt.Rune() {
@@ -371,6 +456,39 @@ func (s *Multiplexer) Start() {
 						if selected.Killable && !selected.dead && !s.focused {
 							selected.Kill()
 						}
+						if selected.Killable && selected.dead && !s.focused && (selected.restarting || len(selected.waiting) > 0) {
+							// cancel the pending start
+							selected.run++
+							selected.restarting = false
+							selected.waiting = nil
+							selected.stopped = true
+							s.draw()
+						}
+					case '/':
+						if !s.focused {
+							s.startSearch()
+							return
+						}
+					case 'n', 'N':
+						if !s.focused {
+							s.searchNext(evt.Rune() == 'N')
+							return
+						}
+					case 'w':
+						if !s.focused {
+							s.save()
+							return
+						}
+					case '|':
+						if !s.focused {
+							s.toggleSplit(splitVertical)
+							return
+						}
+					case '-':
+						if !s.focused {
+							s.toggleSplit(splitHorizontal)
+							return
+						}
 					case 'f':
 						if !s.focused && selected != nil && selected.Filterable && selected.filterAvailable && selected.ListOptions != nil {
 							options := selected.ListOptions()
@@ -428,7 +546,8 @@ func (s *Multiplexer) Start() {
 					if !s.focused {
 						if selected.Killable {
 							if selected.dead {
-								selected.start()
+								selected.restarts = 0
+								s.run(selected)
 								s.sort()
 								s.draw()
 								return
@@ -446,6 +565,10 @@ func (s *Multiplexer) Start() {
 						return
 					}
 				case tcell.KeyEscape:
+					if !s.focused && selected != nil && selected.search != "" {
+						s.clearSearch(selected)
+						return
+					}
 					if !s.focused && selected != nil && selected.filter != "" {
 						s.clearPaneFilter(selected)
 						return
@@ -1,10 +1,16 @@
 package multiplexer
 
 import (
+	"context"
+	"fmt"
 	"os/exec"
+	"strings"
+	"time"
 
 	"github.com/gdamore/tcell/v2"
+	"github.com/sst/sst/v3/cmd/sst/mosaic/lifecycle"
 	tcellterm "github.com/sst/sst/v3/cmd/sst/mosaic/multiplexer/tcell-term"
+	"github.com/sst/sst/v3/cmd/sst/mosaic/ui"
 	"github.com/sst/sst/v3/pkg/process"
 )
 
@@ -22,6 +28,11 @@ type PaneConfig struct {
 	FilterSubtitle  string
 	ListOptions     func() []FilterOption
 	OnFilterChanged func(string)
+	// DependsOn are the keys of the panes that have to be ready before this
+	// one is started.
+	DependsOn []string
+	Ready     *lifecycle.Probe
+	Restart   lifecycle.Restart
 }
 
 type pane struct {
@@ -31,6 +42,39 @@ type pane struct {
 	dead            bool
 	cmd             *exec.Cmd
 	filter          string
+	search          string
+	// reaping is the command that is being waited on for its exit code
+	reaping *exec.Cmd
+
+	// run is bumped on every start so events from an earlier run are ignored
+	run        int
+	started    time.Time
+	cancel     context.CancelFunc
+	ready      bool
+	waiting    []string
+	stopped    bool
+	restarting bool
+	restarts   int
+	exitCode   int
+}
+
+type EventReady struct {
+	tcell.EventTime
+	pane *pane
+	run  int
+}
+
+type EventExited struct {
+	tcell.EventTime
+	pane *pane
+	run  int
+	code int
+}
+
+type EventRestart struct {
+	tcell.EventTime
+	pane *pane
+	run  int
 }
 
 type EventProcess struct {
@@ -60,9 +104,120 @@ func (p *pane) start() error {
 }
 
 func (p *pane) Kill() {
+	p.stopped = true
 	p.vt.Close()
 }
 
+func (p *pane) lifecycle() lifecycle.Config {
+	return lifecycle.Config{
+		DependsOn: p.DependsOn,
+		Ready:     p.Ready,
+		Restart:   p.Restart,
+	}
+}
+
+// status is shown next to the title in the sidebar.
+func (p *pane) status() (string, tcell.Style) {
+	switch {
+	case len(p.waiting) > 0:
+		return "waiting", tcell.StyleDefault.Foreground(tcell.ColorGray)
+	case p.restarting:
+		return "restarting", tcell.StyleDefault.Foreground(tcell.ColorYellow)
+	case !p.dead && p.Ready != nil && !p.ready:
+		return "starting", tcell.StyleDefault.Foreground(tcell.ColorYellow)
+	case !p.dead && p.Ready != nil && p.ready:
+		return "ready", tcell.StyleDefault.Foreground(tcell.ColorGreen)
+	case p.dead && p.exitCode > 0:
+		return fmt.Sprintf("exit %d", p.exitCode), tcell.StyleDefault.Foreground(tcell.ColorRed)
+	}
+	return "", tcell.StyleDefault
+}
+
+// start runs the pane once its dependencies are ready, otherwise it waits
+// for them.
+func (s *Multiplexer) start(p *pane) {
+	waiting := lifecycle.Waiting(p.DependsOn, s.isReady)
+	if len(waiting) == 0 {
+		s.run(p)
+		return
+	}
+	changed := strings.Join(waiting, ",") != strings.Join(p.waiting, ",")
+	p.waiting = waiting
+	if changed {
+		p.vt.Start(process.Command("echo", ui.TEXT_DIM.Render("Waiting for "+strings.Join(waiting, ", ")+" to be ready, press enter to start now.")))
+	}
+	p.dead = true
+}
+
+// run starts the pane right away, regardless of its dependencies.
+func (s *Multiplexer) run(p *pane) {
+	if p.cancel != nil {
+		p.cancel()
+	}
+	p.waiting = nil
+	p.ready = false
+	p.stopped = false
+	p.restarting = false
+	p.exitCode = 0
+	check := p.Ready.Start()
+	if p.Ready != nil && p.Ready.Log != "" {
+		p.vt.Output = check
+	}
+	err := p.start()
+	p.vt.Output = nil
+	if err != nil {
+		return
+	}
+	p.run++
+	p.started = time.Now()
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+	run := p.run
+	go func() {
+		if check.Wait(ctx) == nil {
+			s.screen.PostEvent(&EventReady{pane: p, run: run})
+		}
+	}()
+}
+
+func (s *Multiplexer) isReady(key string) bool {
+	for _, p := range s.processes {
+		if p.Key == key {
+			return !p.dead && p.ready
+		}
+	}
+	return false
+}
+
+// startWaiting starts the panes whose dependencies just became ready.
+func (s *Multiplexer) startWaiting() {
+	for _, p := range s.processes {
+		if len(p.waiting) > 0 {
+			s.start(p)
+		}
+	}
+}
+
+// exited is called once the process of a pane is gone and applies its
+// restart policy. It returns how long until the pane is started again.
+func (s *Multiplexer) exited(p *pane, code int) (time.Duration, bool) {
+	p.exitCode = code
+	if p.stopped || !p.lifecycle().ShouldRestart(code) {
+		return 0, false
+	}
+	if lifecycle.Stable(p.started) {
+		p.restarts = 0
+	}
+	delay := lifecycle.Backoff(p.restarts)
+	p.restarts++
+	p.restarting = true
+	run := p.run
+	time.AfterFunc(delay, func() {
+		s.screen.PostEvent(&EventRestart{pane: p, run: run})
+	})
+	return delay, true
+}
+
 func (s *pane) scrollUp(offset int) {
 	s.vt.ScrollUp(offset)
 }
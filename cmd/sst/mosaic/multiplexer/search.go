@@ -0,0 +1,206 @@
+package multiplexer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+type split int
+
+const (
+	splitNone split = iota
+	// splitVertical shows the pinned pane to the right
+	splitVertical
+	// splitHorizontal shows the pinned pane below
+	splitHorizontal
+)
+
+func (s *Multiplexer) startSearch() {
+	selected := s.selectedProcess()
+	if selected == nil {
+		return
+	}
+	s.searching = true
+	selected.search = ""
+	selected.vt.ClearSearch()
+	s.draw()
+}
+
+func (s *Multiplexer) handleSearchKey(evt *tcell.EventKey) {
+	selected := s.selectedProcess()
+	if selected == nil {
+		s.searching = false
+		return
+	}
+	switch evt.Key() {
+	case tcell.KeyEscape:
+		s.searching = false
+		s.clearSearch(selected)
+		return
+	case tcell.KeyEnter:
+		s.searching = false
+		if selected.search == "" {
+			s.clearSearch(selected)
+			return
+		}
+	case tcell.KeyBackspace, tcell.KeyBackspace2:
+		if len(selected.search) == 0 {
+			return
+		}
+		runes := []rune(selected.search)
+		selected.search = string(runes[:len(runes)-1])
+		selected.vt.Search(selected.search)
+	case tcell.KeyRune:
+		selected.search += string(evt.Rune())
+		selected.vt.Search(selected.search)
+	default:
+		return
+	}
+	s.draw()
+	s.screen.Sync()
+}
+
+func (s *Multiplexer) clearSearch(p *pane) {
+	p.search = ""
+	p.vt.ClearSearch()
+	p.scrollReset()
+	s.draw()
+	s.screen.Sync()
+}
+
+func (s *Multiplexer) searchNext(backwards bool) {
+	selected := s.selectedProcess()
+	if selected == nil || selected.search == "" {
+		return
+	}
+	selected.vt.SearchNext(backwards)
+	s.draw()
+	s.screen.Sync()
+}
+
+// save writes the full scrollback of the selected pane to the log directory.
+func (s *Multiplexer) save() {
+	selected := s.selectedProcess()
+	if selected == nil {
+		return
+	}
+	name := strings.NewReplacer("/", "-", "\\", "-", " ", "-").Replace(selected.Key)
+	path := filepath.Join(s.logDir, fmt.Sprintf("%s-%s.log", name, time.Now().Format("20060102-150405")))
+	err := os.MkdirAll(s.logDir, 0755)
+	if err == nil {
+		err = os.WriteFile(path, []byte(selected.vt.Text()), 0644)
+	}
+	s.notice = "Saved to " + path
+	if err != nil {
+		s.notice = "Failed to save: " + err.Error()
+	}
+	s.draw()
+}
+
+// toggleSplit pins the selected pane next to or below the main area so it
+// can be watched while other panes are selected. Toggling the same split
+// again closes it.
+func (s *Multiplexer) toggleSplit(kind split) {
+	selected := s.selectedProcess()
+	if s.splitPane != nil {
+		s.splitPane.vt.SetSurface(s.main)
+	}
+	if s.split == kind && (selected == nil || selected == s.splitPane) {
+		s.split = splitNone
+		s.splitPane = nil
+	} else if selected != nil {
+		s.split = kind
+		s.splitPane = selected
+		selected.vt.SetSurface(s.second)
+	}
+	s.resize(s.width, s.height)
+	s.draw()
+	s.screen.Sync()
+}
+
+// paneRect is the area of the main pane, which shrinks when split.
+func (s *Multiplexer) paneRect() (int, int) {
+	width, height := s.mainRect()
+	switch s.split {
+	case splitVertical:
+		return max(0, (width-MAIN_PAD_WIDTH)/2), height
+	case splitHorizontal:
+		return width, max(0, (height-1)/2)
+	}
+	return width, height
+}
+
+// secondRect is the position and size of the pinned pane.
+func (s *Multiplexer) secondRect() (int, int, int, int) {
+	width, height := s.mainRect()
+	paneWidth, paneHeight := s.paneRect()
+	switch s.split {
+	case splitVertical:
+		return s.mainX() + paneWidth + MAIN_PAD_WIDTH, s.contentY(), max(0, width-paneWidth-MAIN_PAD_WIDTH), height
+	case splitHorizontal:
+		return s.mainX(), s.contentY() + paneHeight + 1, width, max(0, height-paneHeight-1)
+	}
+	return 0, 0, 0, 0
+}
+
+// inPane reports whether a point on the screen is on the main pane.
+func (s *Multiplexer) inPane(x int, y int) bool {
+	width, height := s.paneRect()
+	return x >= s.mainX() && x < s.mainX()+width && y >= s.contentY() && y < s.contentY()+height
+}
+
+func (s *Multiplexer) drawSplit(selected *pane) {
+	if s.split == splitNone || s.splitPane == nil {
+		return
+	}
+	style := tcell.StyleDefault.Foreground(tcell.ColorGray).Dim(true)
+	x, y, width, height := s.secondRect()
+	if s.split == splitVertical {
+		for i := y; i < y+height; i++ {
+			s.screen.SetContent(x-MAIN_PAD_WIDTH/2-1, i, '│', nil, style)
+		}
+	}
+	if s.split == splitHorizontal {
+		for i := x; i < x+width; i++ {
+			s.screen.SetContent(i, y-1, '─', nil, style)
+		}
+	}
+	s.splitPane.vt.Draw()
+	if selected == s.splitPane {
+		s.drawLine(s.mainX(), s.contentY(), "Select another pane to show next to "+s.splitPane.Title, tcell.StyleDefault.Foreground(tcell.ColorGray), s.mainWidth())
+	}
+}
+
+// drawStatus fills the row below the main area with the search prompt or
+// the last notice.
+func (s *Multiplexer) drawStatus(selected *pane) {
+	y := s.height - 1
+	if y < s.contentY() || selected == nil {
+		return
+	}
+	dim := tcell.StyleDefault.Foreground(tcell.ColorGray)
+	if s.searching || selected.search != "" {
+		x := s.drawLine(s.mainX(), y, "/", dim, s.mainWidth())
+		x = s.drawLine(x, y, selected.search, tcell.StyleDefault, s.mainWidth()-(x-s.mainX()))
+		if s.searching {
+			s.screen.ShowCursor(x, y)
+		}
+		current, total := selected.vt.SearchStatus()
+		if selected.search != "" {
+			label := "no matches"
+			if total > 0 {
+				label = fmt.Sprintf("%d/%d", current, total)
+			}
+			s.drawLine(x+2, y, label, dim, s.mainWidth()-(x+2-s.mainX()))
+		}
+		return
+	}
+	if s.notice != "" {
+		s.drawLine(s.mainX(), y, s.notice, dim, s.mainWidth())
+	}
+}
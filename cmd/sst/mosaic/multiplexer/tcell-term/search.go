@@ -0,0 +1,208 @@
+package tcellterm
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// Match is a search result. Line counts from the start of the scrollback, so
+// it stays the same while new output pushes lines off the screen.
+type Match struct {
+	Line int
+	Col  int
+	Len  int
+}
+
+type search struct {
+	query   []rune
+	fold    bool
+	matches []Match
+	current int
+}
+
+var (
+	matchStyle   = tcell.StyleDefault.Background(tcell.ColorOlive).Foreground(tcell.ColorBlack)
+	currentStyle = tcell.StyleDefault.Background(tcell.ColorOrange).Foreground(tcell.ColorBlack)
+)
+
+// Search finds every occurrence of query in the scrollback and on the screen
+// and scrolls to the last one. It is case insensitive unless the query has
+// an uppercase letter. It returns the number of matches.
+func (vt *VT) Search(query string) int {
+	vt.mu.Lock()
+	defer vt.mu.Unlock()
+	if query == "" {
+		vt.search = nil
+		return 0
+	}
+	vt.search = &search{
+		query: []rune(query),
+		fold:  strings.ToLower(query) == query,
+	}
+	vt.findMatches()
+	vt.search.current = len(vt.search.matches) - 1
+	vt.showMatch()
+	return len(vt.search.matches)
+}
+
+// SearchNext moves to the next match further down, or further up when
+// backwards is set. The matches are looked up again first since the output
+// may have changed.
+func (vt *VT) SearchNext(backwards bool) {
+	vt.mu.Lock()
+	defer vt.mu.Unlock()
+	if vt.search == nil {
+		return
+	}
+	var previous *Match
+	if vt.search.current >= 0 && vt.search.current < len(vt.search.matches) {
+		match := vt.search.matches[vt.search.current]
+		previous = &match
+	}
+	vt.findMatches()
+	total := len(vt.search.matches)
+	if total == 0 {
+		vt.search.current = -1
+		return
+	}
+	index := total - 1
+	if previous != nil {
+		// the match after or before the previous one, wrapping around
+		index = 0
+		for i, match := range vt.search.matches {
+			if match.Line < previous.Line || match.Line == previous.Line && match.Col <= previous.Col {
+				index = i + 1
+			}
+		}
+		if backwards {
+			index -= 2
+		}
+	}
+	vt.search.current = (index%total + total) % total
+	vt.showMatch()
+}
+
+// SearchStatus returns the position of the current match, starting at 1, and
+// the number of matches.
+func (vt *VT) SearchStatus() (int, int) {
+	vt.mu.Lock()
+	defer vt.mu.Unlock()
+	if vt.search == nil {
+		return 0, 0
+	}
+	return vt.search.current + 1, len(vt.search.matches)
+}
+
+func (vt *VT) ClearSearch() {
+	vt.mu.Lock()
+	defer vt.mu.Unlock()
+	vt.search = nil
+}
+
+func (vt *VT) findMatches() {
+	vt.search.matches = []Match{}
+	lines := vt.lines()
+	for index, line := range lines {
+		for _, col := range vt.search.find(line) {
+			vt.search.matches = append(vt.search.matches, Match{
+				Line: index,
+				Col:  col,
+				Len:  len(vt.search.query),
+			})
+		}
+	}
+}
+
+// find returns the columns in a row where the query starts.
+func (s *search) find(cells []cell) []int {
+	result := []int{}
+	if len(s.query) == 0 {
+		return result
+	}
+outer:
+	for col := 0; col+len(s.query) <= len(cells); col++ {
+		for i, r := range s.query {
+			if !s.equal(cells[col+i].rune(), r) {
+				continue outer
+			}
+		}
+		result = append(result, col)
+	}
+	return result
+}
+
+func (s *search) equal(a, b rune) bool {
+	if s.fold {
+		return unicode.ToLower(a) == b
+	}
+	return a == b
+}
+
+// lines is the scrollback followed by the primary screen.
+func (vt *VT) lines() [][]cell {
+	result := make([][]cell, 0, len(vt.primaryScrollback)+len(vt.primaryScreen))
+	result = append(result, vt.primaryScrollback...)
+	return append(result, vt.primaryScreen...)
+}
+
+// showMatch scrolls so the current match is on screen.
+func (vt *VT) showMatch() {
+	if vt.search.current < 0 || vt.search.current >= len(vt.search.matches) {
+		return
+	}
+	line := vt.search.matches[vt.search.current].Line
+	scrollback := len(vt.primaryScrollback)
+	if line >= scrollback {
+		vt.scroll = -1
+		return
+	}
+	vt.scroll = max(0, line-vt.height()/2)
+	if vt.scroll >= scrollback {
+		vt.scroll = -1
+	}
+}
+
+// highlights returns the style of every cell in a row that is part of a
+// match, keyed by column.
+func (vt *VT) highlights(line int, cells []cell) map[int]tcell.Style {
+	if vt.search == nil {
+		return nil
+	}
+	var current *Match
+	if vt.search.current >= 0 && vt.search.current < len(vt.search.matches) {
+		current = &vt.search.matches[vt.search.current]
+	}
+	result := map[int]tcell.Style{}
+	for _, start := range vt.search.find(cells) {
+		style := matchStyle
+		if current != nil && current.Line == line && current.Col == start {
+			style = currentStyle
+		}
+		for col := start; col < start+len(vt.search.query); col++ {
+			result[col] = style
+		}
+	}
+	return result
+}
+
+// Text returns the scrollback and the primary screen as plain text, without
+// trailing whitespace.
+func (vt *VT) Text() string {
+	vt.mu.Lock()
+	defer vt.mu.Unlock()
+	result := strings.Builder{}
+	for _, cells := range vt.lines() {
+		line := strings.Builder{}
+		for _, c := range cells {
+			line.WriteRune(c.rune())
+			for _, comb := range c.combining {
+				line.WriteRune(comb)
+			}
+		}
+		result.WriteString(strings.TrimRightFunc(line.String(), unicode.IsSpace))
+		result.WriteRune('\n')
+	}
+	return strings.TrimRightFunc(result.String(), unicode.IsSpace) + "\n"
+}
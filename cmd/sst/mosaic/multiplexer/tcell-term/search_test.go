@@ -0,0 +1,65 @@
+package tcellterm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeLines(vt *VT, lines ...string) {
+	for _, line := range lines {
+		for _, r := range line {
+			vt.print(r)
+		}
+		vt.cr()
+		vt.lf()
+	}
+}
+
+func TestSearch(t *testing.T) {
+	vt := New()
+	vt.Resize(10, 2)
+	writeLines(vt, "error one", "ok", "Error two", "ok")
+
+	assert.Equal(t, 2, vt.Search("error"))
+	current, total := vt.SearchStatus()
+	assert.Equal(t, 2, current)
+	assert.Equal(t, 2, total)
+	assert.Equal(t, 2, vt.search.matches[1].Line)
+
+	vt.SearchNext(false)
+	current, _ = vt.SearchStatus()
+	assert.Equal(t, 1, current, "wraps around to the first match")
+	assert.True(t, vt.IsScrolling(), "scrolls back to the first match")
+
+	vt.SearchNext(true)
+	current, _ = vt.SearchStatus()
+	assert.Equal(t, 2, current)
+
+	assert.Equal(t, 1, vt.Search("Error"), "uppercase makes it case sensitive")
+	assert.Equal(t, 0, vt.Search("missing"))
+
+	vt.ClearSearch()
+	current, total = vt.SearchStatus()
+	assert.Equal(t, 0, current)
+	assert.Equal(t, 0, total)
+}
+
+func TestHighlights(t *testing.T) {
+	vt := New()
+	vt.Resize(10, 1)
+	writeLines(vt, "ab ab")
+	vt.Search("ab")
+	highlights := vt.highlights(0, vt.primaryScrollback[0])
+	assert.Equal(t, matchStyle, highlights[0])
+	assert.Equal(t, matchStyle, highlights[1])
+	assert.NotContains(t, highlights, 2)
+	assert.Equal(t, currentStyle, highlights[3], "the last match is the current one")
+}
+
+func TestText(t *testing.T) {
+	vt := New()
+	vt.Resize(10, 2)
+	writeLines(vt, "first", "second", "third")
+	assert.Equal(t, "first\nsecond\nthird\n", vt.Text())
+}
@@ -31,6 +31,8 @@ type VT struct {
 	// Set the TERM environment variable to be passed to the command's
 	// environment. If not set, xterm-256color will be used
 	TERM string
+	// If set, Output receives a copy of everything the command writes
+	Output io.Writer
 
 	mu sync.Mutex
 
@@ -64,6 +66,7 @@ type VT struct {
 	mouseBtn tcell.ButtonMask
 
 	selection *selection
+	search    *search
 }
 
 type selection struct {
@@ -171,7 +174,11 @@ func (vt *VT) Start(cmd *exec.Cmd) error {
 	}
 
 	vt.Resize(w, h)
-	vt.parser = NewParser(vt.pty)
+	var reader io.Reader = vt.pty
+	if vt.Output != nil {
+		reader = io.TeeReader(vt.pty, vt.Output)
+	}
+	vt.parser = NewParser(reader)
 	go func() {
 		defer vt.recover()
 		for {
@@ -551,6 +558,7 @@ func (vt *VT) drawRow(row int, cols []cell) {
 	if vt.scroll != -1 {
 		scrollOffset = vt.scroll
 	}
+	highlights := vt.highlights(row+scrollOffset, cols)
 	builder := strings.Builder{}
 	for col := 0; col < len(cols); {
 		cell := cols[col]
@@ -565,6 +573,9 @@ func (vt *VT) drawRow(row int, cols []cell) {
 			style = style.Reverse(true)
 			builder.WriteRune(content)
 		}
+		if highlight, ok := highlights[col]; ok {
+			style = highlight
+		}
 		vt.surface.SetContent(col, row, content, cell.combining, style)
 		if w == 0 {
 			w = 1
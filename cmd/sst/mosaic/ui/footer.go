@@ -30,6 +30,8 @@ type footer struct {
 	downloading map[string]*apitype.ProgressEvent
 	skipped     int
 	cancelled   bool
+	// paused leaves the terminal to a prompt
+	paused bool
 
 	spinner int
 
@@ -56,6 +58,8 @@ func (m *footer) Send(input any) {
 
 type spinnerTick struct{}
 
+type pauseMsg bool
+
 func (m *footer) Start(ctx context.Context) {
 	ticker := time.NewTicker(time.Millisecond * 100)
 	defer ticker.Stop()
@@ -80,9 +84,15 @@ func (m *footer) Start(ctx context.Context) {
 			case lineMsg:
 				m.clear()
 				fmt.Println(evt)
+			case pauseMsg:
+				m.paused = bool(evt)
+				m.clear()
 			default:
 				m.Update(val)
 			}
+			if m.paused {
+				continue
+			}
 			next := m.View(width)
 			m.Render(width, next)
 		}
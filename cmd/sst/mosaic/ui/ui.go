@@ -263,6 +263,28 @@ func (u *UI) Event(unknown interface{}) {
 	case *project.PolicyAdvisoryEvent:
 		u.printEvent(TEXT_WARNING, "Warning", u.FormatURN(evt.URN)+" "+evt.Policy+": "+evt.Message)
 
+	case *project.ResumeEvent:
+		u.printEvent(TEXT_INFO, "Resume", fmt.Sprintf("Retrying %d resources that did not deploy in %s", len(evt.Targets), evt.UpdateID))
+		for _, urn := range evt.Skipped {
+			u.printEvent(TEXT_DIM, "Skipped", u.FormatURN(urn))
+		}
+
+	case *project.ChangePolicyEvent:
+		for _, item := range evt.Violations {
+			u.printEvent(TEXT_WARNING, "Approval", item.Message)
+		}
+		if evt.Approved {
+			u.printEvent(TEXT_SUCCESS, "Approved", "These changes were approved with "+evt.Token)
+		} else {
+			u.printEvent(TEXT_WARNING, "Approval", "Approve these changes with `--approve "+evt.Token+"`")
+		}
+
+	case *project.ProviderUpgradeWarningEvent:
+		u.printEvent(TEXT_WARNING, "Warning", strings.Split(evt.Message, "\n")...)
+
+	case *project.TypesWarningEvent:
+		u.printEvent(TEXT_WARNING, "Types", strings.Split(evt.Message, "\n")...)
+
 	case *project.StackCommandEvent:
 		u.reset()
 		u.header(evt.Version, evt.App, evt.Stage)
@@ -633,6 +655,18 @@ func (u *UI) printEvent(barColor lipgloss.Style, label string, message ...string
 	}
 }
 
+// Pause stops drawing the footer until the returned function is called, so a
+// prompt can be shown while a command is running.
+func (u *UI) Pause() func() {
+	if u.footer == nil {
+		return func() {}
+	}
+	u.footer.Send(pauseMsg(true))
+	return func() {
+		u.footer.Send(pauseMsg(false))
+	}
+}
+
 func (u *UI) Destroy() {
 	if u.footer != nil {
 		u.footer.Destroy()
@@ -0,0 +1,136 @@
+package watcher
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+)
+
+// IgnoreFiles are read from every watched directory. Their patterns apply to
+// that directory and everything below it, like they do in git.
+var IgnoreFiles = []string{".gitignore", ".sstignore"}
+
+// defaultIgnores come before any ignore file so they can be negated, for
+// example with `!.storybook/` in a .sstignore.
+var defaultIgnores = []string{"node_modules/", ".*/"}
+
+type ignore struct {
+	root     string
+	patterns map[string][]gitignore.Pattern
+	matcher  gitignore.Matcher
+}
+
+func newIgnore(root string) *ignore {
+	result := &ignore{
+		root:     root,
+		patterns: map[string][]gitignore.Pattern{},
+	}
+	result.patterns[""] = defaultPatterns()
+	result.build()
+	return result
+}
+
+func defaultPatterns() []gitignore.Pattern {
+	result := []gitignore.Pattern{}
+	for _, line := range defaultIgnores {
+		result = append(result, gitignore.ParsePattern(line, nil))
+	}
+	return result
+}
+
+// load reads the ignore files in dir, replacing any patterns read from it
+// before.
+func (i *ignore) load(dir string) error {
+	rel, err := filepath.Rel(i.root, dir)
+	if err != nil {
+		return err
+	}
+	rel = filepath.ToSlash(rel)
+	if rel == "." {
+		rel = ""
+	}
+	domain := split(rel)
+	patterns := []gitignore.Pattern{}
+	if rel == "" {
+		patterns = defaultPatterns()
+	}
+	for _, name := range IgnoreFiles {
+		file, err := os.Open(filepath.Join(dir, name))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return err
+		}
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if strings.HasPrefix(line, "#") || strings.TrimSpace(line) == "" {
+				continue
+			}
+			patterns = append(patterns, gitignore.ParsePattern(line, domain))
+		}
+		file.Close()
+		if err := scanner.Err(); err != nil {
+			return err
+		}
+	}
+	if len(patterns) == 0 {
+		delete(i.patterns, rel)
+	} else {
+		i.patterns[rel] = patterns
+	}
+	i.build()
+	return nil
+}
+
+// build orders the patterns so deeper directories take precedence.
+func (i *ignore) build() {
+	dirs := make([]string, 0, len(i.patterns))
+	for dir := range i.patterns {
+		dirs = append(dirs, dir)
+	}
+	sort.Slice(dirs, func(a, b int) bool {
+		depthA, depthB := len(split(dirs[a])), len(split(dirs[b]))
+		if depthA != depthB {
+			return depthA < depthB
+		}
+		return dirs[a] < dirs[b]
+	})
+	all := []gitignore.Pattern{}
+	for _, dir := range dirs {
+		all = append(all, i.patterns[dir]...)
+	}
+	i.matcher = gitignore.NewMatcher(all)
+}
+
+// Match reports whether path should not be watched. Paths outside of the
+// root are never ignored.
+func (i *ignore) Match(path string, isDir bool) bool {
+	rel, err := filepath.Rel(i.root, path)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return false
+	}
+	return i.matcher.Match(split(filepath.ToSlash(rel)), isDir)
+}
+
+func split(rel string) []string {
+	if rel == "" {
+		return nil
+	}
+	return strings.Split(rel, "/")
+}
+
+func isIgnoreFile(path string) bool {
+	name := filepath.Base(path)
+	for _, file := range IgnoreFiles {
+		if name == file {
+			return true
+		}
+	}
+	return false
+}
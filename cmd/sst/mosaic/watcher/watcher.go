@@ -2,9 +2,11 @@ package watcher
 
 import (
 	"context"
+	"io/fs"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
@@ -12,13 +14,33 @@ import (
 	"github.com/sst/sst/v3/pkg/bus"
 )
 
-type FileChangedEvent struct {
-	Path string
+// ChangeSetEvent is published once the file system has been quiet for the
+// debounce period, or once the first change has waited for the max wait, with
+// every file that changed since the last one.
+type ChangeSetEvent struct {
+	Paths []string
 }
 
 type WatchConfig struct {
 	Root  string
 	Watch []string
+	// Debounce defaults to DefaultDebounce.
+	Debounce time.Duration
+	// MaxWait is the longest a change waits while files keep changing, it
+	// defaults to MaxWaitFactor times the debounce.
+	MaxWait time.Duration
+}
+
+const (
+	DefaultDebounce = 200 * time.Millisecond
+	MaxWaitFactor   = 10
+)
+
+type watch struct {
+	log     *slog.Logger
+	watcher *fsnotify.Watcher
+	ignore  *ignore
+	matches []string
 }
 
 func Start(ctx context.Context, config WatchConfig) error {
@@ -29,6 +51,7 @@ func Start(ctx context.Context, config WatchConfig) error {
 	if err != nil {
 		return err
 	}
+	defer watcher.Close()
 
 	err = watcher.AddWith(config.Root)
 	if err != nil {
@@ -49,30 +72,18 @@ func Start(ctx context.Context, config WatchConfig) error {
 		}
 	}
 
-	ignoreSubstrings := []string{"node_modules"}
+	w := &watch{
+		log:     log,
+		watcher: watcher,
+		ignore:  newIgnore(config.Root),
+		matches: matches,
+	}
+	err = w.ignore.load(config.Root)
+	if err != nil {
+		return err
+	}
 	for _, match := range matches {
-		err = filepath.Walk(match, func(path string, info os.FileInfo, err error) error {
-			if err != nil {
-				return err
-			}
-			if info.IsDir() {
-				if strings.HasPrefix(info.Name(), ".") {
-					return filepath.SkipDir
-				}
-				for _, substring := range ignoreSubstrings {
-					if strings.Contains(path, substring) {
-						return filepath.SkipDir
-					}
-				}
-
-				log.Info("watching", "path", path)
-				err = watcher.Add(path)
-				if err != nil {
-					return err
-				}
-			}
-			return nil
-		})
+		_, err = w.add(match, true)
 		if err != nil {
 			return err
 		}
@@ -80,27 +91,126 @@ func Start(ctx context.Context, config WatchConfig) error {
 
 	headFile := filepath.Join(config.Root, ".git/HEAD")
 	watcher.Add(headFile)
-	limiter := map[string]time.Time{}
+
+	debounce := config.Debounce
+	if debounce == 0 {
+		debounce = DefaultDebounce
+	}
+	maxWait := config.MaxWait
+	if maxWait == 0 {
+		maxWait = MaxWaitFactor * debounce
+	}
+	timer := time.NewTimer(debounce)
+	timer.Stop()
+	pending := map[string]bool{}
+	// when the oldest pending change was seen
+	var first time.Time
 	for {
 		select {
 		case event, ok := <-watcher.Events:
 			if !ok {
 				return nil
 			}
-			// if event.Name == headFile {
-			// 	return nil
-			// }
 			if event.Op&(fsnotify.Write|fsnotify.Create) == 0 {
 				log.Info("ignoring file event", "path", event.Name, "op", event.Op)
 				continue
 			}
+			info, err := os.Stat(event.Name)
+			isDir := err == nil && info.IsDir()
+			if event.Name != headFile && w.ignore.Match(event.Name, isDir) {
+				log.Info("ignoring file event", "path", event.Name, "op", event.Op, "reason", "ignored")
+				continue
+			}
 			log.Info("file event", "path", event.Name, "op", event.Op)
-			if time.Since(limiter[event.Name]) > 500*time.Millisecond {
-				limiter[event.Name] = time.Now()
-				bus.Publish(&FileChangedEvent{Path: event.Name})
+			if isIgnoreFile(event.Name) {
+				if err := w.ignore.load(filepath.Dir(event.Name)); err != nil {
+					log.Error("failed to read ignore file", "path", event.Name, "err", err)
+				}
+			}
+			if isDir {
+				if event.Op&fsnotify.Create == 0 || !w.within(event.Name) {
+					continue
+				}
+				// files can be written before the directory is watched so
+				// they are part of the change set as well
+				files, err := w.add(event.Name, false)
+				if err != nil {
+					log.Error("failed to watch directory", "path", event.Name, "err", err)
+				}
+				for _, file := range files {
+					pending[file] = true
+				}
+			} else {
+				pending[event.Name] = true
+			}
+			if first.IsZero() {
+				first = time.Now()
+			}
+			timer.Reset(min(debounce, max(maxWait-time.Since(first), 0)))
+		case <-timer.C:
+			first = time.Time{}
+			if len(pending) == 0 {
+				continue
+			}
+			paths := make([]string, 0, len(pending))
+			for path := range pending {
+				paths = append(paths, path)
+			}
+			slices.Sort(paths)
+			pending = map[string]bool{}
+			log.Info("change set", "files", len(paths))
+			bus.Publish(&ChangeSetEvent{Paths: paths})
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
 			}
+			log.Error("watcher error", "err", err)
 		case <-ctx.Done():
 			return nil
 		}
 	}
 }
+
+// add watches dir and every directory below it that is not ignored, and
+// returns the files it found. Errors for paths that disappear while walking
+// are only returned when strict is set.
+func (w *watch) add(dir string, strict bool) ([]string, error) {
+	files := []string{}
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if !strict && os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if !entry.IsDir() {
+			if !w.ignore.Match(path, false) {
+				files = append(files, path)
+			}
+			return nil
+		}
+		if w.ignore.Match(path, true) {
+			return filepath.SkipDir
+		}
+		if err := w.ignore.load(path); err != nil {
+			return err
+		}
+		w.log.Info("watching", "path", path)
+		err = w.watcher.Add(path)
+		if err != nil && !strict && os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	})
+	return files, err
+}
+
+// within reports whether a new directory is covered by the watch globs.
+func (w *watch) within(path string) bool {
+	for _, match := range w.matches {
+		if path == match || strings.HasPrefix(path, match+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
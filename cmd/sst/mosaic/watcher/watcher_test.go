@@ -0,0 +1,156 @@
+package watcher
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sst/sst/v3/pkg/bus"
+)
+
+func write(t *testing.T, path string, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIgnore(t *testing.T) {
+	root := t.TempDir()
+	write(t, filepath.Join(root, ".gitignore"), "dist/\n# comment\n*.log\n")
+	write(t, filepath.Join(root, ".sstignore"), "!.storybook/\n")
+	write(t, filepath.Join(root, "packages/web/.gitignore"), "generated/\n")
+
+	i := newIgnore(root)
+	if err := i.load(root); err != nil {
+		t.Fatal(err)
+	}
+	if err := i.load(filepath.Join(root, "packages/web")); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"src/index.ts", false, false},
+		{"dist", true, true},
+		{"packages/web/dist", true, true},
+		{"debug.log", false, true},
+		{"node_modules", true, true},
+		{"packages/web/node_modules", true, true},
+		{".sst", true, true},
+		{".storybook", true, false},
+		{"packages/web/generated", true, true},
+		{"packages/api/generated", true, false},
+	}
+	for _, c := range cases {
+		if got := i.Match(filepath.Join(root, c.path), c.isDir); got != c.want {
+			t.Errorf("Match(%q) = %v, want %v", c.path, got, c.want)
+		}
+	}
+	if i.Match(root, true) {
+		t.Error("the root is never ignored")
+	}
+}
+
+func TestStart(t *testing.T) {
+	root := t.TempDir()
+	write(t, filepath.Join(root, ".gitignore"), "dist/\n")
+	write(t, filepath.Join(root, "src/index.ts"), "")
+	write(t, filepath.Join(root, "dist/index.js"), "")
+
+	events := bus.Subscribe(&ChangeSetEvent{})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		done <- Start(ctx, WatchConfig{Root: root, Debounce: 100 * time.Millisecond})
+	}()
+	// give the watcher time to walk the tree
+	time.Sleep(200 * time.Millisecond)
+
+	write(t, filepath.Join(root, "src/index.ts"), "a")
+	write(t, filepath.Join(root, "src/index.ts"), "b")
+	write(t, filepath.Join(root, "dist/index.js"), "a")
+	write(t, filepath.Join(root, "src/lib/util.ts"), "a")
+
+	var paths []string
+	select {
+	case evt := <-events:
+		paths = evt.(*ChangeSetEvent).Paths
+	case <-time.After(2 * time.Second):
+		t.Fatal("no change set")
+	}
+	if len(paths) != 2 || paths[0] != filepath.Join(root, "src/index.ts") || paths[1] != filepath.Join(root, "src/lib/util.ts") {
+		t.Fatalf("got %v", paths)
+	}
+
+	// the new directory is watched from now on
+	write(t, filepath.Join(root, "src/lib/util.ts"), "b")
+	select {
+	case evt := <-events:
+		paths = evt.(*ChangeSetEvent).Paths
+	case <-time.After(2 * time.Second):
+		t.Fatal("no change set for the new directory")
+	}
+	if len(paths) != 1 || paths[0] != filepath.Join(root, "src/lib/util.ts") {
+		t.Fatalf("got %v", paths)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStartMaxWait(t *testing.T) {
+	root := t.TempDir()
+	write(t, filepath.Join(root, "src/index.ts"), "")
+
+	events := bus.Subscribe(&ChangeSetEvent{})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		done <- Start(ctx, WatchConfig{Root: root, Debounce: 200 * time.Millisecond, MaxWait: 500 * time.Millisecond})
+	}()
+	time.Sleep(200 * time.Millisecond)
+
+	// keep writing more often than the debounce
+	started := time.Now()
+	stop := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(50 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				os.WriteFile(filepath.Join(root, "src/index.ts"), []byte(time.Now().String()), 0644)
+			}
+		}
+	}()
+	defer close(stop)
+
+	select {
+	case <-events:
+		if elapsed := time.Since(started); elapsed > 2*time.Second {
+			t.Fatalf("change set took %v", elapsed)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("no change set while files kept changing")
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
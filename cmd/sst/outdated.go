@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/briandowns/spinner"
+	"github.com/sst/sst/v3/cmd/sst/cli"
+	"github.com/sst/sst/v3/cmd/sst/mosaic/ui"
+	"github.com/sst/sst/v3/internal/util"
+	"github.com/sst/sst/v3/pkg/project"
+)
+
+var CmdOutdated = &cli.Command{
+	Name: "outdated",
+	Description: cli.Description{
+		Short: "Check for provider updates",
+		Long: strings.Join([]string{
+			"Lists the providers in your app along with the version that's installed, the version your",
+			"config wants, and the latest version in the registry.",
+			"",
+			"```bash frame=\"none\"",
+			"sst outdated",
+			"```",
+			"",
+			"To upgrade a provider to its latest version, run `sst add --upgrade`.",
+			"",
+			"```bash frame=\"none\"",
+			"sst add aws --upgrade",
+			"```",
+			"",
+			"With `--output json` the providers are printed as the result.",
+		}, "\n"),
+	},
+	Run: func(c *cli.Cli) error {
+		cfgPath, err := c.Discover()
+		if err != nil {
+			return err
+		}
+		stage, err := c.Stage(cfgPath)
+		if err != nil {
+			return err
+		}
+		p, err := project.New(&project.ProjectConfig{
+			Version: version,
+			Config:  cfgPath,
+			Stage:   stage,
+		})
+		if err != nil {
+			return err
+		}
+		spin := spinner.New(spinner.CharSets[14], 100*time.Millisecond, spinner.WithWriterFile(os.Stderr))
+		spin.Color("cyan")
+		if c.JSON() {
+			spin.Disable()
+		}
+		spin.Suffix = "  Checking providers..."
+		spin.Start()
+		providers, err := p.Outdated()
+		spin.Stop()
+		if err != nil {
+			return err
+		}
+
+		if c.JSON() {
+			c.SetResult(providers)
+			return nil
+		}
+
+		if len(providers) == 0 {
+			fmt.Println(ui.TEXT_DIM.Render("No providers installed"))
+			return nil
+		}
+		width := len("Provider")
+		for _, provider := range providers {
+			if len(provider.Name) > width {
+				width = len(provider.Name)
+			}
+		}
+		fmt.Println(ui.TEXT_NORMAL_BOLD.Render(fmt.Sprintf("%-*s  %-12s  %-12s  %-12s", width, "Provider", "Current", "Wanted", "Latest")))
+		for _, provider := range providers {
+			style := ui.TEXT_NORMAL
+			if provider.Current != provider.Wanted {
+				style = ui.TEXT_DANGER
+			} else if provider.Current != provider.Latest {
+				style = ui.TEXT_WARNING
+			}
+			fmt.Println(
+				style.Render(fmt.Sprintf("%-*s", width, provider.Name)),
+				"",
+				fmt.Sprintf("%-12s  %-12s  %-12s", provider.Current, provider.Wanted, provider.Latest),
+			)
+		}
+		return nil
+	},
+}
+
+func upgradeProvider(c *cli.Cli, p *project.Project, name string, cfgPath string, stage string, spin *spinner.Spinner) error {
+	if _, ok := p.App().Providers[name]; !ok {
+		return util.NewReadableError(nil, fmt.Sprintf("Provider \"%s\" is not in your config. Use `sst add %s` to add it.", name, name))
+	}
+	pkg := name
+	current := ""
+	if entry, ok := p.LockedProvider(name); ok {
+		pkg = entry.Package
+		current = entry.Version
+	}
+	spin.Suffix = "  Checking for updates..."
+	entry, err := project.FindProvider(name, "latest", pkg)
+	if err != nil {
+		return util.NewReadableError(err, "Could not find provider "+name)
+	}
+	if entry.Version == current {
+		spin.Stop()
+		if c.JSON() {
+			c.SetResult(upgradeResult(name, current, current))
+			return nil
+		}
+		ui.Success(fmt.Sprintf("Provider \"%s\" is already on the latest version %s", name, current))
+		return nil
+	}
+	err = p.Upgrade(name, entry.Version)
+	if err != nil {
+		return util.NewReadableError(err, err.Error())
+	}
+	spin.Suffix = "  Downloading provider..."
+	p, err = project.New(&project.ProjectConfig{
+		Version: version,
+		Config:  cfgPath,
+		Stage:   stage,
+	})
+	if err != nil {
+		return err
+	}
+	err = p.Install()
+	if err != nil {
+		return err
+	}
+	spin.Stop()
+	if c.JSON() {
+		c.SetResult(upgradeResult(name, current, entry.Version))
+		return nil
+	}
+	if current == "" {
+		ui.Success(fmt.Sprintf("Upgraded provider \"%s\" to %s", name, entry.Version))
+		return nil
+	}
+	ui.Success(fmt.Sprintf("Upgraded provider \"%s\" from %s to %s", name, current, entry.Version))
+	return nil
+}
+
+func upgradeResult(name string, from string, to string) map[string]interface{} {
+	return map[string]interface{}{
+		"provider": name,
+		"from":     from,
+		"version":  to,
+		"upgraded": from != to,
+	}
+}
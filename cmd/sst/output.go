@@ -0,0 +1,326 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/joho/godotenv"
+	"github.com/kballard/go-shellquote"
+	"github.com/sst/sst/v3/cmd/sst/cli"
+	"github.com/sst/sst/v3/cmd/sst/mosaic/dev"
+	"github.com/sst/sst/v3/cmd/sst/mosaic/ui"
+	"github.com/sst/sst/v3/internal/util"
+	"github.com/sst/sst/v3/pkg/project"
+	"github.com/sst/sst/v3/pkg/server"
+	"github.com/yalp/jsonpath"
+)
+
+var CmdOutput = &cli.Command{
+	Name: "output",
+	Description: cli.Description{
+		Short: "Read outputs and linked resources",
+		Long: strings.Join([]string{
+			"Reads the outputs of your app and the properties of the resources that are linked, from",
+			"the last deploy of the stage. If `sst dev` is running for the stage, it reads them from there.",
+			"",
+			"```bash frame=\"none\"",
+			"sst output",
+			"```",
+			"",
+			"Pass in the name of an output, or of a linked resource, to read just that.",
+			"",
+			"```bash frame=\"none\"",
+			"sst output api",
+			"sst output MyBucket.name",
+			"```",
+			"",
+			"Strings are printed as is, so they can be used in scripts. Everything else is printed as JSON.",
+			"",
+			"```bash frame=\"none\"",
+			"curl $(sst output api --stage production)",
+			"```",
+			"",
+			"The name can also be a JSONPath, where `$.outputs` has the outputs and `$.links` has the",
+			"linked resources.",
+			"",
+			"```bash frame=\"none\"",
+			"sst output '$.links[\"MyBucket\", \"MyQueue\"]' --json",
+			"```",
+			"",
+			"Use `--dotenv` or `--shell` to print them as environment variables. Without a name, the",
+			"linked resources are printed as `SST_RESOURCE_<name>`, the same way `sst shell` sets them.",
+			"",
+			"```bash frame=\"none\"",
+			"sst output --dotenv > .env",
+			"eval \"$(sst output MyBucket --shell)\"",
+			"```",
+			"",
+			"Values that are marked as secret, like the value of a `sst.Secret`, are hidden unless",
+			"`--show-secrets` is passed in.",
+		}, "\n"),
+	},
+	Args: []cli.Argument{
+		{
+			Name: "name",
+			Description: cli.Description{
+				Short: "The output, linked resource, or JSONPath",
+				Long:  "The name of the output or linked resource, optionally followed by a path like `MyBucket.name`. Or a JSONPath starting with `$`.",
+			},
+		},
+	},
+	Flags: []cli.Flag{
+		{
+			Name: "json",
+			Type: "bool",
+			Description: cli.Description{
+				Short: "Output as JSON",
+				Long:  "Print the value as JSON, including strings.",
+			},
+		},
+		{
+			Name: "dotenv",
+			Type: "bool",
+			Description: cli.Description{
+				Short: "Output as a .env file",
+				Long:  "Print the values as a `.env` file.",
+			},
+		},
+		{
+			Name: "shell",
+			Type: "bool",
+			Description: cli.Description{
+				Short: "Output as shell exports",
+				Long:  "Print the values as `export` statements that can be evaluated by a shell.",
+			},
+		},
+		{
+			Name: "show-secrets",
+			Type: "bool",
+			Description: cli.Description{
+				Short: "Show secret values",
+				Long:  "Show the values that are marked as secret instead of hiding them.",
+			},
+		},
+	},
+	Examples: []cli.Example{
+		{
+			Content: "sst output api --stage production",
+			Description: cli.Description{
+				Short: "Read an output of production",
+			},
+		},
+		{
+			Content: "sst output --dotenv > .env",
+			Description: cli.Description{
+				Short: "Write the linked resources to a .env file",
+			},
+		},
+	},
+	Run: func(c *cli.Cli) error {
+		formats := 0
+		for _, flag := range []string{"json", "dotenv", "shell"} {
+			if c.Bool(flag) {
+				formats++
+			}
+		}
+		if formats > 1 {
+			return util.NewReadableError(nil, "Only one of --json, --dotenv and --shell can be used")
+		}
+
+		complete, err := readCompleted(c)
+		if err != nil {
+			return err
+		}
+
+		name := c.Positional(0)
+		document := complete.Document(c.Bool("show-secrets"))
+		value, err := queryOutput(document, name)
+		if err != nil {
+			return err
+		}
+		if c.JSON() {
+			c.SetResult(value)
+			return nil
+		}
+
+		switch {
+		case c.Bool("json"):
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			err = encoder.Encode(value)
+		case c.Bool("dotenv"):
+			var vars map[string]string
+			vars, err = outputEnv(value, name)
+			if err == nil {
+				var result string
+				result, err = godotenv.Marshal(vars)
+				if err == nil {
+					fmt.Println(result)
+				}
+			}
+		case c.Bool("shell"):
+			var vars map[string]string
+			vars, err = outputEnv(value, name)
+			if err != nil {
+				break
+			}
+			keys := make([]string, 0, len(vars))
+			for key := range vars {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				fmt.Printf("export %s=%s\n", key, shellquote.Join(vars[key]))
+			}
+		default:
+			if str, ok := value.(string); ok {
+				fmt.Println(str)
+				break
+			}
+			var data []byte
+			data, err = json.MarshalIndent(value, "", "  ")
+			fmt.Println(string(data))
+		}
+		if err != nil {
+			return err
+		}
+		if !c.Bool("show-secrets") && hasMask(value) {
+			fmt.Fprintln(os.Stderr, ui.TEXT_DIM.Render("Secret values are hidden, pass in --show-secrets to show them"))
+		}
+		return nil
+	},
+}
+
+// readCompleted gets the state of the stage from `sst dev` if it's running,
+// and from the last deploy otherwise.
+func readCompleted(c *cli.Cli) (*project.CompleteEvent, error) {
+	cfgPath, err := c.Discover()
+	if err != nil {
+		return nil, err
+	}
+	stage, err := c.Stage(cfgPath)
+	if err != nil {
+		return nil, err
+	}
+	if url, err := server.Discover(cfgPath, stage); err == nil {
+		return dev.Completed(c.Context, url)
+	}
+	p, err := c.InitProject()
+	if err != nil {
+		return nil, err
+	}
+	defer p.Cleanup()
+	return p.GetCompleted(c.Context)
+}
+
+var outputName = regexp.MustCompile(`^[^.\[]+`)
+
+// queryOutput looks up a name in the document from CompleteEvent.Document.
+// JSONPaths are read as is. Otherwise the first part of the name is an output
+// or, if there's no output with that name, a linked resource.
+func queryOutput(document map[string]interface{}, name string) (interface{}, error) {
+	if name == "" {
+		return document, nil
+	}
+	path := name
+	if !strings.HasPrefix(name, "$") {
+		first := outputName.FindString(name)
+		if first == "" {
+			return nil, util.NewReadableError(nil, fmt.Sprintf("Invalid name \"%s\"", name))
+		}
+		rest := strings.TrimPrefix(name, first)
+		if outputs, _ := document["outputs"].(map[string]interface{}); outputs != nil && outputs[first] != nil {
+			path = fmt.Sprintf("$[\"outputs\"][%q]%s", first, rest)
+		} else if links, _ := document["links"].(map[string]interface{}); links != nil && links[first] != nil {
+			path = fmt.Sprintf("$[\"links\"][%q]%s", first, rest)
+		} else {
+			return nil, util.NewReadableError(nil, fmt.Sprintf("There is no output or linked resource named \"%s\"", first))
+		}
+	}
+	value, err := jsonpath.Read(document, path)
+	if err != nil {
+		return nil, util.NewReadableError(err, fmt.Sprintf("Could not read \"%s\": %s", name, err.Error()))
+	}
+	return value, nil
+}
+
+var envName = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// outputEnv turns a value into environment variables. Objects become one
+// variable per key and anything that's not a string is JSON encoded. The
+// whole document uses the outputs as is and the links as SST_RESOURCE_*.
+func outputEnv(value interface{}, name string) (map[string]string, error) {
+	result := map[string]string{}
+	set := func(key string, value interface{}) error {
+		if str, ok := value.(string); ok {
+			result[envName.ReplaceAllString(key, "_")] = str
+			return nil
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		result[envName.ReplaceAllString(key, "_")] = string(data)
+		return nil
+	}
+	if name == "" {
+		document, _ := value.(map[string]interface{})
+		outputs, _ := document["outputs"].(map[string]interface{})
+		for key, value := range outputs {
+			if err := set(key, value); err != nil {
+				return nil, err
+			}
+		}
+		links, _ := document["links"].(map[string]interface{})
+		for key, value := range links {
+			if err := set("SST_RESOURCE_"+key, value); err != nil {
+				return nil, err
+			}
+		}
+		return result, nil
+	}
+	if object, ok := value.(map[string]interface{}); ok {
+		for key, value := range object {
+			if err := set(key, value); err != nil {
+				return nil, err
+			}
+		}
+		return result, nil
+	}
+	key := strings.TrimRight(name, "\"']")
+	if index := strings.LastIndexAny(key, ".[\"'"); index != -1 {
+		key = key[index+1:]
+	}
+	key = strings.Trim(envName.ReplaceAllString(key, "_"), "_")
+	if key == "" {
+		return nil, util.NewReadableError(nil, fmt.Sprintf("Could not name the variable for \"%s\", select an object instead", name))
+	}
+	if err := set(key, value); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func hasMask(value interface{}) bool {
+	switch cast := value.(type) {
+	case string:
+		return cast == project.SecretMask
+	case map[string]interface{}:
+		for _, value := range cast {
+			if hasMask(value) {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, value := range cast {
+			if hasMask(value) {
+				return true
+			}
+		}
+	}
+	return false
+}
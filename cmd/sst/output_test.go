@@ -0,0 +1,81 @@
+package main
+
+import (
+	"testing"
+)
+
+func outputDocument() map[string]interface{} {
+	return map[string]interface{}{
+		"outputs": map[string]interface{}{
+			"api":     "https://api.example.com",
+			"my-site": map[string]interface{}{"url": "https://example.com"},
+		},
+		"links": map[string]interface{}{
+			"MyBucket": map[string]interface{}{"name": "bucket-123"},
+			"api":      map[string]interface{}{"url": "shadowed"},
+		},
+	}
+}
+
+func TestQueryOutput(t *testing.T) {
+	document := outputDocument()
+	cases := []struct {
+		name string
+		want interface{}
+	}{
+		{"api", "https://api.example.com"},
+		{"my-site.url", "https://example.com"},
+		{"MyBucket.name", "bucket-123"},
+		{`$.links["MyBucket"].name`, "bucket-123"},
+	}
+	for _, c := range cases {
+		got, err := queryOutput(document, c.name)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if got != c.want {
+			t.Errorf("%s: got %v, want %v", c.name, got, c.want)
+		}
+	}
+	if _, err := queryOutput(document, "Missing"); err == nil {
+		t.Error("expected an error for a missing name")
+	}
+	if _, err := queryOutput(document, "MyBucket.missing"); err == nil {
+		t.Error("expected an error for a missing property")
+	}
+}
+
+func TestOutputEnv(t *testing.T) {
+	document := outputDocument()
+	all, err := outputEnv(document, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if all["api"] != "https://api.example.com" {
+		t.Errorf("api = %q", all["api"])
+	}
+	if all["my_site"] != `{"url":"https://example.com"}` {
+		t.Errorf("my_site = %q", all["my_site"])
+	}
+	if all["SST_RESOURCE_MyBucket"] != `{"name":"bucket-123"}` {
+		t.Errorf("SST_RESOURCE_MyBucket = %q", all["SST_RESOURCE_MyBucket"])
+	}
+
+	value, _ := queryOutput(document, "MyBucket")
+	bucket, err := outputEnv(value, "MyBucket")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bucket) != 1 || bucket["name"] != "bucket-123" {
+		t.Errorf("got %v", bucket)
+	}
+
+	value, _ = queryOutput(document, `$["links"]["MyBucket"]["name"]`)
+	single, err := outputEnv(value, `$["links"]["MyBucket"]["name"]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if single["name"] != "bucket-123" {
+		t.Errorf("got %v", single)
+	}
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/sst/sst/v3/cmd/sst/cli"
+	"github.com/sst/sst/v3/cmd/sst/mosaic/ui"
+	"github.com/sst/sst/v3/internal/util"
+	"github.com/sst/sst/v3/pkg/project"
+)
+
+var CmdProfile = &cli.Command{
+	Name: "profile",
+	Description: cli.Description{
+		Short: "Switch between profiles",
+		Long: strings.Join([]string{
+			"Switch between profiles. A profile bundles the stage, the AWS profile or role, the",
+			"region, and the env files that you use together. They are defined in a",
+			"`sst.profiles.json` next to your `sst.config.ts`.",
+			"",
+			"```json title=\"sst.profiles.json\"",
+			"{",
+			"  \"dev\": {",
+			"    \"stage\": \"frank\",",
+			"    \"awsProfile\": \"acme-dev\"",
+			"  },",
+			"  \"staging\": {",
+			"    \"stage\": \"staging\",",
+			"    \"awsProfile\": \"acme-staging\",",
+			"    \"awsRole\": \"arn:aws:iam::123456789012:role/deploy\",",
+			"    \"region\": \"eu-west-1\",",
+			"    \"envFiles\": [\".env.shared\"]",
+			"  }",
+			"}",
+			"```",
+			"",
+			"The profile in use is stored in `.sst/profile`, or it can be set with the",
+			"`SST_PROFILE` environment variable.",
+			"",
+			"The stage is picked from the first of these that's set.",
+			"",
+			"1. The `--stage` flag.",
+			"2. The `SST_STAGE` environment variable.",
+			"3. The stage of the profile.",
+			"4. The personal stage in `.sst/stage`.",
+			"",
+			"The environment variables are then loaded from these, where the later ones take",
+			"precedence.",
+			"",
+			"1. `.env` and `.env.local`.",
+			"2. The `envFiles` of the profile, in order.",
+			"3. The variables set in your shell.",
+			"4. The `awsProfile`, `awsRole`, and `region` of the profile.",
+			"5. `.env.<stage>` and `.env.<stage>.local`.",
+			"",
+			"The `.env` and `.env.local` in the current directory are loaded for every command,",
+			"even the ones that don't use a stage.",
+			"",
+			"Run `sst diagnostic` to see where each variable came from.",
+		}, "\n"),
+	},
+	Children: []*cli.Command{
+		{
+			Name: "list",
+			Description: cli.Description{
+				Short: "List the profiles",
+				Long:  "Lists the profiles in `sst.profiles.json`, and the one in use.",
+			},
+			Run: func(c *cli.Cli) error {
+				cfgPath, err := c.Discover()
+				if err != nil {
+					return err
+				}
+				profiles, err := project.LoadProfiles(cfgPath)
+				if err != nil {
+					return err
+				}
+				active, _, err := project.LoadActiveProfile(cfgPath)
+				if err != nil {
+					return err
+				}
+				if c.JSON() {
+					c.SetResult(map[string]interface{}{
+						"active":   active,
+						"profiles": profiles,
+					})
+					return nil
+				}
+				if len(profiles) == 0 {
+					fmt.Println(ui.TEXT_DIM.Render("No profiles are defined in " + project.ProfilesFile))
+					return nil
+				}
+				names := make([]string, 0, len(profiles))
+				for name := range profiles {
+					names = append(names, name)
+				}
+				sort.Strings(names)
+				for _, name := range names {
+					marker := "  "
+					if name == active {
+						marker = ui.TEXT_HIGHLIGHT_BOLD.Render("> ")
+					}
+					fmt.Println(marker + ui.TEXT_NORMAL_BOLD.Render(name) + " " + ui.TEXT_DIM.Render(describeProfile(profiles[name])))
+				}
+				return nil
+			},
+		},
+		{
+			Name: "use",
+			Description: cli.Description{
+				Short: "Switch to a profile",
+				Long: strings.Join([]string{
+					"Switch to a profile, the commands that are run after this use its stage, AWS",
+					"credentials, and env files.",
+					"",
+					"```bash frame=\"none\"",
+					"sst profile use staging",
+					"```",
+				}, "\n"),
+			},
+			Args: []cli.Argument{
+				{
+					Name:     "name",
+					Required: true,
+					Description: cli.Description{
+						Short: "The profile",
+						Long:  "The name of the profile in `sst.profiles.json`.",
+					},
+				},
+			},
+			Run: func(c *cli.Cli) error {
+				cfgPath, err := c.Discover()
+				if err != nil {
+					return err
+				}
+				profiles, err := project.LoadProfiles(cfgPath)
+				if err != nil {
+					return err
+				}
+				name := c.Positional(0)
+				profile, ok := profiles[name]
+				if !ok {
+					return util.NewReadableError(nil, fmt.Sprintf("The profile \"%s\" is not defined in %s", name, project.ProfilesFile)).WithCode("profile_not_found")
+				}
+				if profile.Stage != "" && project.InvalidStageRegex.MatchString(profile.Stage) {
+					return util.NewReadableError(project.ErrInvalidStageName, fmt.Sprintf("The stage \"%s\" of the profile \"%s\" is not a valid stage name", profile.Stage, name)).WithCode("profiles_invalid")
+				}
+				if err := project.SetActiveProfile(cfgPath, name); err != nil {
+					return err
+				}
+				if c.JSON() {
+					c.SetResult(map[string]interface{}{
+						"active":  name,
+						"profile": profile,
+					})
+					return nil
+				}
+				ui.Success(fmt.Sprintf("Using the \"%s\" profile", name))
+				return nil
+			},
+		},
+		{
+			Name: "clear",
+			Description: cli.Description{
+				Short: "Stop using a profile",
+				Long:  "Stop using the profile, and go back to the personal stage in `.sst/stage`.",
+			},
+			Run: func(c *cli.Cli) error {
+				cfgPath, err := c.Discover()
+				if err != nil {
+					return err
+				}
+				if err := project.SetActiveProfile(cfgPath, ""); err != nil {
+					return err
+				}
+				if c.JSON() {
+					c.SetResult(map[string]interface{}{"active": ""})
+					return nil
+				}
+				ui.Success("Not using a profile")
+				return nil
+			},
+		},
+	},
+}
+
+func describeProfile(profile *project.Profile) string {
+	parts := []string{}
+	if profile.Stage != "" {
+		parts = append(parts, "stage "+profile.Stage)
+	}
+	if profile.AwsProfile != "" {
+		parts = append(parts, "aws profile "+profile.AwsProfile)
+	}
+	if profile.AwsRole != "" {
+		parts = append(parts, "role "+profile.AwsRole)
+	}
+	if profile.Region != "" {
+		parts = append(parts, profile.Region)
+	}
+	if len(profile.EnvFiles) > 0 {
+		parts = append(parts, strings.Join(profile.EnvFiles, ", "))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/sst/sst/v3/cmd/sst/cli"
+	"github.com/sst/sst/v3/cmd/sst/mosaic/ui"
+	"github.com/sst/sst/v3/cmd/sst/mosaic/ui/common"
+	"github.com/sst/sst/v3/pkg/project"
+)
+
+// stackProgress writes the events of a deploy, remove, or refresh as progress
+// for `--output json`, and sets the result once the stack command completes.
+func stackProgress(c *cli.Cli, evt interface{}) {
+	switch evt := evt.(type) {
+	case *project.StackCommandEvent:
+		c.Progress("start", map[string]interface{}{
+			"app":     evt.App,
+			"stage":   evt.Stage,
+			"command": evt.Command,
+			"version": evt.Version,
+		})
+	case *project.BuildFailedEvent:
+		c.Progress("build.failed", map[string]interface{}{"error": evt.Error})
+	case *project.ResumeEvent:
+		c.Progress("resume", map[string]interface{}{
+			"updateID": evt.UpdateID,
+			"targets":  evt.Targets,
+			"skipped":  evt.Skipped,
+		})
+	case *project.ChangePolicyEvent:
+		c.Progress("approval", map[string]interface{}{
+			"violations": evt.Violations,
+			"token":      evt.Token,
+			"approved":   evt.Approved,
+		})
+	case *project.ConcurrentUpdateEvent:
+		c.Progress("locked", nil)
+	case *project.CancelledEvent:
+		c.Progress("cancelled", nil)
+	case *project.ProviderUpgradeWarningEvent:
+		c.Progress("warning", map[string]interface{}{"message": evt.Message})
+	case *project.TypesWarningEvent:
+		c.Progress("warning", map[string]interface{}{"message": evt.Message})
+	case *project.PolicyAdvisoryEvent:
+		c.Progress("warning", map[string]interface{}{"message": evt.Message, "policy": evt.Policy, "urn": evt.URN})
+	case *common.StdoutEvent:
+		c.Progress("log", map[string]interface{}{"line": evt.Line})
+	case *apitype.ResourcePreEvent:
+		if evt.Metadata.Op == apitype.OpSame {
+			return
+		}
+		c.Progress("resource.started", resourceProgress(evt.Metadata))
+	case *apitype.ResOutputsEvent:
+		if evt.Metadata.Op == apitype.OpSame {
+			return
+		}
+		c.Progress("resource.done", resourceProgress(evt.Metadata))
+	case *apitype.ResOpFailedEvent:
+		c.Progress("resource.failed", resourceProgress(evt.Metadata))
+	case *apitype.DiagnosticEvent:
+		if evt.Severity != "error" && evt.Severity != "warning" {
+			return
+		}
+		c.Progress("diagnostic", map[string]interface{}{
+			"urn":      evt.URN,
+			"severity": evt.Severity,
+			"message":  evt.Message,
+		})
+	case *project.CompleteEvent:
+		if evt.Old {
+			return
+		}
+		c.Progress("complete", map[string]interface{}{
+			"finished": evt.Finished,
+			"errors":   len(evt.Errors),
+		})
+		c.SetResult(stackResult(evt))
+	}
+}
+
+func resourceProgress(metadata apitype.StepEventMetadata) map[string]interface{} {
+	return map[string]interface{}{
+		"urn":  metadata.URN,
+		"name": resource.URN(metadata.URN).Name(),
+		"type": metadata.Type,
+		"op":   string(metadata.Op),
+	}
+}
+
+// stackResult is the result of a stack command, with secret outputs masked
+// like `sst output` does.
+func stackResult(complete *project.CompleteEvent) map[string]interface{} {
+	errors := []project.Error{}
+	errors = append(errors, complete.Errors...)
+	return map[string]interface{}{
+		"updateID":  complete.UpdateID,
+		"finished":  complete.Finished,
+		"outputs":   complete.Document(false)["outputs"],
+		"errors":    errors,
+		"resources": len(complete.Resources),
+	}
+}
+
+// stackUIOptions keeps the UI quiet when the progress is written as JSON.
+func stackUIOptions(c *cli.Cli) []ui.Option {
+	if c.JSON() {
+		return []ui.Option{ui.WithSilent}
+	}
+	return nil
+}
@@ -30,11 +30,15 @@ func CmdRefresh(c *cli.Cli) error {
 
 	var wg errgroup.Group
 	defer wg.Wait()
-	ui := ui.New(c.Context)
+	ui := ui.New(c.Context, stackUIOptions(c)...)
 	events := bus.SubscribeAll()
 	defer close(events)
 	wg.Go(func() error {
 		for evt := range events {
+			if c.JSON() {
+				stackProgress(c, evt)
+				continue
+			}
 			ui.Event(evt)
 		}
 		return nil
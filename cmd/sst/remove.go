@@ -25,7 +25,7 @@ func CmdRemove(c *cli.Cli) error {
 
 	var wg errgroup.Group
 	defer wg.Wait()
-	ui := ui.New(c.Context)
+	ui := ui.New(c.Context, stackUIOptions(c)...)
 	s, err := server.New()
 	if err != nil {
 		return err
@@ -38,6 +38,10 @@ func CmdRemove(c *cli.Cli) error {
 	defer close(events)
 	wg.Go(func() error {
 		for evt := range events {
+			if c.JSON() {
+				stackProgress(c, evt)
+				continue
+			}
 			ui.Event(evt)
 		}
 		return nil
@@ -74,6 +74,15 @@ var CmdSecretList = &cli.Command{
 		if err := wg.Wait(); err != nil {
 			return err
 		}
+		if c.JSON() {
+			c.SetResult(map[string]interface{}{
+				"app":      p.App().Name,
+				"stage":    p.App().Stage,
+				"secrets":  secrets,
+				"fallback": fallback,
+			})
+			return nil
+		}
 		if len(secrets) == 0 && len(fallback) == 0 {
 			return util.NewReadableError(nil, "No secrets found")
 		}
@@ -183,6 +192,7 @@ var CmdSecretLoad = &cli.Command{
 		}
 		defer file.Close()
 
+		loaded := []string{}
 		scanner := bufio.NewScanner(file)
 		for scanner.Scan() {
 			line := scanner.Text()
@@ -213,7 +223,10 @@ var CmdSecretLoad = &cli.Command{
 					}
 				}
 
-				ui.Success(fmt.Sprintf("Setting %s", key))
+				if !c.JSON() {
+					ui.Success(fmt.Sprintf("Setting %s", key))
+				}
+				loaded = append(loaded, key)
 				secrets[key] = value
 			}
 		}
@@ -224,6 +237,12 @@ var CmdSecretLoad = &cli.Command{
 		url, _ := server.Discover(p.PathConfig(), p.App().Stage)
 		if url != "" {
 			dev.Deploy(c.Context, url)
+		}
+		if c.JSON() {
+			c.SetResult(secretResult(p.App().Name, stage, loaded))
+			return nil
+		}
+		if url != "" {
 			return nil
 		}
 
@@ -340,7 +359,7 @@ var CmdSecretSet = &cli.Command{
 				return err
 			}
 			isTerminal := (stat.Mode() & os.ModeCharDevice) != 0
-			if isTerminal {
+			if isTerminal && !c.JSON() {
 				fmt.Print("Enter value: ")
 			}
 			reader := bufio.NewReader(os.Stdin)
@@ -391,6 +410,10 @@ var CmdSecretSet = &cli.Command{
 			dev.Deploy(c.Context, url)
 		}
 
+		if c.JSON() {
+			c.SetResult(secretResult(p.App().Name, stage, []string{key}))
+			return nil
+		}
 		if c.Bool("fallback") {
 			ui.Success(fmt.Sprintf("Set fallback value for \"%s\".%s", key, suffix))
 			return nil
@@ -481,6 +504,10 @@ var CmdSecretRemove = &cli.Command{
 			suffix = ""
 			dev.Deploy(c.Context, url)
 		}
+		if c.JSON() {
+			c.SetResult(secretResult(p.App().Name, stage, []string{key}))
+			return nil
+		}
 		if c.Bool("fallback") {
 			ui.Success(fmt.Sprintf("Removed fallback value for \"%s\".%s", key, suffix))
 			return nil
@@ -489,3 +516,14 @@ var CmdSecretRemove = &cli.Command{
 		return nil
 	},
 }
+
+// secretResult is the result of the commands that change secrets, the stage
+// is empty for fallback values.
+func secretResult(app string, stage string, names []string) map[string]interface{} {
+	return map[string]interface{}{
+		"app":      app,
+		"stage":    stage,
+		"fallback": stage == "",
+		"secrets":  names,
+	}
+}
@@ -77,7 +77,7 @@ func CmdShell(c *cli.Cli) error {
 	target := c.String("target")
 	if target != "" {
 		cmd.Env = append(cmd.Env, c.Env()...)
-		env, err := p.EnvFor(c.Context, complete, target)
+		env, err := p.EnvFor(c.Context, complete, target, nil)
 		if err != nil {
 			return err
 		}
@@ -0,0 +1,596 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sst/sst/v3/cmd/sst/cli"
+	"github.com/sst/sst/v3/cmd/sst/mosaic/errors"
+	"github.com/sst/sst/v3/cmd/sst/mosaic/ui"
+	"github.com/sst/sst/v3/internal/util"
+	"github.com/sst/sst/v3/pkg/process"
+	"github.com/sst/sst/v3/pkg/project"
+	"github.com/sst/sst/v3/pkg/project/provider"
+)
+
+var CmdStage = &cli.Command{
+	Name: "stage",
+	Description: cli.Description{
+		Short: "Manage the stages of your app",
+		Long: strings.Join([]string{
+			"Manage the stages of your app. List them, see when they were last deployed, and",
+			"clean up the ones that are no longer needed.",
+			"",
+			"Stages can be set to expire with the `--ttl` flag of `sst deploy`, this is useful for",
+			"preview stages that are deployed for each pull request.",
+			"",
+			"```bash frame=\"none\"",
+			"sst deploy --stage pr-123 --ttl 3d",
+			"```",
+			"",
+			"And then removed with `sst stage gc`.",
+			"",
+			":::note",
+			"Stages are tracked from the first update that's run with this version of the CLI.",
+			":::",
+		}, "\n"),
+	},
+	Children: []*cli.Command{
+		{
+			Name: "list",
+			Description: cli.Description{
+				Short: "List the stages of your app",
+				Long: strings.Join([]string{
+					"Lists the stages of your app for the current set of credentials, with when they",
+					"were last updated, the version of SST they were updated with, how many resources",
+					"they have, and whether they are locked, protected, or expired.",
+				}, "\n"),
+			},
+			Run: func(c *cli.Cli) error {
+				p, err := c.InitProject()
+				if err != nil {
+					return err
+				}
+				defer p.Cleanup()
+
+				statuses, err := loadStages(p)
+				if err != nil {
+					return err
+				}
+				if c.JSON() {
+					c.SetResult(map[string]interface{}{
+						"app":    p.App().Name,
+						"stages": statuses,
+					})
+					return nil
+				}
+				if len(statuses) == 0 {
+					fmt.Println(ui.TEXT_DIM.Render("No stages are deployed"))
+					return nil
+				}
+				printStages(statuses, time.Now())
+				return nil
+			},
+		},
+		{
+			Name: "info",
+			Description: cli.Description{
+				Short: "Show the details of a stage",
+				Long: strings.Join([]string{
+					"Shows the last update of a stage, its errors, its lock, and when it expires.",
+					"",
+					"Defaults to the current stage.",
+				}, "\n"),
+			},
+			Args: []cli.Argument{
+				{
+					Name: "name",
+					Description: cli.Description{
+						Short: "The stage",
+						Long:  "The name of the stage. Defaults to the current stage.",
+					},
+				},
+			},
+			Run: func(c *cli.Cli) error {
+				p, err := c.InitProject()
+				if err != nil {
+					return err
+				}
+				defer p.Cleanup()
+
+				name := c.Positional(0)
+				if name == "" {
+					name = p.App().Stage
+				}
+				status, err := loadStage(p.Backend(), p.App().Name, name)
+				if err != nil {
+					return err
+				}
+				status.Current = name == p.App().Stage
+
+				if c.JSON() {
+					updateErrors := []provider.SummaryError{}
+					if status.update != nil && status.update.Errors != nil {
+						updateErrors = status.update.Errors
+					}
+					c.SetResult(map[string]interface{}{
+						"app":    p.App().Name,
+						"stage":  status,
+						"errors": updateErrors,
+					})
+					return nil
+				}
+				printStage(p.App().Name, status, time.Now())
+				return nil
+			},
+		},
+		{
+			Name: "ttl",
+			Description: cli.Description{
+				Short: "Set when a stage expires",
+				Long: strings.Join([]string{
+					"Sets the current stage to expire after the given duration, like `3d`, `12h`, or `2w`.",
+					"Pass in `never` to stop it from expiring.",
+					"",
+					"```bash frame=\"none\"",
+					"sst stage ttl 7d --stage pr-123",
+					"```",
+					"",
+					"Expired stages are removed with `sst stage gc`. Deploying with `sst deploy --ttl`",
+					"also sets this.",
+				}, "\n"),
+			},
+			Args: []cli.Argument{
+				{
+					Name:     "duration",
+					Required: true,
+					Description: cli.Description{
+						Short: "How long until it expires",
+						Long:  "How long until the stage expires, like `3d`, `12h`, or `2w`. Or `never`.",
+					},
+				},
+			},
+			Run: func(c *cli.Cli) error {
+				p, err := c.InitProject()
+				if err != nil {
+					return err
+				}
+				defer p.Cleanup()
+
+				record, err := provider.GetStage(p.Backend(), p.App().Name, p.App().Stage)
+				if err != nil {
+					return err
+				}
+				if record == nil {
+					return util.NewReadableError(nil, fmt.Sprintf("The stage \"%s\" has not been deployed with this version of SST. Run `sst deploy --ttl %s` instead.", p.App().Stage, c.Positional(0))).WithCode("stage_not_tracked")
+				}
+				record.Expires = ""
+				if c.Positional(0) != "never" {
+					ttl, err := parseAge(c.Positional(0))
+					if err != nil {
+						return err
+					}
+					record.Expires = time.Now().Add(ttl).UTC().Format(time.RFC3339)
+				}
+				err = provider.PutStage(p.Backend(), p.App().Name, p.App().Stage, record)
+				if err != nil {
+					return err
+				}
+				if c.JSON() {
+					c.SetResult(map[string]interface{}{
+						"stage":   p.App().Stage,
+						"expires": record.Expires,
+					})
+					return nil
+				}
+				if record.Expires == "" {
+					ui.Success(fmt.Sprintf("The stage \"%s\" does not expire", p.App().Stage))
+					return nil
+				}
+				ui.Success(fmt.Sprintf("The stage \"%s\" expires on %s", p.App().Stage, record.Expires))
+				return nil
+			},
+		},
+		{
+			Name: "gc",
+			Description: cli.Description{
+				Short: "Remove the expired stages",
+				Long: strings.Join([]string{
+					"Removes the stages that have expired, one at a time. A stage has expired if the",
+					"`--ttl` it was deployed with has passed, or if it has not been updated for longer",
+					"than `--older-than`.",
+					"",
+					"```bash frame=\"none\"",
+					"sst stage gc --older-than 7d",
+					"```",
+					"",
+					"Stages that are protected or locked are skipped, and so are the ones that have",
+					"not been updated with this version of SST. Each stage is removed with `sst remove`,",
+					"so a stage that's protected in your `sst.config.ts` is never removed.",
+					"",
+					"Use `--dry-run` to see the stages that would be removed.",
+				}, "\n"),
+			},
+			Flags: []cli.Flag{
+				{
+					Name: "older-than",
+					Type: "string",
+					Description: cli.Description{
+						Short: "Remove stages not updated in this long",
+						Long:  "Also remove the stages that have not been updated in this long, like `7d` or `2w`.",
+					},
+				},
+				{
+					Name: "dry-run",
+					Type: "bool",
+					Description: cli.Description{
+						Short: "List the stages to remove",
+						Long:  "List the stages that would be removed, without removing them.",
+					},
+				},
+			},
+			Examples: []cli.Example{
+				{
+					Content: "sst stage gc --older-than 7d --dry-run",
+					Description: cli.Description{
+						Short: "See the stages that have not been updated in a week",
+					},
+				},
+			},
+			Run: CmdStageGC,
+		},
+	},
+}
+
+// stageStatus is a stage and its last update, as listed by `sst stage`.
+type stageStatus struct {
+	Name      string             `json:"name"`
+	Current   bool               `json:"current"`
+	UpdateID  string             `json:"updateID,omitempty"`
+	Command   string             `json:"command,omitempty"`
+	Version   string             `json:"version,omitempty"`
+	Updated   string             `json:"updated,omitempty"`
+	Resources int                `json:"resources"`
+	Errors    int                `json:"errors"`
+	Protected bool               `json:"protected"`
+	Expires   string             `json:"expires,omitempty"`
+	Lock      *provider.LockInfo `json:"lock,omitempty"`
+
+	update *provider.Update
+}
+
+func loadStage(backend provider.Home, app, name string) (*stageStatus, error) {
+	status := &stageStatus{Name: name}
+	lock, err := provider.GetLock(backend, app, name)
+	if err != nil {
+		return nil, err
+	}
+	status.Lock = lock
+	record, err := provider.GetStage(backend, app, name)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		return status, nil
+	}
+	status.UpdateID = record.UpdateID
+	status.Resources = record.Resources
+	status.Protected = record.Protected
+	status.Expires = record.Expires
+	update, err := provider.GetUpdate(backend, app, name, record.UpdateID)
+	if err != nil {
+		return nil, err
+	}
+	if update != nil {
+		status.update = update
+		status.Command = update.Command
+		status.Version = update.Version
+		status.Errors = len(update.Errors)
+		status.Updated = update.TimeCompleted
+		if status.Updated == "" {
+			status.Updated = update.TimeStarted
+		}
+	}
+	return status, nil
+}
+
+func loadStages(p *project.Project) ([]*stageStatus, error) {
+	names, err := provider.ListStages(p.Backend(), p.App().Name)
+	if err != nil {
+		return nil, err
+	}
+	statuses := []*stageStatus{}
+	for _, name := range names {
+		status, err := loadStage(p.Backend(), p.App().Name, name)
+		if err != nil {
+			return nil, err
+		}
+		status.Current = name == p.App().Stage
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// expired returns if the stage is past its expiry, or has not been updated in
+// longer than olderThan, when it's set.
+func (s *stageStatus) expired(now time.Time, olderThan time.Duration) bool {
+	if expires, err := time.Parse(time.RFC3339, s.Expires); err == nil && !now.Before(expires) {
+		return true
+	}
+	if updated, err := time.Parse(time.RFC3339, s.Updated); err == nil && olderThan > 0 && now.Sub(updated) > olderThan {
+		return true
+	}
+	return false
+}
+
+// keep returns why an expired stage can't be removed, if it can't be.
+func (s *stageStatus) keep() string {
+	if s.UpdateID == "" {
+		return "not tracked"
+	}
+	if s.Protected {
+		return "protected"
+	}
+	if s.Lock != nil {
+		return "locked"
+	}
+	return ""
+}
+
+// parseAge parses a duration that can also be in days or weeks, like `7d` or
+// `2w`.
+func parseAge(value string) (time.Duration, error) {
+	invalid := util.NewReadableError(nil, fmt.Sprintf("Invalid duration \"%s\", use a number followed by m, h, d, or w, like `7d`", value)).WithCode("duration_invalid")
+	unit := map[byte]time.Duration{'d': 24 * time.Hour, 'w': 7 * 24 * time.Hour}
+	if len(value) > 1 {
+		if multiple, ok := unit[value[len(value)-1]]; ok {
+			count, err := strconv.Atoi(value[:len(value)-1])
+			if err != nil || count <= 0 {
+				return 0, invalid
+			}
+			return time.Duration(count) * multiple, nil
+		}
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		return 0, invalid
+	}
+	return duration, nil
+}
+
+// formatRelative renders how long ago or from now a time is, like `3d ago` or
+// `in 5h`.
+func formatRelative(value string, now time.Time) string {
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return "-"
+	}
+	duration := now.Sub(parsed)
+	suffix := " ago"
+	prefix := ""
+	if duration < 0 {
+		duration = -duration
+		suffix = ""
+		prefix = "in "
+	}
+	amount := ""
+	switch {
+	case duration < time.Minute:
+		return "just now"
+	case duration < time.Hour:
+		amount = fmt.Sprintf("%dm", int(duration.Minutes()))
+	case duration < 24*time.Hour:
+		amount = fmt.Sprintf("%dh", int(duration.Hours()))
+	default:
+		amount = fmt.Sprintf("%dd", int(duration.Hours()/24))
+	}
+	return prefix + amount + suffix
+}
+
+func stageFlags(status *stageStatus, now time.Time) []string {
+	flags := []string{}
+	if status.Lock != nil {
+		flags = append(flags, ui.TEXT_WARNING.Render("locked"))
+	}
+	if status.Protected {
+		flags = append(flags, ui.TEXT_INFO.Render("protected"))
+	}
+	if status.Errors > 0 {
+		flags = append(flags, ui.TEXT_DANGER.Render("failed"))
+	}
+	if status.Expires != "" {
+		if status.expired(now, 0) {
+			flags = append(flags, ui.TEXT_DANGER.Render("expired"))
+		} else {
+			flags = append(flags, ui.TEXT_DIM.Render("expires "+formatRelative(status.Expires, now)))
+		}
+	}
+	return flags
+}
+
+func printStages(statuses []*stageStatus, now time.Time) {
+	width := len("Stage")
+	for _, status := range statuses {
+		width = max(width, len(status.Name))
+	}
+	fmt.Println(ui.TEXT_DIM.Render(fmt.Sprintf("  %-*s  %-10s  %-10s  %-9s", width, "Stage", "Updated", "Version", "Resources")))
+	for _, status := range statuses {
+		marker := "  "
+		if status.Current {
+			marker = ui.TEXT_HIGHLIGHT_BOLD.Render("> ")
+		}
+		version := status.Version
+		if version == "" {
+			version = "-"
+		}
+		fmt.Println(
+			marker+ui.TEXT_NORMAL_BOLD.Render(fmt.Sprintf("%-*s", width, status.Name)),
+			ui.TEXT_GRAY.Render(fmt.Sprintf(" %-10s  %-10s  %-9d", formatRelative(status.Updated, now), version, status.Resources)),
+			strings.Join(stageFlags(status, now), " "),
+		)
+	}
+}
+
+func printStage(app string, status *stageStatus, now time.Time) {
+	renderKeyValue("App", app)
+	renderKeyValue("Stage", status.Name)
+	if status.UpdateID == "" {
+		fmt.Println(ui.TEXT_DIM.Render("This stage has not been updated with this version of SST"))
+	} else {
+		renderKeyValue("Updated", status.Updated+" ("+formatRelative(status.Updated, now)+")")
+		renderKeyValue("Command", status.Command)
+		renderKeyValue("Update", status.UpdateID)
+		renderKeyValue("Version", status.Version)
+		renderKeyValue("Resources", strconv.Itoa(status.Resources))
+		renderKeyValue("Protected", strconv.FormatBool(status.Protected))
+		expires := "never"
+		if status.Expires != "" {
+			expires = status.Expires + " (" + formatRelative(status.Expires, now) + ")"
+		}
+		renderKeyValue("Expires", expires)
+	}
+	if status.Lock != nil {
+		renderKeyValue("Locked", fmt.Sprintf("by %s %s since %s", status.Lock.Command, status.Lock.UpdateID, status.Lock.Created.Format(time.RFC3339)))
+	}
+	if status.update != nil && len(status.update.Errors) > 0 {
+		fmt.Println()
+		fmt.Println(ui.TEXT_DANGER_BOLD.Render("Errors"))
+		for _, item := range status.update.Errors {
+			if item.URN != "" {
+				fmt.Println(ui.TEXT_NORMAL_BOLD.Render("  " + item.URN))
+			}
+			fmt.Println(ui.TEXT_GRAY.Render("  " + item.Message))
+		}
+	}
+}
+
+const (
+	gcStatusRemoved = "removed"
+	gcStatusFailed  = "failed"
+	gcStatusSkipped = "skipped"
+	gcStatusExpired = "expired"
+)
+
+type gcResult struct {
+	Stage  string           `json:"stage"`
+	Status string           `json:"status"`
+	Reason string           `json:"reason,omitempty"`
+	Error  *cli.ResultError `json:"error,omitempty"`
+}
+
+func CmdStageGC(c *cli.Cli) error {
+	olderThan := time.Duration(0)
+	if c.String("older-than") != "" {
+		var err error
+		olderThan, err = parseAge(c.String("older-than"))
+		if err != nil {
+			return err
+		}
+	}
+	p, err := c.InitProject()
+	if err != nil {
+		return err
+	}
+	defer p.Cleanup()
+	cfgPath := p.PathConfig()
+
+	statuses, err := loadStages(p)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	results := []gcResult{}
+	for _, status := range statuses {
+		if !status.expired(now, olderThan) {
+			continue
+		}
+		result := gcResult{Stage: status.Name, Status: gcStatusExpired}
+		if reason := status.keep(); reason != "" {
+			result.Status = gcStatusSkipped
+			result.Reason = reason
+		}
+		results = append(results, result)
+	}
+
+	failed := 0
+	for i := range results {
+		result := &results[i]
+		if result.Status == gcStatusSkipped {
+			if !c.JSON() {
+				fmt.Println(ui.TEXT_DIM.Render(fmt.Sprintf("Skipping %s, it's %s", result.Stage, result.Reason)))
+			}
+			continue
+		}
+		if c.Bool("dry-run") {
+			if !c.JSON() {
+				fmt.Println(ui.TEXT_NORMAL.Render("Would remove " + result.Stage))
+			}
+			continue
+		}
+		if !c.JSON() {
+			fmt.Println(ui.TEXT_INFO_BOLD.Render("Removing " + result.Stage))
+		}
+		result.Error = removeStage(c, cfgPath, result.Stage)
+		result.Status = gcStatusRemoved
+		if result.Error != nil {
+			result.Status = gcStatusFailed
+			failed++
+		}
+	}
+
+	if c.JSON() {
+		c.SetResult(map[string]interface{}{"stages": results})
+	} else if len(results) == 0 {
+		fmt.Println(ui.TEXT_DIM.Render("No stages have expired"))
+	}
+	if failed > 0 {
+		return util.NewReadableError(nil, fmt.Sprintf("%d of the expired stages could not be removed", failed)).WithCode("stage_gc_failed")
+	}
+	return nil
+}
+
+// removeStage runs `sst remove` for a stage in its own process, so each one
+// is removed with the config evaluated for that stage. It gets the shell's
+// environment, without the env files that were loaded for this stage.
+func removeStage(c *cli.Cli, cfgPath string, stage string) *cli.ResultError {
+	executable, err := os.Executable()
+	if err != nil {
+		return &cli.ResultError{Code: errors.CodeUnexpected, Message: err.Error()}
+	}
+	args := []string{"remove", "--stage", stage, "--config", cfgPath}
+	if c.Bool("verbose") {
+		args = append(args, "--verbose")
+	}
+	if c.JSON() {
+		args = append(args, "--output", "json")
+	}
+	cmd := process.Command(executable, args...)
+	cmd.Dir = filepath.Dir(cfgPath)
+	cmd.Env = c.Env()
+	cmd.Stdin = os.Stdin
+	cmd.Stderr = os.Stderr
+	var stdout bytes.Buffer
+	cmd.Stdout = os.Stdout
+	if c.JSON() {
+		cmd.Stdout = &stdout
+	}
+	err = cmd.Run()
+	if c.JSON() {
+		var document struct {
+			Error *cli.ResultError `json:"error"`
+		}
+		if json.Unmarshal(stdout.Bytes(), &document) == nil && document.Error != nil {
+			return document.Error
+		}
+	}
+	if err != nil {
+		return &cli.ResultError{Code: "remove_failed", Message: fmt.Sprintf("Could not remove %s", stage)}
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sst/sst/v3/pkg/project/provider"
+)
+
+func TestParseAge(t *testing.T) {
+	cases := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"7d", 7 * 24 * time.Hour},
+		{"2w", 14 * 24 * time.Hour},
+		{"12h", 12 * time.Hour},
+		{"90m", 90 * time.Minute},
+	}
+	for _, tc := range cases {
+		got, err := parseAge(tc.value)
+		if err != nil || got != tc.want {
+			t.Errorf("parseAge(%q) = %v %v, want %v", tc.value, got, err, tc.want)
+		}
+	}
+	for _, value := range []string{"", "d", "-1d", "0d", "soon", "7x"} {
+		if _, err := parseAge(value); err == nil {
+			t.Errorf("parseAge(%q) should fail", value)
+		}
+	}
+}
+
+func TestStageExpired(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name      string
+		status    stageStatus
+		olderThan time.Duration
+		want      bool
+	}{
+		{"no expiry", stageStatus{Updated: "2026-10-01T00:00:00Z"}, 0, false},
+		{"expired", stageStatus{Expires: "2026-10-19T11:00:00Z"}, 0, true},
+		{"not yet expired", stageStatus{Expires: "2026-10-20T00:00:00Z"}, 0, false},
+		{"older than", stageStatus{Updated: "2026-10-01T00:00:00Z"}, 7 * 24 * time.Hour, true},
+		{"recently updated", stageStatus{Updated: "2026-10-18T00:00:00Z"}, 7 * 24 * time.Hour, false},
+		{"not tracked", stageStatus{}, 7 * 24 * time.Hour, false},
+	}
+	for _, tc := range cases {
+		if got := tc.status.expired(now, tc.olderThan); got != tc.want {
+			t.Errorf("%s: expired = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestStageKeep(t *testing.T) {
+	cases := []struct {
+		status stageStatus
+		want   string
+	}{
+		{stageStatus{UpdateID: "01"}, ""},
+		{stageStatus{}, "not tracked"},
+		{stageStatus{UpdateID: "01", Protected: true}, "protected"},
+		{stageStatus{UpdateID: "01", Lock: &provider.LockInfo{UpdateID: "02"}}, "locked"},
+	}
+	for _, tc := range cases {
+		if got := tc.status.keep(); got != tc.want {
+			t.Errorf("keep(%+v) = %q, want %q", tc.status, got, tc.want)
+		}
+	}
+}
+
+func TestFormatRelative(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	cases := map[string]string{
+		"2026-10-19T11:59:30Z": "just now",
+		"2026-10-19T11:15:00Z": "45m ago",
+		"2026-10-19T07:00:00Z": "5h ago",
+		"2026-10-16T12:00:00Z": "3d ago",
+		"2026-10-21T12:00:00Z": "in 2d",
+		"":                     "-",
+	}
+	for value, want := range cases {
+		if got := formatRelative(value, now); got != want {
+			t.Errorf("formatRelative(%q) = %q, want %q", value, got, want)
+		}
+	}
+}
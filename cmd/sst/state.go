@@ -183,6 +183,23 @@ var CmdState = &cli.Command{
 					return err
 				}
 
+				if c.JSON() {
+					home := map[string]string{}
+					for _, line := range lines {
+						home[line.Key] = line.Value
+					}
+					if stages == nil {
+						stages = []string{}
+					}
+					c.SetResult(map[string]interface{}{
+						"app":    p.App().Name,
+						"stage":  currentStage,
+						"home":   home,
+						"stages": stages,
+					})
+					return nil
+				}
+
 				renderKeyValue("App", p.App().Name)
 
 				for _, line := range lines {
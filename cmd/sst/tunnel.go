@@ -1,23 +1,77 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"os"
 	"os/user"
+	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/sst/sst/v3/cmd/sst/cli"
 	"github.com/sst/sst/v3/cmd/sst/mosaic/dev"
 	"github.com/sst/sst/v3/cmd/sst/mosaic/ui"
 	"github.com/sst/sst/v3/internal/util"
+	"github.com/sst/sst/v3/pkg/flag"
+	"github.com/sst/sst/v3/pkg/global"
 	"github.com/sst/sst/v3/pkg/process"
 	"github.com/sst/sst/v3/pkg/project"
 	"github.com/sst/sst/v3/pkg/server"
 	"github.com/sst/sst/v3/pkg/tunnel"
+	"github.com/sst/sst/v3/pkg/tunnel/proxyenv"
 )
 
+type tunnelStartConfig struct {
+	Tunnels    []tunnel.Config  `json:"tunnels"`
+	KnownHosts string           `json:"knownHosts"`
+	Forwards   []tunnel.Forward `json:"forwards,omitempty"`
+}
+
+func parseTunnelPorts(input string) (map[string]int, error) {
+	result := map[string]int{}
+	for _, entry := range strings.Split(input, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(entry, "=")
+		port, err := strconv.Atoi(value)
+		if !ok || err != nil || port <= 0 || port > 65535 {
+			return nil, util.NewReadableError(nil, "Invalid tunnel port \""+entry+"\", use the format Name=port")
+		}
+		if port == tunnel.DefaultPort {
+			return nil, util.NewReadableError(nil, fmt.Sprintf("Port %d is reserved for routing across all tunnels", tunnel.DefaultPort))
+		}
+		result[name] = port
+	}
+	return result, nil
+}
+
+func printTunnels(completed *project.CompleteEvent, names []string) {
+	fmt.Println(ui.TEXT_HIGHLIGHT_BOLD.Render("Tunnel"))
+	fmt.Println()
+	for _, name := range names {
+		tun := completed.Tunnels[name]
+		fmt.Print(ui.TEXT_HIGHLIGHT_BOLD.Render("▤"))
+		fmt.Println(ui.TEXT_NORMAL.Render("  "+tun.IP) + ui.TEXT_DIM.Render(" "+name))
+		fmt.Println()
+		fmt.Print(ui.TEXT_SUCCESS_BOLD.Render("➜"))
+		fmt.Println(ui.TEXT_NORMAL.Render("  Ranges"))
+		for _, subnet := range tun.Subnets {
+			fmt.Println(ui.TEXT_DIM.Render("   " + subnet))
+		}
+		fmt.Println()
+	}
+	fmt.Println(ui.TEXT_DIM.Render("Waiting for connections..."))
+	fmt.Println()
+}
+
 var CmdTunnel = &cli.Command{
 	Name: "tunnel",
 	Description: cli.Description{
@@ -57,14 +111,99 @@ var CmdTunnel = &cli.Command{
 			"",
 			"This needs a network interface on your local machine. You can create this",
 			"with the `sst tunnel install` command.",
+			"",
+			"If your app has more than one VPC with a bastion, all of them are tunneled at the",
+			"same time. Traffic is routed to the VPC whose ranges contain the destination.",
+			"",
+			"Each tunnel also gets its own SOCKS5 proxy. This is useful when the ranges of your",
+			"VPCs overlap. By default these use the ports after `1080`, or you can set them.",
+			"",
+			"```bash frame=\"none\"",
+			"sst tunnel --ports MyVpc=1081,OtherVpc=1082",
+			"```",
+			"",
+			"The host key of a bastion is recorded the first time you connect to it and is",
+			"verified after that. If you recreate a bastion, remove its entry from the",
+			"`tunnel_known_hosts` file in the SST config directory.",
+			"",
+			"If the connection to a bastion drops, it is re-established automatically.",
+			"",
+			"#### Rootless",
+			"",
+			"If you can't get root, for example in CI, you can run the tunnel without a network",
+			"interface. This doesn't need `sst tunnel install`.",
+			"",
+			"```bash frame=\"none\"",
+			"sst tunnel --rootless",
+			"```",
+			"",
+			"Instead of routing traffic transparently, this only serves a SOCKS5 proxy on",
+			"`localhost:1080` and an HTTP proxy on `localhost:3128`. Traffic outside of your VPC",
+			"ranges goes out directly.",
+			"",
+			"Set `SST_TUNNEL_MODE=rootless` to use this mode in `sst dev` as well. Your dev",
+			"processes then get `ALL_PROXY`, `HTTP_PROXY`, `HTTPS_PROXY` and `NO_PROXY` set.",
+			"",
+			"For tools that can't use a proxy, you can forward local ports to a destination in",
+			"your VPC. This works in either mode.",
+			"",
+			"```bash frame=\"none\"",
+			"sst tunnel --rootless --forward 5432=mydb.cluster-abc.us-east-1.rds.amazonaws.com:5432",
+			"```",
+			"",
+			"You can also set these with `SST_TUNNEL_FORWARDS`.",
+			"",
+			"#### Status",
+			"",
+			"To check on a running tunnel, use `sst tunnel status`. It shows if each bastion is",
+			"connected, the round trip time of the last keepalive, the open connections and bytes",
+			"transferred per destination, and the most recent errors.",
+			"",
+			"```bash frame=\"none\"",
+			"sst tunnel status",
+			"```",
+			"",
+			"In `sst dev`, this is shown under the _Tunnel Status_ tab in the sidebar.",
 		}, "\n"),
 	},
+	Flags: []cli.Flag{
+		{
+			Name: "ports",
+			Type: "string",
+			Description: cli.Description{
+				Short: "SOCKS ports for each tunnel",
+				Long:  "Set the SOCKS5 port of each tunnel as a comma separated list of `Name=port`.",
+			},
+		},
+		{
+			Name: "rootless",
+			Type: "bool",
+			Description: cli.Description{
+				Short: "Run without a network interface",
+				Long:  "Only serve the SOCKS5 and HTTP proxies so the tunnel doesn't need root.",
+			},
+		},
+		{
+			Name: "forward",
+			Type: "string",
+			Description: cli.Description{
+				Short: "Forward local ports into the VPC",
+				Long:  "Forward local ports to destinations in the VPC as a comma separated list of `port=host:port`.",
+			},
+		},
+	},
 	Run: func(c *cli.Cli) error {
-		if tunnel.NeedsInstall() {
-			return util.NewReadableError(nil, "The sst tunnel needs to be installed or upgraded. Run `sudo sst tunnel install`")
+		rootless := c.Bool("rootless") || proxyenv.Rootless()
+		forwards, err := tunnel.ParseForwards(flag.SST_TUNNEL_FORWARDS + "," + c.String("forward"))
+		if err != nil {
+			return util.NewReadableError(err, err.Error())
 		}
 
-		if tunnel.IsRunning() {
+		if !rootless && tunnel.NeedsInstall() {
+			return util.NewReadableError(nil, "The sst tunnel needs to be installed or upgraded. Run `sudo sst tunnel install`, or use `sst tunnel --rootless`")
+		}
+
+		if !rootless && tunnel.IsRunning() {
 			return util.NewReadableError(nil, "Another tunnel process is already running. Stop it before starting a new one.")
 		}
 
@@ -104,43 +243,72 @@ var CmdTunnel = &cli.Command{
 		if len(completed.Tunnels) == 0 {
 			return util.NewReadableError(nil, "No tunnels found for stage "+stage)
 		}
-		var tun project.Tunnel
-		for _, item := range completed.Tunnels {
-			tun = item
+		ports, err := parseTunnelPorts(c.String("ports"))
+		if err != nil {
+			return err
+		}
+		names := make([]string, 0, len(completed.Tunnels))
+		for name := range completed.Tunnels {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		configs := []tunnel.Config{}
+		subnets := []string{}
+		for _, name := range names {
+			tun := completed.Tunnels[name]
+			configs = append(configs, tunnel.Config{
+				Name:       name,
+				Host:       tun.IP + ":22",
+				Username:   tun.Username,
+				PrivateKey: tun.PrivateKey,
+				Subnets:    tun.Subnets,
+				Port:       ports[name],
+			})
+			subnets = append(subnets, tun.Subnets...)
+			delete(ports, name)
+		}
+		for name := range ports {
+			return util.NewReadableError(nil, "No tunnel named "+name+" in stage "+stage)
+		}
+		knownHosts := filepath.Join(global.ConfigDir(), "tunnel_known_hosts")
+		printTunnels(completed, names)
+		if rootless {
+			slog.Info("starting rootless tunnel", "tunnels", len(configs))
+			err := tunnel.StartProxies(c.Context, configs, tunnel.Options{
+				KnownHosts: knownHosts,
+				Rootless:   true,
+				Forwards:   forwards,
+			})
+			if errors.Is(err, tunnel.ErrHostKeyMismatch) {
+				return util.NewReadableError(err, err.Error())
+			}
+			return err
+		}
+		config, err := json.Marshal(tunnelStartConfig{
+			Tunnels:    configs,
+			KnownHosts: knownHosts,
+			Forwards:   forwards,
+		})
+		if err != nil {
+			return err
 		}
-		subnets := strings.Join(tun.Subnets, ",")
 		// run as root
 		tunnelCmd := process.CommandContext(
 			c.Context,
 			"sudo", "-n", "-E",
 			tunnel.BINARY_PATH, "tunnel", "start",
-			"--subnets", subnets,
-			"--host", tun.IP,
-			"--user", tun.Username,
+			"--subnets", strings.Join(subnets, ","),
 			"--print-logs",
 		)
 		tunnelCmd.Env = append(
 			os.Environ(),
 			"SST_SKIP_LOCAL=true",
 			"SST_SKIP_DEPENDENCY_CHECK=true",
-			"SSH_PRIVATE_KEY="+tun.PrivateKey,
+			"SST_TUNNEL_CONFIG="+string(config),
 			"SST_LOG="+strings.ReplaceAll(os.Getenv("SST_LOG"), ".log", "_sudo.log"),
 		)
 		tunnelCmd.Stdout = os.Stdout
 		slog.Info("starting tunnel", "cmd", tunnelCmd.Args)
-		fmt.Println(ui.TEXT_HIGHLIGHT_BOLD.Render("Tunnel"))
-		fmt.Println()
-		fmt.Print(ui.TEXT_HIGHLIGHT_BOLD.Render("▤"))
-		fmt.Println(ui.TEXT_NORMAL.Render("  " + tun.IP))
-		fmt.Println()
-		fmt.Print(ui.TEXT_SUCCESS_BOLD.Render("➜"))
-		fmt.Println(ui.TEXT_NORMAL.Render("  Ranges"))
-		for _, subnet := range tun.Subnets {
-			fmt.Println(ui.TEXT_DIM.Render("   " + subnet))
-		}
-		fmt.Println()
-		fmt.Println(ui.TEXT_DIM.Render("Waiting for connections..."))
-		fmt.Println()
 		stderr, _ := tunnelCmd.StderrPipe()
 		tunnelCmd.Start()
 		output, _ := io.ReadAll(stderr)
@@ -150,6 +318,74 @@ var CmdTunnel = &cli.Command{
 		return nil
 	},
 	Children: []*cli.Command{
+		{
+			Name: "status",
+			Description: cli.Description{
+				Short: "Show the status of the tunnel",
+				Long: strings.Join([]string{
+					"Show the status of the tunnel that's running on this machine.",
+					"",
+					"```bash frame=\"none\"",
+					"sst tunnel status",
+					"```",
+					"",
+					"This includes the health of the connection to each bastion, the open connections",
+					"for each destination, and the most recent errors when connecting to one.",
+					"",
+					"Use `--watch` to keep it updated, or `--json` to use it in a script.",
+				}, "\n"),
+			},
+			Flags: []cli.Flag{
+				{
+					Name: "json",
+					Type: "bool",
+					Description: cli.Description{
+						Short: "Output as JSON",
+						Long:  "Output the status as JSON to stdout.",
+					},
+				},
+				{
+					Name: "watch",
+					Type: "bool",
+					Description: cli.Description{
+						Short: "Keep it updated",
+						Long:  "Refresh the status every few seconds.",
+					},
+				},
+			},
+			Run: func(c *cli.Cli) error {
+				if !c.Bool("watch") {
+					status, err := tunnel.GetStatus(c.Context)
+					if err != nil {
+						return util.NewReadableError(err, "No tunnel is running. Start one with `sst tunnel`.")
+					}
+					if c.Bool("json") {
+						encoder := json.NewEncoder(os.Stdout)
+						encoder.SetIndent("", "  ")
+						return encoder.Encode(status)
+					}
+					printTunnelStatus(os.Stdout, status)
+					return nil
+				}
+				ticker := time.NewTicker(2 * time.Second)
+				defer ticker.Stop()
+				for {
+					status, err := tunnel.GetStatus(c.Context)
+					// clear the screen before drawing again
+					fmt.Print("\033[H\033[2J")
+					if err != nil {
+						fmt.Println(ui.TEXT_DIM.Render("Waiting for the tunnel to start..."))
+					} else {
+						printTunnelStatus(os.Stdout, status)
+					}
+					select {
+					case <-c.Context.Done():
+						return nil
+					case <-ticker.C:
+					}
+				}
+			},
+		},
 		{
 			Name: "install",
 			Description: cli.Description{
@@ -232,30 +468,130 @@ var CmdTunnel = &cli.Command{
 			},
 			Run: func(c *cli.Cli) error {
 				subnets := strings.Split(c.String("subnets"), ",")
-				host := c.String("host")
-				port := c.String("port")
-				user := c.String("user")
-				if port == "" {
-					port = "22"
+				config := tunnelStartConfig{}
+				if raw := os.Getenv("SST_TUNNEL_CONFIG"); raw != "" {
+					if err := json.Unmarshal([]byte(raw), &config); err != nil {
+						return err
+					}
+				} else {
+					// started by an older version of sst with a single tunnel
+					port := c.String("port")
+					if port == "" {
+						port = "22"
+					}
+					config.Tunnels = []tunnel.Config{{
+						Name:       "tunnel",
+						Host:       c.String("host") + ":" + port,
+						Username:   c.String("user"),
+						PrivateKey: os.Getenv("SSH_PRIVATE_KEY"),
+						Subnets:    subnets,
+					}}
+				}
+				if config.KnownHosts == "" {
+					config.KnownHosts = filepath.Join(global.ConfigDir(), "tunnel_known_hosts")
 				}
-				slog.Info("starting tunnel", "subnet", subnets, "host", host, "port", port)
+				slog.Info("starting tunnel", "subnet", subnets, "tunnels", len(config.Tunnels))
 				err := tunnel.Start(subnets...)
 				if err != nil {
 					return err
 				}
 				defer tunnel.Stop()
 				slog.Info("tunnel started")
-				err = tunnel.StartProxy(
-					c.Context,
-					user,
-					host+":"+port,
-					[]byte(os.Getenv("SSH_PRIVATE_KEY")),
-				)
+				err = tunnel.StartProxies(c.Context, config.Tunnels, tunnel.Options{
+					KnownHosts: config.KnownHosts,
+					Forwards:   config.Forwards,
+				})
 				if err != nil {
 					slog.Error("failed to start tunnel", "error", err)
+					if errors.Is(err, tunnel.ErrHostKeyMismatch) {
+						fmt.Println(ui.TEXT_DANGER_BOLD.Render("| ") + ui.TEXT_NORMAL.Render(err.Error()))
+					}
 				}
 				return nil
 			},
 		},
 	},
 }
+
+func printTunnelStatus(w io.Writer, status *tunnel.Status) {
+	title := "Tunnel"
+	if status.Rootless {
+		title += " (rootless)"
+	}
+	fmt.Fprintln(w, ui.TEXT_HIGHLIGHT_BOLD.Render(title)+ui.TEXT_DIM.Render(" up "+formatAge(status.Started)))
+	fmt.Fprintln(w)
+	for _, tun := range status.Tunnels {
+		state := ui.TEXT_SUCCESS_BOLD.Render("●") + ui.TEXT_NORMAL.Render("  "+tun.Name)
+		details := []string{tun.Host}
+		if tun.Connected {
+			details = append(details, "connected "+formatAge(tun.ConnectedAt))
+			if !tun.LastKeepalive.IsZero() {
+				details = append(details, fmt.Sprintf("rtt %.0fms", tun.RTT))
+			}
+		} else {
+			state = ui.TEXT_DANGER_BOLD.Render("●") + ui.TEXT_NORMAL.Render("  "+tun.Name)
+			details = append(details, "disconnected")
+		}
+		if tun.Reconnects > 0 {
+			details = append(details, fmt.Sprintf("%d reconnects", tun.Reconnects))
+		}
+		fmt.Fprintln(w, state+ui.TEXT_DIM.Render("  "+strings.Join(details, " · ")))
+		if tun.Port != 0 {
+			fmt.Fprintln(w, ui.TEXT_DIM.Render(fmt.Sprintf("   socks5://127.0.0.1:%d", tun.Port)))
+		}
+		if tun.LastError != "" {
+			fmt.Fprintln(w, ui.TEXT_DANGER.Render("   "+tun.LastError))
+		}
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, ui.TEXT_NORMAL_BOLD.Render("Connections"))
+	if len(status.Destinations) == 0 {
+		fmt.Fprintln(w, ui.TEXT_DIM.Render("   None yet"))
+	} else {
+		fmt.Fprintln(w, ui.TEXT_DIM.Render(fmt.Sprintf("   %-5s %-6s %-9s %-9s %s", "Open", "Total", "In", "Out", "Destination")))
+		for _, dest := range status.Destinations {
+			via := "direct"
+			if dest.Tunnel != "" {
+				via = "via " + dest.Tunnel
+			}
+			style := ui.TEXT_DIM
+			if dest.Open > 0 {
+				style = ui.TEXT_NORMAL
+			}
+			fmt.Fprintln(w, style.Render(fmt.Sprintf("   %-5d %-6d %-9s %-9s %s", dest.Open, dest.Total, formatBytes(dest.BytesIn), formatBytes(dest.BytesOut), dest.Address))+ui.TEXT_DIM.Render(" "+via))
+		}
+	}
+	if len(status.Errors) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, ui.TEXT_NORMAL_BOLD.Render("Recent errors"))
+		for i := len(status.Errors) - 1; i >= 0; i-- {
+			item := status.Errors[i]
+			fmt.Fprintln(w, ui.TEXT_DIM.Render("   "+item.Time.Local().Format("15:04:05")+"  "+item.Address)+"  "+ui.TEXT_DANGER.Render(item.Error))
+		}
+	}
+}
+
+func formatBytes(value int64) string {
+	const unit = 1024
+	if value < unit {
+		return fmt.Sprintf("%d B", value)
+	}
+	div, exp := int64(unit), 0
+	for n := value / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(value)/float64(div), "KMGTPE"[exp])
+}
+
+func formatAge(since time.Time) string {
+	age := time.Since(since)
+	switch {
+	case age < time.Minute:
+		return fmt.Sprintf("%ds", int(age.Seconds()))
+	case age < time.Hour:
+		return fmt.Sprintf("%dm", int(age.Minutes()))
+	default:
+		return fmt.Sprintf("%dh%dm", int(age.Hours()), int(age.Minutes())%60)
+	}
+}
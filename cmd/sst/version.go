@@ -16,6 +16,16 @@ var CmdVersion = &cli.Command{
 		Long:  `Prints the current version of the CLI.`,
 	},
 	Run: func(cli *cli.Cli) error {
+		if cli.JSON() {
+			cli.SetResult(map[string]interface{}{
+				"version": version,
+				"pulumi":  sdk.Version.String(),
+				"config":  global.ConfigDir(),
+				"arch":    runtime.GOARCH,
+				"os":      runtime.GOOS,
+			})
+			return nil
+		}
 		fmt.Println("sst", version)
 		if cli.Bool("verbose") {
 			fmt.Println("pulumi", sdk.Version)
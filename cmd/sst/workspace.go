@@ -0,0 +1,507 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sst/sst/v3/cmd/sst/cli"
+	"github.com/sst/sst/v3/cmd/sst/mosaic/errors"
+	"github.com/sst/sst/v3/cmd/sst/mosaic/ui"
+	"github.com/sst/sst/v3/internal/util"
+	"github.com/sst/sst/v3/pkg/process"
+	"github.com/sst/sst/v3/pkg/project"
+	"golang.org/x/sync/semaphore"
+)
+
+// WorkspaceFile configures the apps that `sst deploy --workspace` deploys,
+// it's optional and lives in the directory the command is run from.
+const WorkspaceFile = "sst.workspace.json"
+
+type workspaceConfig struct {
+	Concurrency int `json:"concurrency"`
+	// Apps are keyed by their directory, relative to the workspace.
+	Apps map[string]struct {
+		DependsOn []string `json:"dependsOn"`
+	} `json:"apps"`
+}
+
+// deployTarget is an app and a stage that's deployed by `--stages` or
+// `--workspace`.
+type deployTarget struct {
+	App   string
+	Dir   string
+	Stage string
+	// Config is the absolute path to the sst.config.ts of the app.
+	Config string
+	// DependsOn have to be deployed first, and the target is skipped if one
+	// of them fails.
+	DependsOn []*deployTarget
+	// After has to finish first, it's the previous stage of the same app. The
+	// stages share the .sst directory, with its outputs and logs, so they're
+	// deployed one at a time.
+	After *deployTarget
+
+	done   chan struct{}
+	result deployResult
+}
+
+func (t *deployTarget) String() string {
+	if t.App == "" {
+		return t.Stage
+	}
+	if t.Stage == "" {
+		return t.App
+	}
+	return t.App + " " + t.Stage
+}
+
+const (
+	deployStatusDeployed = "deployed"
+	deployStatusFailed   = "failed"
+	deployStatusSkipped  = "skipped"
+)
+
+type deployResult struct {
+	App      string                 `json:"app"`
+	Stage    string                 `json:"stage,omitempty"`
+	Status   string                 `json:"status"`
+	Duration string                 `json:"duration,omitempty"`
+	UpdateID string                 `json:"updateID,omitempty"`
+	Outputs  map[string]interface{} `json:"outputs,omitempty"`
+	Error    *cli.ResultError       `json:"error,omitempty"`
+}
+
+// discoverApps finds the directories with a sst.config.ts under the root,
+// relative to it.
+func discoverApps(root string) ([]string, error) {
+	apps := []string{}
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			name := entry.Name()
+			if path != root && (strings.HasPrefix(name, ".") || name == "node_modules") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if entry.Name() == "sst.config.ts" {
+			rel, err := filepath.Rel(root, filepath.Dir(path))
+			if err != nil {
+				return err
+			}
+			apps = append(apps, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	sort.Strings(apps)
+	return apps, err
+}
+
+func loadWorkspace(root string) (*workspaceConfig, error) {
+	config := &workspaceConfig{}
+	data, err := os.ReadFile(filepath.Join(root, WorkspaceFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return config, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, util.NewReadableError(err, fmt.Sprintf("Invalid %s: %s", WorkspaceFile, err.Error())).WithCode("workspace_invalid")
+	}
+	return config, nil
+}
+
+// planDeploys creates a target for each app and stage, ordered so that the
+// apps an app depends on come first.
+func planDeploys(root string, apps []string, stages []string, config *workspaceConfig) ([]*deployTarget, error) {
+	known := map[string]bool{}
+	for _, app := range apps {
+		known[app] = true
+	}
+	dependsOn := map[string][]string{}
+	for app, item := range config.Apps {
+		if !known[app] {
+			return nil, util.NewReadableError(nil, fmt.Sprintf("The app \"%s\" in %s does not have a sst.config.ts", app, WorkspaceFile)).WithCode("workspace_invalid")
+		}
+		for _, dependency := range item.DependsOn {
+			if !known[dependency] {
+				return nil, util.NewReadableError(nil, fmt.Sprintf("The app \"%s\" depends on \"%s\", which does not have a sst.config.ts", app, dependency)).WithCode("workspace_invalid")
+			}
+		}
+		dependsOn[app] = item.DependsOn
+	}
+
+	ordered := []string{}
+	state := map[string]int{}
+	var visit func(app string, path []string) error
+	visit = func(app string, path []string) error {
+		switch state[app] {
+		case 1:
+			return util.NewReadableError(nil, "The apps depend on each other: "+strings.Join(append(path, app), " -> ")).WithCode("workspace_cycle")
+		case 2:
+			return nil
+		}
+		state[app] = 1
+		for _, dependency := range dependsOn[app] {
+			if err := visit(dependency, append(path, app)); err != nil {
+				return err
+			}
+		}
+		state[app] = 2
+		ordered = append(ordered, app)
+		return nil
+	}
+	for _, app := range apps {
+		if err := visit(app, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	targets := []*deployTarget{}
+	byKey := map[string]*deployTarget{}
+	previous := map[string]*deployTarget{}
+	for _, stage := range stages {
+		for _, app := range ordered {
+			dir := filepath.Join(root, filepath.FromSlash(app))
+			target := &deployTarget{
+				App:    app,
+				Dir:    dir,
+				Stage:  stage,
+				Config: filepath.Join(dir, "sst.config.ts"),
+				done:   make(chan struct{}),
+			}
+			for _, dependency := range dependsOn[app] {
+				target.DependsOn = append(target.DependsOn, byKey[dependency+"\n"+stage])
+			}
+			target.After = previous[app]
+			previous[app] = target
+			byKey[app+"\n"+stage] = target
+			targets = append(targets, target)
+		}
+	}
+	return targets, nil
+}
+
+// runDeploys runs the targets with at most concurrency of them at a time,
+// each one after the targets it waits on.
+func runDeploys(ctx context.Context, targets []*deployTarget, concurrency int, deploy func(*deployTarget, map[string]map[string]interface{}) deployResult) []deployResult {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	lock := semaphore.NewWeighted(int64(concurrency))
+	var wg sync.WaitGroup
+	for _, target := range targets {
+		wg.Add(1)
+		go func(target *deployTarget) {
+			defer wg.Done()
+			defer close(target.done)
+			target.result = deployResult{App: target.App, Stage: target.Stage}
+			if target.After != nil {
+				<-target.After.done
+			}
+			outputs := map[string]map[string]interface{}{}
+			for _, dependency := range target.DependsOn {
+				<-dependency.done
+				if dependency.result.Status != deployStatusDeployed {
+					target.result.Status = deployStatusSkipped
+					target.result.Error = &cli.ResultError{
+						Code:    "dependency_failed",
+						Message: fmt.Sprintf("Skipped because %s did not deploy", dependency.App),
+					}
+					return
+				}
+				outputs[dependency.App] = dependency.result.Outputs
+			}
+			if err := lock.Acquire(ctx, 1); err != nil {
+				target.result.Status = deployStatusSkipped
+				target.result.Error = &cli.ResultError{Code: errors.CodeCancelled, Message: "Cancelled"}
+				return
+			}
+			defer lock.Release(1)
+			target.result = deploy(target, outputs)
+		}(target)
+	}
+	wg.Wait()
+
+	results := []deployResult{}
+	for _, target := range targets {
+		results = append(results, target.result)
+	}
+	return results
+}
+
+// deployChild deploys a target with `sst deploy --output json` in the
+// directory of the app, and forwards its progress.
+func deployChild(c *cli.Cli, target *deployTarget, outputs map[string]map[string]interface{}) deployResult {
+	result := deployResult{App: target.App, Stage: target.Stage, Status: deployStatusFailed}
+	started := time.Now()
+	defer func() { result.Duration = time.Since(started).Round(time.Second).String() }()
+
+	executable, err := os.Executable()
+	if err != nil {
+		result.Error = &cli.ResultError{Code: errors.CodeUnexpected, Message: err.Error()}
+		return result
+	}
+	args := []string{"deploy", "--output", "json", "--config", target.Config}
+	if target.Stage != "" {
+		args = append(args, "--stage", target.Stage)
+	}
+	for _, name := range []string{"continue", "dev", "frozen", "verbose"} {
+		if c.Bool(name) {
+			args = append(args, "--"+name)
+		}
+	}
+	if c.String("ttl") != "" {
+		args = append(args, "--ttl", c.String("ttl"))
+	}
+	if c.String("policy") != "" {
+		// the deploy runs in the directory of the app
+		policy, err := filepath.Abs(c.String("policy"))
+		if err != nil {
+			result.Error = &cli.ResultError{Code: errors.CodeUnexpected, Message: err.Error()}
+			return result
+		}
+		args = append(args, "--policy", policy)
+	}
+	outputsJson, err := json.Marshal(outputs)
+	if err != nil {
+		result.Error = &cli.ResultError{Code: errors.CodeUnexpected, Message: err.Error()}
+		return result
+	}
+
+	cmd := process.Command(executable, args...)
+	cmd.Dir = target.Dir
+	// the env files are loaded by each deploy for its own stage
+	cmd.Env = append(append([]string{}, c.Env()...), "SST_WORKSPACE_OUTPUTS="+string(outputsJson))
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		result.Error = &cli.ResultError{Code: errors.CodeUnexpected, Message: err.Error()}
+		return result
+	}
+	if err := cmd.Start(); err != nil {
+		result.Error = &cli.ResultError{Code: errors.CodeUnexpected, Message: err.Error()}
+		return result
+	}
+	printDeployEvent(c, target, "start", nil)
+	last := ""
+	scanner := bufio.NewScanner(stderr)
+	scanner.Buffer(make([]byte, 1024*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var event map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			if line := strings.TrimSpace(scanner.Text()); line != "" {
+				last = line
+			}
+			continue
+		}
+		kind, _ := event["type"].(string)
+		delete(event, "type")
+		delete(event, "time")
+		printDeployEvent(c, target, kind, event)
+	}
+	cmd.Wait()
+
+	var document struct {
+		OK     bool             `json:"ok"`
+		Error  *cli.ResultError `json:"error"`
+		Result struct {
+			UpdateID string                 `json:"updateID"`
+			Outputs  map[string]interface{} `json:"outputs"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &document); err != nil {
+		result.Error = &cli.ResultError{Code: errors.CodeUnexpected, Message: "The deploy exited without a result", Detail: last}
+		return result
+	}
+	result.UpdateID = document.Result.UpdateID
+	if outputs, ok := dropSecrets(document.Result.Outputs).(map[string]interface{}); ok {
+		result.Outputs = outputs
+	}
+	result.Error = document.Error
+	if document.OK && cmd.ProcessState.ExitCode() == 0 {
+		result.Status = deployStatusDeployed
+	}
+	return result
+}
+
+// dropSecrets removes the secret outputs, which the deploy result has masked,
+// so they aren't passed to the apps that depend on it. Masked items of a list
+// are set to null to keep the indexes.
+func dropSecrets(value interface{}) interface{} {
+	switch cast := value.(type) {
+	case map[string]interface{}:
+		result := map[string]interface{}{}
+		for key, item := range cast {
+			if item == project.SecretMask {
+				continue
+			}
+			result[key] = dropSecrets(item)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(cast))
+		for index, item := range cast {
+			if item == project.SecretMask {
+				continue
+			}
+			result[index] = dropSecrets(item)
+		}
+		return result
+	}
+	return value
+}
+
+var deployPrint sync.Mutex
+
+// printDeployEvent writes the progress of a target, as progress events with
+// `--output json` or a line per step otherwise.
+func printDeployEvent(c *cli.Cli, target *deployTarget, kind string, data map[string]interface{}) {
+	if c.JSON() {
+		event := map[string]interface{}{}
+		for key, value := range data {
+			event[key] = value
+		}
+		event["app"] = target.App
+		event["stage"] = target.Stage
+		c.Progress(kind, event)
+		return
+	}
+	line := ""
+	switch kind {
+	case "start":
+		line = ui.TEXT_INFO.Render("Deploying")
+	case "resource.failed":
+		line = ui.TEXT_DANGER.Render("Failed   ") + " " + fmt.Sprint(data["name"])
+	case "locked":
+		line = ui.TEXT_DANGER.Render("Locked   ") + " a concurrent update was detected"
+	case "approval":
+		line = ui.TEXT_WARNING.Render("Approval ") + " changes need to be approved with --approve " + fmt.Sprint(data["token"])
+	case "complete":
+		line = ui.TEXT_DIM.Render("Complete ")
+	default:
+		return
+	}
+	deployPrint.Lock()
+	defer deployPrint.Unlock()
+	fmt.Println(ui.TEXT_HIGHLIGHT_BOLD.Render("|"), ui.TEXT_NORMAL_BOLD.Render(fmt.Sprintf("%-24s", target.String())), line)
+}
+
+// deployMany deploys several stages, apps, or both, and summarizes the
+// result of each one.
+func deployMany(c *cli.Cli) error {
+	stages := splitFlag(c.String("stages"))
+	if len(stages) == 0 {
+		stages = []string{c.String("stage")}
+	}
+
+	root := ""
+	cfgPath := ""
+	apps := []string{""}
+	config := &workspaceConfig{}
+	if c.Bool("workspace") {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+		root = cwd
+		config, err = loadWorkspace(root)
+		if err != nil {
+			return err
+		}
+		apps, err = discoverApps(root)
+		if err != nil {
+			return err
+		}
+		if len(apps) == 0 {
+			return util.NewReadableError(nil, "Could not find any sst.config.ts in this directory").WithCode("config_not_found")
+		}
+	} else {
+		var err error
+		cfgPath, err = c.Discover()
+		if err != nil {
+			return err
+		}
+		root = filepath.Dir(cfgPath)
+	}
+
+	targets, err := planDeploys(root, apps, stages, config)
+	if err != nil {
+		return err
+	}
+	if cfgPath != "" {
+		// the config can be named something other than sst.config.ts
+		for _, target := range targets {
+			target.Config = cfgPath
+		}
+	}
+	concurrency := config.Concurrency
+	if c.String("concurrency") != "" {
+		concurrency, err = strconv.Atoi(c.String("concurrency"))
+		if err != nil || concurrency < 1 {
+			return util.NewReadableError(err, "The --concurrency must be a number greater than 0").WithCode("concurrency_invalid")
+		}
+	}
+	if concurrency == 0 {
+		concurrency = 2
+	}
+
+	results := runDeploys(c.Context, targets, concurrency, func(target *deployTarget, outputs map[string]map[string]interface{}) deployResult {
+		return deployChild(c, target, outputs)
+	})
+
+	failed := 0
+	for _, result := range results {
+		if result.Status != deployStatusDeployed {
+			failed++
+		}
+	}
+	if c.JSON() {
+		c.SetResult(map[string]interface{}{"deploys": results})
+	} else {
+		printDeploySummary(targets)
+	}
+	if failed > 0 {
+		return util.NewReadableError(nil, fmt.Sprintf("%d of %d deploys did not complete", failed, len(results))).WithCode("deploy_failed")
+	}
+	return nil
+}
+
+func printDeploySummary(targets []*deployTarget) {
+	fmt.Println()
+	width := 0
+	for _, target := range targets {
+		width = max(width, len(target.String()))
+	}
+	for _, target := range targets {
+		result := target.result
+		status := ui.TEXT_SUCCESS_BOLD.Render("✓  " + fmt.Sprintf("%-9s", result.Status))
+		if result.Status == deployStatusFailed {
+			status = ui.TEXT_DANGER_BOLD.Render("✕  " + fmt.Sprintf("%-9s", result.Status))
+		}
+		if result.Status == deployStatusSkipped {
+			status = ui.TEXT_DIM.Render("-  " + fmt.Sprintf("%-9s", result.Status))
+		}
+		detail := result.Duration
+		if result.Error != nil {
+			detail = result.Error.Message
+		}
+		fmt.Println(status, ui.TEXT_NORMAL_BOLD.Render(fmt.Sprintf("%-*s", width, target.String())), ui.TEXT_DIM.Render(detail))
+	}
+	fmt.Println()
+}
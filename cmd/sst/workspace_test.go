@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sst/sst/v3/internal/util"
+)
+
+func TestDiscoverApps(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"", "services/api", "services/web", "node_modules/pkg", ".sst/platform"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(root, dir, "sst.config.ts"), []byte(""), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	apps, err := discoverApps(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{".", "services/api", "services/web"}
+	if !reflect.DeepEqual(apps, want) {
+		t.Fatalf("got %v, want %v", apps, want)
+	}
+}
+
+func workspaceWith(dependsOn map[string][]string) *workspaceConfig {
+	config := &workspaceConfig{Apps: map[string]struct {
+		DependsOn []string `json:"dependsOn"`
+	}{}}
+	for app, dependencies := range dependsOn {
+		item := config.Apps[app]
+		item.DependsOn = dependencies
+		config.Apps[app] = item
+	}
+	return config
+}
+
+func TestPlanDeploys(t *testing.T) {
+	apps := []string{"api", "database", "web"}
+	config := workspaceWith(map[string][]string{
+		"api": {"database"},
+		"web": {"api"},
+	})
+	targets, err := planDeploys("/repo", apps, []string{"dev", "prod"}, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []string{}
+	for _, target := range targets {
+		got = append(got, target.String())
+	}
+	want := []string{"database dev", "api dev", "web dev", "database prod", "api prod", "web prod"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if targets[1].Dir != filepath.Join("/repo", "api") {
+		t.Errorf("dir = %s", targets[1].Dir)
+	}
+	if targets[1].Config != filepath.Join("/repo", "api", "sst.config.ts") {
+		t.Errorf("config = %s", targets[1].Config)
+	}
+	if len(targets[1].DependsOn) != 1 || targets[1].DependsOn[0] != targets[0] {
+		t.Errorf("api dev should depend on database dev")
+	}
+	if targets[4].DependsOn[0] != targets[3] {
+		t.Errorf("api prod should depend on database prod")
+	}
+	if targets[0].After != nil || targets[3].After != targets[0] {
+		t.Errorf("database prod should wait on database dev")
+	}
+
+	targets, err = planDeploys("/repo", []string{""}, []string{"a", "b", "c"}, &workspaceConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if targets[1].After != targets[0] || targets[2].After != targets[1] {
+		t.Errorf("the stages of an app should be deployed one at a time")
+	}
+}
+
+func TestPlanDeploysErrors(t *testing.T) {
+	cases := []struct {
+		name      string
+		dependsOn map[string][]string
+		code      string
+	}{
+		{"cycle", map[string][]string{"api": {"web"}, "web": {"api"}}, "workspace_cycle"},
+		{"unknown dependency", map[string][]string{"api": {"missing"}}, "workspace_invalid"},
+		{"unknown app", map[string][]string{"missing": {"api"}}, "workspace_invalid"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := planDeploys("/repo", []string{"api", "web"}, []string{"dev"}, workspaceWith(tc.dependsOn))
+			readable, ok := err.(*util.ReadableError)
+			if !ok {
+				t.Fatalf("expected a readable error, got %v", err)
+			}
+			if readable.Code() != tc.code {
+				t.Errorf("code = %s, want %s", readable.Code(), tc.code)
+			}
+		})
+	}
+}
+
+func TestRunDeploys(t *testing.T) {
+	config := workspaceWith(map[string][]string{
+		"api": {"database"},
+		"web": {"api"},
+	})
+	targets, err := planDeploys("/repo", []string{"api", "database", "web", "other"}, []string{"dev"}, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	running, peak := 0, 0
+	received := map[string]map[string]map[string]interface{}{}
+	results := runDeploys(context.Background(), targets, 2, func(target *deployTarget, outputs map[string]map[string]interface{}) deployResult {
+		mu.Lock()
+		running++
+		peak = max(peak, running)
+		received[target.App] = outputs
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+
+		result := deployResult{App: target.App, Stage: target.Stage, Status: deployStatusDeployed}
+		result.Outputs = map[string]interface{}{"name": target.App}
+		if target.App == "api" {
+			result.Status = deployStatusFailed
+		}
+		return result
+	})
+
+	statuses := map[string]string{}
+	for _, result := range results {
+		statuses[result.App] = result.Status
+	}
+	want := map[string]string{
+		"database": deployStatusDeployed,
+		"api":      deployStatusFailed,
+		"web":      deployStatusSkipped,
+		"other":    deployStatusDeployed,
+	}
+	if !reflect.DeepEqual(statuses, want) {
+		t.Fatalf("got %v, want %v", statuses, want)
+	}
+	if peak > 2 {
+		t.Errorf("ran %d deploys at a time", peak)
+	}
+	if received["api"]["database"]["name"] != "database" {
+		t.Errorf("api did not receive the outputs of database: %v", received["api"])
+	}
+	if _, ok := received["web"]; ok {
+		t.Errorf("web should not have been deployed")
+	}
+	for _, result := range results {
+		if result.App == "web" && (result.Error == nil || result.Error.Code != "dependency_failed") {
+			t.Errorf("web error = %v", result.Error)
+		}
+	}
+}
+
+func TestDropSecrets(t *testing.T) {
+	outputs := map[string]interface{}{
+		"url":      "https://example.com",
+		"password": "********",
+		"database": map[string]interface{}{"host": "db", "password": "********"},
+		"keys":     []interface{}{"public", "********"},
+	}
+	expected := map[string]interface{}{
+		"url":      "https://example.com",
+		"database": map[string]interface{}{"host": "db"},
+		"keys":     []interface{}{"public", nil},
+	}
+	if got := dropSecrets(outputs); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("got %v", got)
+	}
+}
@@ -21,6 +21,7 @@ type ReadableError struct {
 	message string
 	error   error
 	hinted  bool
+	code    string
 }
 
 func NewReadableError(err error, message string) *ReadableError {
@@ -43,6 +44,17 @@ func (e *ReadableError) IsHinted() bool {
 	return e.hinted
 }
 
+// WithCode sets a stable code on the error that identifies it in the
+// `--output json` result, it should not change once released.
+func (e *ReadableError) WithCode(code string) *ReadableError {
+	e.code = code
+	return e
+}
+
+func (e *ReadableError) Code() string {
+	return e.code
+}
+
 type CleanupFunc func() error
 
 type KeyLock struct {
@@ -0,0 +1,282 @@
+package cost
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/sst/sst/v3/pkg/flag"
+	"github.com/sst/sst/v3/pkg/global"
+)
+
+// HoursPerMonth is the number of hours AWS uses when converting hourly prices
+// to monthly ones.
+const HoursPerMonth = 730
+
+//go:embed prices.json
+var defaultTable []byte
+
+// Table holds the prices and the assumed usage used to estimate the monthly
+// cost of a resource. Prices are on-demand us-east-1 list prices.
+type Table struct {
+	Currency string `json:"currency"`
+	Updated  string `json:"updated"`
+	// Rates are unit prices keyed by "<provider>.<service>.<unit>".
+	Rates map[string]float64 `json:"rates"`
+	// Usage is the assumed monthly usage for resources that are billed on
+	// traffic rather than on provisioned capacity.
+	Usage map[string]float64 `json:"usage"`
+	// Instances are hourly prices keyed by instance class.
+	Instances map[string]float64 `json:"instances"`
+}
+
+func (t *Table) Rate(key string) float64 {
+	return t.Rates[key]
+}
+
+func (t *Table) UsageOf(key string) float64 {
+	return t.Usage[key]
+}
+
+// TablePath is where an updated price table can be dropped in to override
+// the one bundled with the CLI.
+func TablePath() string {
+	if flag.SST_COST_TABLE != "" {
+		return flag.SST_COST_TABLE
+	}
+	return filepath.Join(global.ConfigDir(), "prices.json")
+}
+
+// LoadTable returns the bundled price table merged with the entries from the
+// local override file if one exists.
+func LoadTable() (*Table, error) {
+	table, err := parseTable(defaultTable)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(TablePath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return table, nil
+		}
+		return nil, err
+	}
+	override, err := parseTable(data)
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("loaded price table override", "path", TablePath(), "updated", override.Updated)
+	table.merge(override)
+	return table, nil
+}
+
+func parseTable(data []byte) (*Table, error) {
+	table := &Table{
+		Rates:     map[string]float64{},
+		Usage:     map[string]float64{},
+		Instances: map[string]float64{},
+	}
+	if err := json.Unmarshal(data, table); err != nil {
+		return nil, err
+	}
+	return table, nil
+}
+
+func (t *Table) merge(other *Table) {
+	if other.Currency != "" {
+		t.Currency = other.Currency
+	}
+	if other.Updated != "" {
+		t.Updated = other.Updated
+	}
+	for key, value := range other.Rates {
+		t.Rates[key] = value
+	}
+	for key, value := range other.Usage {
+		t.Usage[key] = value
+	}
+	for key, value := range other.Instances {
+		t.Instances[key] = value
+	}
+}
+
+// Estimator returns the monthly cost of a resource given its inputs. It
+// returns false if there is not enough information to make an estimate.
+type Estimator func(table *Table, inputs map[string]interface{}) (float64, bool)
+
+var estimators = map[string]Estimator{}
+
+// Register adds an estimator for the given Pulumi resource type, replacing
+// any existing one.
+func Register(resourceType string, estimator Estimator) {
+	estimators[resourceType] = estimator
+}
+
+func lookup(resourceType string) (Estimator, bool) {
+	estimator, ok := estimators[resourceType]
+	return estimator, ok
+}
+
+type Delta struct {
+	URN    string         `json:"urn"`
+	Type   string         `json:"type"`
+	Op     apitype.OpType `json:"op"`
+	Before float64        `json:"before"`
+	After  float64        `json:"after"`
+}
+
+func (d Delta) Monthly() float64 {
+	return d.After - d.Before
+}
+
+type Summary struct {
+	Currency string  `json:"currency"`
+	Deltas   []Delta `json:"deltas"`
+	Total    float64 `json:"total"`
+	// Unknown lists the URNs of changed resources that do not have an estimator
+	// or could not be priced before or after the change.
+	Unknown []string `json:"unknown"`
+}
+
+// Estimate computes the monthly cost delta of the given planned steps.
+// Resources that are replaced are counted once, as the difference between
+// their old and new inputs.
+func Estimate(table *Table, steps []apitype.StepEventMetadata) *Summary {
+	summary := &Summary{
+		Currency: table.Currency,
+		Deltas:   []Delta{},
+		Unknown:  []string{},
+	}
+	tasks := newTaskDefinitions(steps)
+	seen := map[string]bool{}
+	for _, step := range steps {
+		if seen[step.URN] {
+			continue
+		}
+		var before, after map[string]interface{}
+		switch step.Op {
+		case apitype.OpCreate:
+			after = tasks.resolve(step.New, false)
+		case apitype.OpDelete:
+			before = tasks.resolve(step.Old, true)
+		case apitype.OpUpdate, apitype.OpReplace, apitype.OpCreateReplacement:
+			before = tasks.resolve(step.Old, true)
+			after = tasks.resolve(step.New, false)
+		default:
+			continue
+		}
+		seen[step.URN] = true
+		estimator, ok := lookup(step.Type)
+		if !ok {
+			summary.Unknown = append(summary.Unknown, step.URN)
+			continue
+		}
+		delta := Delta{
+			URN:  step.URN,
+			Type: step.Type,
+			Op:   step.Op,
+		}
+		// both sides have to be priced, or the delta is only half of it
+		known := true
+		if before != nil {
+			value, ok := estimator(table, before)
+			delta.Before = value
+			known = known && ok
+		}
+		if after != nil {
+			value, ok := estimator(table, after)
+			delta.After = value
+			known = known && ok
+		}
+		if !known {
+			summary.Unknown = append(summary.Unknown, step.URN)
+			continue
+		}
+		if delta.Monthly() == 0 {
+			continue
+		}
+		summary.Deltas = append(summary.Deltas, delta)
+		summary.Total += delta.Monthly()
+	}
+	sort.SliceStable(summary.Deltas, func(i, j int) bool {
+		return summary.Deltas[i].URN < summary.Deltas[j].URN
+	})
+	return summary
+}
+
+const taskDefinitionType = "aws:ecs/taskDefinition:TaskDefinition"
+
+// taskDefinitions finds the task definition a service runs, so the service
+// can be priced with its cpu and memory.
+type taskDefinitions struct {
+	byArn map[string]map[string]interface{}
+	// byParent is used when the task definition is created in the same
+	// update, so its ARN isn't known yet. The old and new states are kept
+	// apart.
+	byParent [2]map[string]map[string]interface{}
+}
+
+func newTaskDefinitions(steps []apitype.StepEventMetadata) *taskDefinitions {
+	result := &taskDefinitions{
+		byArn:    map[string]map[string]interface{}{},
+		byParent: [2]map[string]map[string]interface{}{{}, {}},
+	}
+	for _, step := range steps {
+		if step.Type != taskDefinitionType {
+			continue
+		}
+		for i, state := range []*apitype.StepEventStateMetadata{step.New, step.Old} {
+			if state == nil {
+				continue
+			}
+			if arn, ok := state.Outputs["arn"].(string); ok && arn != "" {
+				result.byArn[arn] = inputs(state)
+			}
+			if state.Parent != "" {
+				result.byParent[i][string(state.Parent)] = inputs(state)
+			}
+		}
+	}
+	return result
+}
+
+// resolve returns the inputs of a state, with the taskDefinition of a service
+// replaced by the inputs of the task definition it references.
+func (t *taskDefinitions) resolve(state *apitype.StepEventStateMetadata, old bool) map[string]interface{} {
+	result := inputs(state)
+	if state == nil || state.Type != "aws:ecs/service:Service" {
+		return result
+	}
+	side := 0
+	if old {
+		side = 1
+	}
+	task, ok := t.byArn[fmt.Sprint(result["taskDefinition"])]
+	if !ok {
+		task, ok = t.byParent[side][string(state.Parent)]
+	}
+	if !ok {
+		return result
+	}
+	resolved := map[string]interface{}{}
+	for key, value := range result {
+		resolved[key] = value
+	}
+	resolved["taskDefinition"] = task
+	return resolved
+}
+
+func inputs(state *apitype.StepEventStateMetadata) map[string]interface{} {
+	if state == nil {
+		return map[string]interface{}{}
+	}
+	if state.Inputs == nil {
+		return map[string]interface{}{}
+	}
+	return state.Inputs
+}
@@ -0,0 +1,161 @@
+package cost
+
+import (
+	"math"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+)
+
+func testTable(t *testing.T) *Table {
+	t.Helper()
+	table, err := parseTable(defaultTable)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return table
+}
+
+func almostEqual(a, b float64) bool {
+	return math.Abs(a-b) < 0.0001
+}
+
+func TestEstimateCreateAndDelete(t *testing.T) {
+	table := testTable(t)
+	summary := Estimate(table, []apitype.StepEventMetadata{
+		{
+			URN:  "urn:pulumi:dev::app::aws:ec2/natGateway:NatGateway::Nat",
+			Type: "aws:ec2/natGateway:NatGateway",
+			Op:   apitype.OpCreate,
+			New:  &apitype.StepEventStateMetadata{Inputs: map[string]interface{}{}},
+		},
+		{
+			URN:  "urn:pulumi:dev::app::aws:rds/instance:Instance::Db",
+			Type: "aws:rds/instance:Instance",
+			Op:   apitype.OpDelete,
+			Old: &apitype.StepEventStateMetadata{Inputs: map[string]interface{}{
+				"instanceClass":    "db.t4g.micro",
+				"allocatedStorage": float64(20),
+			}},
+		},
+	})
+
+	if len(summary.Deltas) != 2 {
+		t.Fatalf("expected 2 deltas, got %d", len(summary.Deltas))
+	}
+	nat := table.Rate("aws.nat.hour")*HoursPerMonth + table.UsageOf("aws.nat.gb")*table.Rate("aws.nat.gbProcessed")
+	db := 0.016*HoursPerMonth + 20*table.Rate("aws.rds.gbMonth")
+	if !almostEqual(summary.Total, nat-db) {
+		t.Errorf("total = %v, want %v", summary.Total, nat-db)
+	}
+}
+
+func TestEstimateReplaceCountedOnce(t *testing.T) {
+	table := testTable(t)
+	urn := "urn:pulumi:dev::app::aws:ecs/service:Service::Service"
+	task := map[string]interface{}{"cpu": "256", "memory": "512"}
+	old := &apitype.StepEventStateMetadata{Type: "aws:ecs/service:Service", Inputs: map[string]interface{}{"desiredCount": float64(1), "taskDefinition": task}}
+	next := &apitype.StepEventStateMetadata{Type: "aws:ecs/service:Service", Inputs: map[string]interface{}{"desiredCount": float64(3), "taskDefinition": task}}
+	summary := Estimate(table, []apitype.StepEventMetadata{
+		{URN: urn, Type: "aws:ecs/service:Service", Op: apitype.OpCreateReplacement, Old: old, New: next},
+		{URN: urn, Type: "aws:ecs/service:Service", Op: apitype.OpReplace, Old: old, New: next},
+	})
+	if len(summary.Deltas) != 1 {
+		t.Fatalf("expected 1 delta, got %d", len(summary.Deltas))
+	}
+	want := 2 * (0.25*table.Rate("aws.fargate.vcpuHour") + 0.5*table.Rate("aws.fargate.gbHour")) * HoursPerMonth
+	if !almostEqual(summary.Total, want) {
+		t.Errorf("total = %v, want %v", summary.Total, want)
+	}
+}
+
+func TestEstimateService(t *testing.T) {
+	table := testTable(t)
+	task := func(urn string, op apitype.OpType, parent string, arn string, cpu string) apitype.StepEventMetadata {
+		state := &apitype.StepEventStateMetadata{
+			Type:    "aws:ecs/taskDefinition:TaskDefinition",
+			Parent:  parent,
+			Inputs:  map[string]interface{}{"cpu": cpu, "memory": "2048"},
+			Outputs: map[string]interface{}{},
+		}
+		if arn != "" {
+			state.Outputs["arn"] = arn
+		}
+		return apitype.StepEventMetadata{URN: urn, Type: "aws:ecs/taskDefinition:TaskDefinition", Op: op, New: state}
+	}
+	service := func(urn string, parent string, taskDefinition string, count float64) apitype.StepEventMetadata {
+		return apitype.StepEventMetadata{URN: urn, Type: "aws:ecs/service:Service", Op: apitype.OpCreate, New: &apitype.StepEventStateMetadata{
+			Type:   "aws:ecs/service:Service",
+			Parent: parent,
+			Inputs: map[string]interface{}{"desiredCount": count, "taskDefinition": taskDefinition},
+		}}
+	}
+	summary := Estimate(table, []apitype.StepEventMetadata{
+		// an existing task definition, referenced by its ARN
+		task("urn:api-task", apitype.OpSame, "urn:api", "arn:api:1", "1024"),
+		service("urn:api-service", "urn:api", "arn:api:1", 2),
+		// a task definition that's created in the same update
+		task("urn:web-task", apitype.OpCreate, "urn:web", "", "512"),
+		service("urn:web-service", "urn:web", "04da6b54-80e4-46f7-96ec-b56ff0331ba9", 1),
+		// a task definition that no service runs
+		task("urn:unused-task", apitype.OpCreate, "urn:unused", "", "4096"),
+	})
+	if len(summary.Unknown) != 0 {
+		t.Fatalf("expected everything to be priced, got %v", summary.Unknown)
+	}
+	hourly := func(cpu float64) float64 {
+		return cpu/1024*table.Rate("aws.fargate.vcpuHour") + 2*table.Rate("aws.fargate.gbHour")
+	}
+	want := (2*hourly(1024) + hourly(512)) * HoursPerMonth
+	if !almostEqual(summary.Total, want) {
+		t.Errorf("total = %v, want %v", summary.Total, want)
+	}
+	if len(summary.Deltas) != 2 {
+		t.Errorf("expected only the services to cost anything, got %v", summary.Deltas)
+	}
+}
+
+func TestEstimateUnknown(t *testing.T) {
+	summary := Estimate(testTable(t), []apitype.StepEventMetadata{
+		{URN: "urn:pulumi:dev::app::aws:sqs/queue:Queue::Queue", Type: "aws:sqs/queue:Queue", Op: apitype.OpCreate},
+		{URN: "urn:pulumi:dev::app::aws:rds/instance:Instance::Db", Type: "aws:rds/instance:Instance", Op: apitype.OpCreate,
+			New: &apitype.StepEventStateMetadata{Inputs: map[string]interface{}{"instanceClass": "db.x99.huge"}}},
+		{URN: "urn:pulumi:dev::app::aws:lambda/function:Function::Fn", Type: "aws:lambda/function:Function", Op: apitype.OpSame},
+	})
+	if len(summary.Unknown) != 2 {
+		t.Fatalf("expected 2 unknown, got %v", summary.Unknown)
+	}
+	if summary.Total != 0 {
+		t.Errorf("expected zero total, got %v", summary.Total)
+	}
+}
+
+func TestLoadTableOverride(t *testing.T) {
+	table := testTable(t)
+	override, err := parseTable([]byte(`{"updated":"2026-01-01","rates":{"aws.nat.hour":1}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	table.merge(override)
+	if table.Rate("aws.nat.hour") != 1 {
+		t.Errorf("rate = %v, want 1", table.Rate("aws.nat.hour"))
+	}
+	if table.Rate("aws.s3.gbMonth") == 0 {
+		t.Error("expected bundled rates to be kept")
+	}
+	if table.Updated != "2026-01-01" {
+		t.Errorf("updated = %q", table.Updated)
+	}
+}
+
+func TestEstimateOneSidePriced(t *testing.T) {
+	urn := "urn:pulumi:dev::app::aws:rds/instance:Instance::Db"
+	summary := Estimate(testTable(t), []apitype.StepEventMetadata{
+		{URN: urn, Type: "aws:rds/instance:Instance", Op: apitype.OpUpdate,
+			Old: &apitype.StepEventStateMetadata{Inputs: map[string]interface{}{"instanceClass": "db.x99.huge"}},
+			New: &apitype.StepEventStateMetadata{Inputs: map[string]interface{}{"instanceClass": "db.t4g.micro"}}},
+	})
+	if len(summary.Deltas) != 0 || len(summary.Unknown) != 1 || summary.Unknown[0] != urn {
+		t.Fatalf("expected the resource to be unknown, got %v %v", summary.Deltas, summary.Unknown)
+	}
+}
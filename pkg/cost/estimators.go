@@ -0,0 +1,138 @@
+package cost
+
+import (
+	"strconv"
+)
+
+func init() {
+	Register("aws:lambda/function:Function", estimateLambda)
+	Register("aws:s3/bucket:Bucket", estimateBucket)
+	Register("aws:s3/bucketV2:BucketV2", estimateBucket)
+	Register("aws:cloudfront/distribution:Distribution", estimateDistribution)
+	Register("aws:rds/instance:Instance", estimateRdsInstance)
+	Register("aws:rds/clusterInstance:ClusterInstance", estimateRdsClusterInstance)
+	Register("aws:rds/cluster:Cluster", estimateRdsCluster)
+	Register("aws:ec2/natGateway:NatGateway", estimateNatGateway)
+	Register("aws:ecs/service:Service", estimateService)
+	Register(taskDefinitionType, estimateFree)
+	Register("cloudflare:index/workerScript:WorkerScript", estimateWorker)
+	Register("cloudflare:index/workersScript:WorkersScript", estimateWorker)
+}
+
+func estimateLambda(table *Table, inputs map[string]interface{}) (float64, bool) {
+	memory, ok := number(inputs, "memorySize")
+	if !ok {
+		memory = 128
+	}
+	requests := table.UsageOf("aws.lambda.requests")
+	seconds := requests * table.UsageOf("aws.lambda.durationMs") / 1000
+	return requests*table.Rate("aws.lambda.request") +
+		seconds*(memory/1024)*table.Rate("aws.lambda.gbSecond"), true
+}
+
+func estimateBucket(table *Table, inputs map[string]interface{}) (float64, bool) {
+	return table.UsageOf("aws.s3.gb")*table.Rate("aws.s3.gbMonth") +
+		table.UsageOf("aws.s3.requests")*table.Rate("aws.s3.request"), true
+}
+
+func estimateDistribution(table *Table, inputs map[string]interface{}) (float64, bool) {
+	if enabled, ok := inputs["enabled"].(bool); ok && !enabled {
+		return 0, true
+	}
+	return table.UsageOf("aws.cloudfront.gb")*table.Rate("aws.cloudfront.gbOut") +
+		table.UsageOf("aws.cloudfront.requests")*table.Rate("aws.cloudfront.request"), true
+}
+
+func estimateRdsInstance(table *Table, inputs map[string]interface{}) (float64, bool) {
+	class, _ := inputs["instanceClass"].(string)
+	hourly, ok := table.Instances[class]
+	if !ok {
+		return 0, false
+	}
+	if multiAz, ok := inputs["multiAz"].(bool); ok && multiAz {
+		hourly *= 2
+	}
+	storage, _ := number(inputs, "allocatedStorage")
+	return hourly*HoursPerMonth + storage*table.Rate("aws.rds.gbMonth"), true
+}
+
+func estimateRdsClusterInstance(table *Table, inputs map[string]interface{}) (float64, bool) {
+	class, _ := inputs["instanceClass"].(string)
+	hourly, ok := table.Instances[class]
+	if !ok {
+		return 0, false
+	}
+	return hourly * HoursPerMonth, true
+}
+
+// Serverless v2 capacity is billed on the cluster, the instances themselves
+// are priced at zero in the table.
+func estimateRdsCluster(table *Table, inputs map[string]interface{}) (float64, bool) {
+	scaling, ok := inputs["serverlessv2ScalingConfiguration"].(map[string]interface{})
+	if !ok {
+		return 0, true
+	}
+	min, ok := number(scaling, "minCapacity")
+	if !ok {
+		return 0, false
+	}
+	return min * table.Rate("aws.rds.acuHour") * HoursPerMonth, true
+}
+
+func estimateNatGateway(table *Table, inputs map[string]interface{}) (float64, bool) {
+	return table.Rate("aws.nat.hour")*HoursPerMonth +
+		table.UsageOf("aws.nat.gb")*table.Rate("aws.nat.gbProcessed"), true
+}
+
+// A service is priced as its desired count of Fargate tasks, always running.
+// The taskDefinition is resolved to the inputs of the task definition by
+// Estimate.
+func estimateService(table *Table, inputs map[string]interface{}) (float64, bool) {
+	if launchType, ok := inputs["launchType"].(string); ok && launchType == "EC2" {
+		// the instances are billed instead
+		return 0, true
+	}
+	count, ok := number(inputs, "desiredCount")
+	if !ok {
+		return 0, true
+	}
+	task, ok := inputs["taskDefinition"].(map[string]interface{})
+	if !ok {
+		return 0, false
+	}
+	cpu, cpuOk := number(task, "cpu")
+	memory, memoryOk := number(task, "memory")
+	if !cpuOk || !memoryOk {
+		return 0, false
+	}
+	return count * (cpu/1024*table.Rate("aws.fargate.vcpuHour") +
+		memory/1024*table.Rate("aws.fargate.gbHour")) * HoursPerMonth, true
+}
+
+// Resources that don't cost anything on their own, like a task definition
+// that's only billed when a service runs it.
+func estimateFree(table *Table, inputs map[string]interface{}) (float64, bool) {
+	return 0, true
+}
+
+func estimateWorker(table *Table, inputs map[string]interface{}) (float64, bool) {
+	requests := table.UsageOf("cloudflare.worker.requests")
+	return requests*table.Rate("cloudflare.worker.request") +
+		requests*table.UsageOf("cloudflare.worker.cpuMs")*table.Rate("cloudflare.worker.cpuMs"), true
+}
+
+func number(inputs map[string]interface{}, key string) (float64, bool) {
+	switch value := inputs[key].(type) {
+	case float64:
+		return value, true
+	case int:
+		return float64(value), true
+	case string:
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return 0, false
+		}
+		return parsed, true
+	}
+	return 0, false
+}
@@ -24,6 +24,23 @@ var SST_EXPERIMENTAL = isTrue("SST_EXPERIMENTAL") || isTrue("SST_EXPERIMENTAL_RU
 var SST_RUN_ID = os.Getenv("SST_RUN_ID")
 var SST_SKIP_APPSYNC = isTrue("SST_SKIP_APPSYNC")
 var SST_NO_BUN = isTrue("NO_BUN") || isTrue("SST_NO_BUN")
+var SST_NPM_MIRROR = os.Getenv("SST_NPM_MIRROR")
+
+// SST_NPM_OFFLINE only resolves packages from SST_NPM_MIRROR and the cache,
+// without falling back to the registry
+var SST_NPM_OFFLINE = isTrue("SST_NPM_OFFLINE")
+var SST_COST_TABLE = os.Getenv("SST_COST_TABLE")
+var SST_TUNNEL_MODE = os.Getenv("SST_TUNNEL_MODE")
+var SST_TUNNEL_FORWARDS = os.Getenv("SST_TUNNEL_FORWARDS")
+
+// SST_TRACE_ENDPOINT is an OTLP/HTTP endpoint that deploys are sent to as traces
+var SST_TRACE_ENDPOINT = os.Getenv("SST_TRACE_ENDPOINT")
+
+// SST_TRACE_HEADERS are sent with the traces, as comma separated key=value pairs
+var SST_TRACE_HEADERS = os.Getenv("SST_TRACE_HEADERS")
+
+// SST_TRACE_FILE is a file that traces are appended to, one OTLP JSON document per line
+var SST_TRACE_FILE = os.Getenv("SST_TRACE_FILE")
 
 func isTrue(name string) bool {
 	val, ok := os.LookupEnv(name)
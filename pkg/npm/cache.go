@@ -0,0 +1,77 @@
+package npm
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// Metadata for dist-tags like "latest" can change at any time so it is only
+// reused for a short while. Metadata for exact versions never changes.
+const cacheTTL = 5 * time.Minute
+
+type cacheEntry struct {
+	Fetched time.Time `json:"fetched"`
+	Package *Package  `json:"package"`
+}
+
+func cacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "sst", "npm")
+}
+
+func isExact(version string) bool {
+	_, err := semver.StrictNewVersion(version)
+	return err == nil
+}
+
+func (r Registry) cachePath(pkgURL string) string {
+	sum := sha256.Sum256([]byte(pkgURL))
+	return filepath.Join(r.cache, hex.EncodeToString(sum[:])+".json")
+}
+
+// readCache returns the cached metadata for the given url and whether it is
+// still fresh. Pass immutable for lookups that are never invalidated.
+func (r Registry) readCache(pkgURL string, immutable bool) (*Package, bool) {
+	if r.cache == "" {
+		return nil, false
+	}
+	data, err := os.ReadFile(r.cachePath(pkgURL))
+	if err != nil {
+		return nil, false
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.Package == nil {
+		return nil, false
+	}
+	return entry.Package, immutable || time.Since(entry.Fetched) < cacheTTL
+}
+
+func (r Registry) writeCache(pkgURL string, pkg *Package) {
+	if r.cache == "" {
+		return
+	}
+	if err := os.MkdirAll(r.cache, 0755); err != nil {
+		return
+	}
+	data, err := json.Marshal(cacheEntry{
+		Fetched: time.Now(),
+		Package: pkg,
+	})
+	if err != nil {
+		return
+	}
+	tmp := r.cachePath(pkgURL) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return
+	}
+	os.Rename(tmp, r.cachePath(pkgURL))
+}
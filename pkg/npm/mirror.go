@@ -0,0 +1,143 @@
+package npm
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// The mirror is a flat directory of tarballs named the way `npm pack` names
+// them, e.g. `pulumi-aws-6.66.2.tgz` for `@pulumi/aws@6.66.2`. It is set with
+// SST_NPM_MIRROR and lets `sst install` run without network access.
+
+func tarballPrefix(name string) string {
+	return strings.ReplaceAll(strings.TrimPrefix(name, "@"), "/", "-") + "-"
+}
+
+// Mirrored returns the path to the tarball for the given package in the
+// mirror directory, if there is one.
+func (r Registry) Mirrored(name string, version string) (string, bool) {
+	if r.mirror == "" {
+		return "", false
+	}
+	match, err := r.findTarball(name, version)
+	if err != nil {
+		return "", false
+	}
+	return match, true
+}
+
+func (r Registry) findTarball(name string, version string) (string, error) {
+	entries, err := os.ReadDir(r.mirror)
+	if err != nil {
+		return "", err
+	}
+	prefix := tarballPrefix(name)
+	var constraint *semver.Constraints
+	if version != "latest" && !isExact(version) {
+		constraint, err = semver.NewConstraint(version)
+		if err != nil {
+			return "", err
+		}
+	}
+	var best *semver.Version
+	bestFile := ""
+	for _, entry := range entries {
+		file := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(file, prefix) || !strings.HasSuffix(file, ".tgz") {
+			continue
+		}
+		raw := strings.TrimSuffix(strings.TrimPrefix(file, prefix), ".tgz")
+		parsed, err := semver.StrictNewVersion(raw)
+		if err != nil {
+			// the prefix of another package, e.g. pulumi-aws-native
+			continue
+		}
+		switch {
+		case version == "latest":
+			if parsed.Prerelease() != "" {
+				continue
+			}
+		case constraint != nil:
+			if !constraint.Check(parsed) {
+				continue
+			}
+		default:
+			if raw != version {
+				continue
+			}
+		}
+		if best == nil || parsed.GreaterThan(best) {
+			best = parsed
+			bestFile = file
+		}
+	}
+	if bestFile == "" {
+		return "", fmt.Errorf("%s@%s not found in mirror %s", name, version, r.mirror)
+	}
+	return filepath.Join(r.mirror, bestFile), nil
+}
+
+func (r Registry) fromMirror(name string, version string) (*Package, error) {
+	tarball, err := r.findTarball(name, version)
+	if err != nil {
+		return nil, err
+	}
+	pkg, err := readTarballPackage(tarball)
+	if err != nil {
+		return nil, err
+	}
+	if pkg.Name != name {
+		return nil, fmt.Errorf("tarball %s contains %s, expected %s", tarball, pkg.Name, name)
+	}
+	data, err := os.ReadFile(tarball)
+	if err != nil {
+		return nil, err
+	}
+	pkg.Dist = &Dist{
+		Tarball:   "file:" + filepath.ToSlash(tarball),
+		Integrity: ComputeIntegrity(data),
+	}
+	return pkg, nil
+}
+
+func readTarballPackage(tarball string) (*Package, error) {
+	file, err := os.Open(tarball)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+	reader := tar.NewReader(gz)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("no package.json in %s", tarball)
+		}
+		if err != nil {
+			return nil, err
+		}
+		// npm tarballs have a single top level directory, usually `package/`
+		clean := path.Clean(header.Name)
+		if path.Base(clean) != "package.json" || strings.Count(clean, "/") != 1 {
+			continue
+		}
+		var pkg Package
+		if err := json.NewDecoder(reader).Decode(&pkg); err != nil {
+			return nil, err
+		}
+		return &pkg, nil
+	}
+}
@@ -2,18 +2,26 @@ package npm
 
 import (
 	"bufio"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/sst/sst/v3/internal/fs"
+	"github.com/sst/sst/v3/pkg/flag"
 )
 
 var envVarRegex = regexp.MustCompile(`\$\{([^}]+)\}`)
@@ -22,6 +30,16 @@ var authRegex = regexp.MustCompile(`^(//.+?):_auth\s*=\s*(.+)$`)
 var usernameRegex = regexp.MustCompile(`^(//.+?):username\s*=\s*(.+)$`)
 var passwordRegex = regexp.MustCompile(`^(//.+?):_password\s*=\s*(.+)$`)
 var registryRegex = regexp.MustCompile(`^registry\s*=\s*(.+)$`)
+var scopeRegistryRegex = regexp.MustCompile(`^(@[^:/\s]+):registry\s*=\s*(.+)$`)
+var cafileRegex = regexp.MustCompile(`^cafile\s*=\s*(.+)$`)
+var strictSSLRegex = regexp.MustCompile(`^strict-ssl\s*=\s*(.+)$`)
+var proxyRegex = regexp.MustCompile(`^proxy\s*=\s*(.+)$`)
+var httpsProxyRegex = regexp.MustCompile(`^https-proxy\s*=\s*(.+)$`)
+var noproxyRegex = regexp.MustCompile(`^no-?proxy\s*=\s*(.+)$`)
+var fetchRetriesRegex = regexp.MustCompile(`^fetch-retries\s*=\s*(\d+)$`)
+
+const defaultRegistry = "https://registry.npmjs.org"
+const defaultRetries = 2
 
 type Auth struct {
 	token  string
@@ -29,8 +47,15 @@ type Auth struct {
 }
 
 type npmrc struct {
-	registry string
-	auths    map[string]Auth
+	registry   string
+	scopes     map[string]string
+	auths      map[string]Auth
+	cafile     string
+	strictSSL  *bool
+	proxy      string
+	httpsProxy string
+	noproxy    []string
+	retries    *int
 }
 
 func expandEnvVars(s string) string {
@@ -40,14 +65,14 @@ func expandEnvVars(s string) string {
 }
 
 func parseNpmrc(content string) npmrc {
-	result := npmrc{auths: make(map[string]Auth)}
+	result := npmrc{auths: make(map[string]Auth), scopes: make(map[string]string)}
 	usernames := make(map[string]string)
 	passwords := make(map[string]string)
 
 	scanner := bufio.NewScanner(strings.NewReader(content))
 	for scanner.Scan() {
 		line := strings.TrimSpace(scanner.Text())
-		if line == "" || strings.HasPrefix(line, "#") {
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
 			continue
 		}
 		if matches := authTokenRegex.FindStringSubmatch(line); matches != nil {
@@ -74,6 +99,41 @@ func parseNpmrc(content string) npmrc {
 		}
 		if matches := registryRegex.FindStringSubmatch(line); matches != nil {
 			result.registry = strings.TrimSpace(matches[1])
+			continue
+		}
+		if matches := scopeRegistryRegex.FindStringSubmatch(line); matches != nil {
+			result.scopes[matches[1]] = strings.TrimSpace(expandEnvVars(matches[2]))
+			continue
+		}
+		if matches := cafileRegex.FindStringSubmatch(line); matches != nil {
+			result.cafile = strings.TrimSpace(expandEnvVars(matches[1]))
+			continue
+		}
+		if matches := strictSSLRegex.FindStringSubmatch(line); matches != nil {
+			strict := strings.TrimSpace(matches[1]) != "false"
+			result.strictSSL = &strict
+			continue
+		}
+		if matches := httpsProxyRegex.FindStringSubmatch(line); matches != nil {
+			result.httpsProxy = strings.TrimSpace(expandEnvVars(matches[1]))
+			continue
+		}
+		if matches := proxyRegex.FindStringSubmatch(line); matches != nil {
+			result.proxy = strings.TrimSpace(expandEnvVars(matches[1]))
+			continue
+		}
+		if matches := noproxyRegex.FindStringSubmatch(line); matches != nil {
+			for _, host := range strings.Split(expandEnvVars(matches[1]), ",") {
+				host = strings.TrimSpace(host)
+				if host != "" {
+					result.noproxy = append(result.noproxy, host)
+				}
+			}
+			continue
+		}
+		if matches := fetchRetriesRegex.FindStringSubmatch(line); matches != nil {
+			retries, _ := strconv.Atoi(matches[1])
+			result.retries = &retries
 		}
 	}
 
@@ -97,27 +157,58 @@ func parseNpmrc(content string) npmrc {
 	return result
 }
 
+// merge applies the settings from other on top of rc, the same way npm lets
+// a project .npmrc override the one in the home directory.
+func (rc *npmrc) merge(other npmrc) {
+	if other.registry != "" {
+		rc.registry = other.registry
+	}
+	for k, v := range other.scopes {
+		rc.scopes[k] = v
+	}
+	for k, v := range other.auths {
+		rc.auths[k] = v
+	}
+	if other.cafile != "" {
+		rc.cafile = other.cafile
+	}
+	if other.strictSSL != nil {
+		rc.strictSSL = other.strictSSL
+	}
+	if other.proxy != "" {
+		rc.proxy = other.proxy
+	}
+	if other.httpsProxy != "" {
+		rc.httpsProxy = other.httpsProxy
+	}
+	if len(other.noproxy) > 0 {
+		rc.noproxy = other.noproxy
+	}
+	if other.retries != nil {
+		rc.retries = other.retries
+	}
+}
+
 type Registry struct {
-	url  string
-	auth Auth
+	url    string
+	auth   Auth
+	config npmrc
+	mirror string
+	// offline resolves packages only from the mirror and the cache
+	offline bool
+	cache   string
+	client  *http.Client
 }
 
 func LoadRegistry() Registry {
-	var merged npmrc
-	merged.auths = make(map[string]Auth)
+	merged := npmrc{auths: make(map[string]Auth), scopes: make(map[string]string)}
 
 	merge := func(path string) {
 		data, err := os.ReadFile(path)
 		if err != nil {
 			return
 		}
-		rc := parseNpmrc(string(data))
-		if rc.registry != "" {
-			merged.registry = rc.registry
-		}
-		for k, v := range rc.auths {
-			merged.auths[k] = v
-		}
+		merged.merge(parseNpmrc(string(data)))
 	}
 
 	// Load from ~/.npmrc
@@ -136,12 +227,180 @@ func LoadRegistry() Registry {
 		registry = merged.registry
 	}
 	if registry == "" {
-		registry = "https://registry.npmjs.org"
+		registry = defaultRegistry
 	}
 
 	auth := findAuth(registry, merged.auths)
 
-	return Registry{url: registry, auth: auth}
+	result := Registry{
+		url:     registry,
+		auth:    auth,
+		config:  merged,
+		mirror:  flag.SST_NPM_MIRROR,
+		offline: flag.SST_NPM_OFFLINE,
+		cache:   cacheDir(),
+	}
+	result.client = newClient(merged)
+	return result
+}
+
+// resolve returns the registry and auth to use for a package, honoring
+// per-scope registries like `@myorg:registry=https://npm.myorg.com`.
+func (r Registry) resolve(name string) (string, Auth) {
+	if strings.HasPrefix(name, "@") {
+		scope := strings.SplitN(name, "/", 2)[0]
+		if match, ok := r.config.scopes[scope]; ok {
+			return match, findAuth(match, r.config.auths)
+		}
+	}
+	return r.url, r.auth
+}
+
+// URL returns the registry a package is fetched from.
+func (r Registry) URL(name string) string {
+	result, _ := r.resolve(name)
+	return result
+}
+
+func (r Registry) retries() int {
+	if r.config.retries != nil {
+		return *r.config.retries
+	}
+	return defaultRetries
+}
+
+func newClient(rc npmrc) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	tlsConfig := &tls.Config{}
+	if rc.strictSSL != nil && !*rc.strictSSL {
+		tlsConfig.InsecureSkipVerify = true
+	}
+	if rc.cafile != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		pem, err := os.ReadFile(rc.cafile)
+		if err != nil {
+			slog.Error("failed to read cafile", "path", rc.cafile, "err", err)
+		} else if !pool.AppendCertsFromPEM(pem) {
+			slog.Error("no certificates found in cafile", "path", rc.cafile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	transport.TLSClientConfig = tlsConfig
+	transport.Proxy = func(req *http.Request) (*url.URL, error) {
+		if matchesNoproxy(req.URL.Hostname(), rc.noproxy) {
+			return nil, nil
+		}
+		proxy := rc.proxy
+		if req.URL.Scheme == "https" && rc.httpsProxy != "" {
+			proxy = rc.httpsProxy
+		}
+		if proxy == "" {
+			return http.ProxyFromEnvironment(req)
+		}
+		return url.Parse(proxy)
+	}
+	return &http.Client{
+		Transport: transport,
+		Timeout:   time.Minute,
+	}
+}
+
+func matchesNoproxy(host string, noproxy []string) bool {
+	for _, entry := range noproxy {
+		if entry == "*" {
+			return true
+		}
+		entry = strings.TrimPrefix(entry, ".")
+		if h, _, err := net.SplitHostPort(entry); err == nil {
+			entry = h
+		}
+		if host == entry || strings.HasSuffix(host, "."+entry) {
+			return true
+		}
+	}
+	return false
+}
+
+// Env returns the environment variables that carry the proxy and certificate
+// settings over to package managers spawned by SST.
+func (r Registry) Env() []string {
+	result := []string{}
+	if r.config.cafile != "" {
+		result = append(result, "NODE_EXTRA_CA_CERTS="+r.config.cafile)
+	}
+	if r.config.strictSSL != nil && !*r.config.strictSSL {
+		result = append(result, "NODE_TLS_REJECT_UNAUTHORIZED=0")
+	}
+	if r.config.proxy != "" {
+		result = append(result, "HTTP_PROXY="+r.config.proxy)
+	}
+	if r.config.httpsProxy != "" {
+		result = append(result, "HTTPS_PROXY="+r.config.httpsProxy)
+	} else if r.config.proxy != "" {
+		result = append(result, "HTTPS_PROXY="+r.config.proxy)
+	}
+	if len(r.config.noproxy) > 0 {
+		result = append(result, "NO_PROXY="+strings.Join(r.config.noproxy, ","))
+	}
+	// the .npmrc written by WriteNpmrc points at these, so the credentials
+	// are never written to the project
+	for i, key := range r.authKeys() {
+		result = append(result, authVar(i)+"="+r.config.auths[key].token)
+	}
+	return result
+}
+
+func (r Registry) authKeys() []string {
+	keys := make([]string, 0, len(r.config.auths))
+	for key := range r.config.auths {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// authVar is the environment variable that holds the credentials of the
+// registry at index i of authKeys.
+func authVar(i int) string {
+	return fmt.Sprintf("SST_NPM_AUTH_%d", i)
+}
+
+// WriteNpmrc writes the registries that were loaded into dir so package
+// managers running there resolve packages from the same ones. The credentials
+// are written as references to the variables returned by Env, which both bun
+// and npm expand.
+func (r Registry) WriteNpmrc(dir string) error {
+	target := filepath.Join(dir, ".npmrc")
+	lines := []string{}
+	if r.url != defaultRegistry {
+		lines = append(lines, "registry="+r.url)
+	}
+	scopes := make([]string, 0, len(r.config.scopes))
+	for scope := range r.config.scopes {
+		scopes = append(scopes, scope)
+	}
+	sort.Strings(scopes)
+	for _, scope := range scopes {
+		lines = append(lines, scope+":registry="+r.config.scopes[scope])
+	}
+	for i, key := range r.authKeys() {
+		name := ":_authToken="
+		if r.config.auths[key].scheme == "Basic" {
+			name = ":_auth="
+		}
+		lines = append(lines, key+name+"${"+authVar(i)+"}")
+	}
+	if len(lines) == 0 {
+		err := os.Remove(target)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	return os.WriteFile(target, []byte(strings.Join(lines, "\n")+"\n"), 0644)
 }
 
 // findAuth walks up the registry URL path to find the longest matching
@@ -185,6 +444,7 @@ func findAuth(registryURL string, auths map[string]Auth) Auth {
 type Package struct {
 	Name    string `json:"name"`
 	Version string `json:"version"`
+	Dist    *Dist  `json:"dist,omitempty"`
 	Pulumi  *struct {
 		Name             string `json:"name"`
 		Version          string `json:"version"`
@@ -194,33 +454,119 @@ type Package struct {
 	}
 }
 
+type Dist struct {
+	Tarball   string `json:"tarball"`
+	Integrity string `json:"integrity"`
+	Shasum    string `json:"shasum"`
+}
+
+// ErrNotFound is returned when the registry does not have the package or the
+// version, or when it's not in the mirror while offline.
+var ErrNotFound = fmt.Errorf("package not found")
+
+// statusError is a response from the registry that isn't a success.
+type statusError struct {
+	code   int
+	status string
+}
+
+func (err *statusError) Error() string {
+	return "failed to fetch package: " + err.status
+}
+
+// Unavailable returns if an error means the registry could not be reached,
+// as opposed to it answering that the package can't be fetched.
+func Unavailable(err error) bool {
+	if errors.Is(err, ErrNotFound) {
+		return false
+	}
+	var status *statusError
+	if errors.As(err, &status) {
+		return status.code == http.StatusTooManyRequests || status.code >= 500
+	}
+	return true
+}
+
 func Get(registry Registry, name string, version string) (*Package, error) {
 	slog.Info("getting package", "name", name, "version", version)
-	pkgURL := fmt.Sprintf("%s/%s/%s", registry.url, name, version)
-
-	req, err := http.NewRequest("GET", pkgURL, nil)
-	if err != nil {
-		return nil, err
+	if registry.mirror != "" {
+		pkg, err := registry.fromMirror(name, version)
+		if err == nil {
+			return pkg, nil
+		}
+		slog.Info("package not in mirror", "name", name, "version", version, "err", err)
 	}
 
-	if registry.auth.token != "" {
-		req.Header.Set("Authorization", registry.auth.scheme+" "+registry.auth.token)
+	registryURL, auth := registry.resolve(name)
+	pkgURL := fmt.Sprintf("%s/%s/%s", strings.TrimSuffix(registryURL, "/"), name, version)
+
+	if cached, fresh := registry.readCache(pkgURL, isExact(version)); cached != nil && (fresh || registry.offline) {
+		slog.Info("using cached package metadata", "url", pkgURL)
+		return cached, nil
+	}
+	if registry.offline {
+		return nil, fmt.Errorf("%w: %s@%s is not in the mirror", ErrNotFound, name, version)
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	data, err := registry.fetch(pkgURL, auth)
 	if err != nil {
+		// fall back to stale metadata when the registry can't be reached, but
+		// not when it says the package doesn't exist
+		if cached, _ := registry.readCache(pkgURL, true); cached != nil && Unavailable(err) {
+			slog.Info("registry unavailable, using stale metadata", "url", pkgURL, "err", err)
+			return cached, nil
+		}
 		return nil, err
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to fetch package: %s", resp.Status)
+	registry.writeCache(pkgURL, data)
+	return data, nil
+}
+
+func (r Registry) fetch(pkgURL string, auth Auth) (*Package, error) {
+	client := r.client
+	if client == nil {
+		client = http.DefaultClient
 	}
-	var data Package
-	err = json.NewDecoder(resp.Body).Decode(&data)
-	if err != nil {
-		return nil, err
+	var lastErr error
+	for attempt := 0; attempt <= r.retries(); attempt++ {
+		if attempt > 0 {
+			time.Sleep(time.Duration(attempt) * time.Second)
+			slog.Info("retrying package fetch", "url", pkgURL, "attempt", attempt)
+		}
+		req, err := http.NewRequest("GET", pkgURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		if auth.token != "" {
+			req.Header.Set("Authorization", auth.scheme+" "+auth.token)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+			resp.Body.Close()
+			lastErr = &statusError{code: resp.StatusCode, status: resp.Status}
+			continue
+		}
+		if resp.StatusCode == http.StatusNotFound {
+			resp.Body.Close()
+			return nil, fmt.Errorf("%w: %s", ErrNotFound, pkgURL)
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, &statusError{code: resp.StatusCode, status: resp.Status}
+		}
+		var data Package
+		err = json.NewDecoder(resp.Body).Decode(&data)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		return &data, nil
 	}
-	return &data, nil
+	return nil, lastErr
 }
 
 func DetectPackageManager(dir string) (string, string) {
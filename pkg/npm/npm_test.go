@@ -1,9 +1,18 @@
 package npm
 
 import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
 	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strings"
+	"sync/atomic"
 	"testing"
 )
 
@@ -267,3 +276,297 @@ func TestLoadRegistryNpmRegistryEnvVar(t *testing.T) {
 		t.Errorf("env auth token = %q, want env-tok", reg.auth.token)
 	}
 }
+
+func TestParseNpmrcScopesAndNetwork(t *testing.T) {
+	t.Setenv("CORP_PROXY", "http://proxy.corp:3128")
+	input := `@myorg:registry=https://npm.myorg.com/
+@other:registry = https://other.registry.io
+cafile=/etc/ssl/corp.pem
+strict-ssl=false
+proxy=${CORP_PROXY}
+https-proxy=http://secure.proxy:3128
+noproxy=localhost, .internal.corp
+fetch-retries=5
+`
+	rc := parseNpmrc(input)
+
+	if rc.scopes["@myorg"] != "https://npm.myorg.com/" {
+		t.Errorf("scope @myorg = %q", rc.scopes["@myorg"])
+	}
+	if rc.scopes["@other"] != "https://other.registry.io" {
+		t.Errorf("scope @other = %q", rc.scopes["@other"])
+	}
+	if rc.registry != "" {
+		t.Errorf("scoped registry should not set default registry, got %q", rc.registry)
+	}
+	if rc.cafile != "/etc/ssl/corp.pem" {
+		t.Errorf("cafile = %q", rc.cafile)
+	}
+	if rc.strictSSL == nil || *rc.strictSSL {
+		t.Errorf("strict-ssl should be false")
+	}
+	if rc.proxy != "http://proxy.corp:3128" {
+		t.Errorf("proxy = %q", rc.proxy)
+	}
+	if rc.httpsProxy != "http://secure.proxy:3128" {
+		t.Errorf("https-proxy = %q", rc.httpsProxy)
+	}
+	if len(rc.noproxy) != 2 || rc.noproxy[1] != ".internal.corp" {
+		t.Errorf("noproxy = %v", rc.noproxy)
+	}
+	if rc.retries == nil || *rc.retries != 5 {
+		t.Errorf("fetch-retries = %v", rc.retries)
+	}
+}
+
+func TestResolveScopedRegistry(t *testing.T) {
+	rc := parseNpmrc(`registry=https://default.registry.io
+@myorg:registry=https://npm.myorg.com/
+//npm.myorg.com/:_authToken=scoped-token
+//default.registry.io/:_authToken=default-token
+`)
+	reg := Registry{url: rc.registry, auth: findAuth(rc.registry, rc.auths), config: rc}
+
+	url, auth := reg.resolve("@myorg/pulumi-thing")
+	if url != "https://npm.myorg.com/" || auth.token != "scoped-token" {
+		t.Errorf("scoped resolve = %q %+v", url, auth)
+	}
+	url, auth = reg.resolve("@pulumi/aws")
+	if url != "https://default.registry.io" || auth.token != "default-token" {
+		t.Errorf("unscoped resolve = %q %+v", url, auth)
+	}
+}
+
+func TestMatchesNoproxy(t *testing.T) {
+	noproxy := []string{"localhost", ".internal.corp", "registry.local:4873"}
+	cases := map[string]bool{
+		"localhost":              true,
+		"npm.internal.corp":      true,
+		"internal.corp":          true,
+		"registry.local":         true,
+		"registry.npmjs.org":     false,
+		"notinternal.corp.co.uk": false,
+	}
+	for host, want := range cases {
+		if got := matchesNoproxy(host, noproxy); got != want {
+			t.Errorf("matchesNoproxy(%q) = %v, want %v", host, got, want)
+		}
+	}
+	if !matchesNoproxy("anything", []string{"*"}) {
+		t.Error("wildcard should match everything")
+	}
+}
+
+func writeTarball(t *testing.T, dir string, file string, pkgJson string) {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	tw.WriteHeader(&tar.Header{Name: "package/package.json", Mode: 0644, Size: int64(len(pkgJson))})
+	tw.Write([]byte(pkgJson))
+	tw.Close()
+	gz.Close()
+	if err := os.WriteFile(filepath.Join(dir, file), buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMirror(t *testing.T) {
+	dir := t.TempDir()
+	writeTarball(t, dir, "pulumi-aws-6.66.2.tgz", `{"name":"@pulumi/aws","version":"6.66.2","pulumi":{"name":"aws"}}`)
+	writeTarball(t, dir, "pulumi-aws-6.70.0.tgz", `{"name":"@pulumi/aws","version":"6.70.0","pulumi":{"name":"aws"}}`)
+	writeTarball(t, dir, "pulumi-aws-7.0.0-alpha.1.tgz", `{"name":"@pulumi/aws","version":"7.0.0-alpha.1","pulumi":{"name":"aws"}}`)
+	writeTarball(t, dir, "pulumi-aws-native-1.0.0.tgz", `{"name":"@pulumi/aws-native","version":"1.0.0"}`)
+	reg := Registry{mirror: dir}
+
+	pkg, err := reg.fromMirror("@pulumi/aws", "latest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pkg.Version != "6.70.0" {
+		t.Errorf("latest = %q, want 6.70.0", pkg.Version)
+	}
+	if pkg.Pulumi == nil || pkg.Pulumi.Name != "aws" {
+		t.Errorf("pulumi metadata not read: %+v", pkg.Pulumi)
+	}
+	if pkg.Dist == nil || !strings.HasPrefix(pkg.Dist.Tarball, "file:") {
+		t.Errorf("tarball = %+v", pkg.Dist)
+	}
+
+	pkg, err = reg.fromMirror("@pulumi/aws", "6.66.2")
+	if err != nil || pkg.Version != "6.66.2" {
+		t.Errorf("exact = %+v, %v", pkg, err)
+	}
+
+	if _, err := reg.fromMirror("@pulumi/aws", "5.0.0"); err == nil {
+		t.Error("expected missing version to fail")
+	}
+	if _, ok := reg.Mirrored("@pulumi/gcp", "latest"); ok {
+		t.Error("expected missing package to not be mirrored")
+	}
+}
+
+func TestCache(t *testing.T) {
+	reg := Registry{cache: t.TempDir()}
+	url := "https://registry.npmjs.org/sst/latest"
+	if cached, _ := reg.readCache(url, false); cached != nil {
+		t.Fatal("expected empty cache")
+	}
+	reg.writeCache(url, &Package{Name: "sst", Version: "3.0.0"})
+	cached, fresh := reg.readCache(url, false)
+	if cached == nil || cached.Version != "3.0.0" || !fresh {
+		t.Errorf("cached = %+v, fresh = %v", cached, fresh)
+	}
+}
+
+func TestVerifyIntegrity(t *testing.T) {
+	data := []byte("provider tarball")
+	integrity := ComputeIntegrity(data)
+	if err := VerifyIntegrity(data, integrity); err != nil {
+		t.Errorf("expected computed integrity to verify: %v", err)
+	}
+	if err := VerifyIntegrity([]byte("tampered"), integrity); err == nil {
+		t.Error("expected tampered data to fail")
+	}
+	if err := VerifyIntegrity(data, "sha1-bogus "+integrity); err != nil {
+		t.Errorf("expected any matching hash to verify: %v", err)
+	}
+	if err := VerifyIntegrity(data, "0ab2e1c0c8b14b0bbd1d1fd5fc3ea7f6fcd1f1a1"); err == nil {
+		t.Error("expected wrong shasum to fail")
+	}
+	if err := VerifyIntegrity(data, ""); err == nil {
+		t.Error("expected empty integrity to fail")
+	}
+}
+
+func TestDownloadVerifiesLocalTarball(t *testing.T) {
+	dir := t.TempDir()
+	writeTarball(t, dir, "pulumi-aws-6.66.2.tgz", `{"name":"@pulumi/aws","version":"6.66.2"}`)
+	file := filepath.Join(dir, "pulumi-aws-6.66.2.tgz")
+	data, _ := os.ReadFile(file)
+
+	got, err := Download(Registry{}, "@pulumi/aws", "file:"+filepath.ToSlash(file), ComputeIntegrity(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != file {
+		t.Errorf("path = %q, want %q", got, file)
+	}
+	if _, err := Download(Registry{}, "@pulumi/aws", "file:"+filepath.ToSlash(file), ComputeIntegrity([]byte("other"))); err == nil {
+		t.Error("expected mismatched integrity to fail")
+	}
+}
+
+func TestWriteNpmrcOmitsCredentials(t *testing.T) {
+	rc := parseNpmrc(`registry=https://npm.corp.com/
+@myorg:registry=https://npm.myorg.com/
+//npm.corp.com/:_authToken=corp-token
+//npm.myorg.com/:_password=` + base64.StdEncoding.EncodeToString([]byte("secret")) + `
+//npm.myorg.com/:username=frank
+`)
+	reg := Registry{url: rc.registry, config: rc}
+	dir := t.TempDir()
+	if err := reg.WriteNpmrc(dir); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, ".npmrc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "registry=https://npm.corp.com/\n@myorg:registry=https://npm.myorg.com/\n" +
+		"//npm.corp.com/:_authToken=${SST_NPM_AUTH_0}\n//npm.myorg.com/:_auth=${SST_NPM_AUTH_1}\n"
+	if string(data) != want {
+		t.Errorf(".npmrc = %q, want %q", data, want)
+	}
+
+	env := strings.Join(reg.Env(), "\n")
+	if !strings.Contains(env, "SST_NPM_AUTH_0=corp-token") {
+		t.Errorf("token not passed in env: %s", env)
+	}
+	if !strings.Contains(env, "SST_NPM_AUTH_1="+base64.StdEncoding.EncodeToString([]byte("frank:secret"))) {
+		t.Errorf("basic auth not passed in env: %s", env)
+	}
+
+	if err := (Registry{url: defaultRegistry, config: parseNpmrc("")}).WriteNpmrc(dir); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, ".npmrc")); !os.IsNotExist(err) {
+		t.Error("expected .npmrc to be removed for the default registry")
+	}
+}
+
+func TestWriteNpmrcAuthWithNpm(t *testing.T) {
+	npmPath, err := exec.LookPath("npm")
+	if err != nil {
+		t.Skip("npm is not installed")
+	}
+	var authorization atomic.Value
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization.Store(r.Header.Get("Authorization"))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"name":"private","dist-tags":{"latest":"1.0.0"},"versions":{"1.0.0":{"name":"private","version":"1.0.0"}}}`))
+	}))
+	defer server.Close()
+
+	host := strings.TrimPrefix(server.URL, "http:")
+	rc := parseNpmrc("registry=" + server.URL + "/\n" + host + "/:_authToken=corp-token\n")
+	reg := Registry{url: rc.registry, config: rc}
+	dir := t.TempDir()
+	if err := reg.WriteNpmrc(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command(npmPath, "view", "private", "version", "--cache", filepath.Join(dir, "cache"))
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "HOME="+dir, "NPM_CONFIG_USERCONFIG="+filepath.Join(dir, "empty"))
+	cmd.Env = append(cmd.Env, reg.Env()...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("npm view failed: %v\n%s", err, output)
+	}
+	if got, _ := authorization.Load().(string); got != "Bearer corp-token" {
+		t.Errorf("Authorization = %q, want %q", got, "Bearer corp-token")
+	}
+}
+
+func TestGetOffline(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Write([]byte(`{"name":"@pulumi/gcp","version":"8.0.0"}`))
+	}))
+	defer server.Close()
+
+	reg := Registry{url: server.URL, mirror: t.TempDir(), offline: true, cache: t.TempDir()}
+	if _, err := Get(reg, "@pulumi/gcp", "8.0.0"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected a mirror miss to fail offline, got %v", err)
+	}
+	if hits.Load() != 0 {
+		t.Errorf("the registry was called %d times while offline", hits.Load())
+	}
+}
+
+func TestGetStaleCache(t *testing.T) {
+	status := http.StatusNotFound
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	retries := 0
+	reg := Registry{url: server.URL, cache: t.TempDir(), config: npmrc{retries: &retries}}
+	// a stale entry, fetched long ago
+	stale := `{"fetched":"2000-01-01T00:00:00Z","package":{"name":"sst","version":"3.0.0"}}`
+	if err := os.WriteFile(reg.cachePath(server.URL+"/sst/latest"), []byte(stale), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Get(reg, "sst", "latest"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected a 404 to not use the stale cache, got %v", err)
+	}
+	status = http.StatusBadGateway
+	pkg, err := Get(reg, "sst", "latest")
+	if err != nil || pkg.Version != "3.0.0" {
+		t.Errorf("expected a 502 to use the stale cache, got %+v %v", pkg, err)
+	}
+}
@@ -0,0 +1,140 @@
+package npm
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ComputeIntegrity returns the subresource integrity string npm uses for a
+// tarball.
+func ComputeIntegrity(data []byte) string {
+	sum := sha512.Sum512(data)
+	return "sha512-" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// VerifyIntegrity checks data against an integrity string. Like npm, the
+// integrity can list multiple space separated hashes and any of them
+// matching is enough. A bare hex string is treated as a legacy sha1 shasum.
+func VerifyIntegrity(data []byte, integrity string) error {
+	integrity = strings.TrimSpace(integrity)
+	if integrity == "" {
+		return fmt.Errorf("no integrity to verify against")
+	}
+	if !strings.Contains(integrity, "-") {
+		sum := sha1.Sum(data)
+		if hex.EncodeToString(sum[:]) == strings.ToLower(integrity) {
+			return nil
+		}
+		return fmt.Errorf("shasum mismatch: expected %s", integrity)
+	}
+	for _, entry := range strings.Fields(integrity) {
+		algorithm, expected, ok := strings.Cut(entry, "-")
+		if !ok {
+			continue
+		}
+		var h hash.Hash
+		switch algorithm {
+		case "sha512":
+			h = sha512.New()
+		case "sha384":
+			h = sha512.New384()
+		case "sha256":
+			h = sha256.New()
+		case "sha1":
+			h = sha1.New()
+		default:
+			continue
+		}
+		h.Write(data)
+		if base64.StdEncoding.EncodeToString(h.Sum(nil)) == expected {
+			return nil
+		}
+	}
+	return fmt.Errorf("integrity mismatch: expected %s", integrity)
+}
+
+func (r Registry) tarballCachePath(integrity string) string {
+	sum := sha256.Sum256([]byte(integrity))
+	return filepath.Join(r.cache, "tarballs", hex.EncodeToString(sum[:])+".tgz")
+}
+
+// Download fetches the tarball of a package, verifies it against the expected
+// integrity and returns the path to the verified copy. Tarballs are cached by
+// integrity so the same version is only downloaded once.
+func Download(registry Registry, name string, tarball string, integrity string) (string, error) {
+	if local, ok := strings.CutPrefix(tarball, "file:"); ok {
+		data, err := os.ReadFile(filepath.FromSlash(local))
+		if err != nil {
+			return "", err
+		}
+		if err := VerifyIntegrity(data, integrity); err != nil {
+			return "", fmt.Errorf("%s: %w", name, err)
+		}
+		return filepath.FromSlash(local), nil
+	}
+
+	if registry.cache == "" {
+		return "", fmt.Errorf("no cache directory available to store %s", name)
+	}
+	cached := registry.tarballCachePath(integrity)
+	if data, err := os.ReadFile(cached); err == nil {
+		if VerifyIntegrity(data, integrity) == nil {
+			return cached, nil
+		}
+		slog.Info("cached tarball failed verification, downloading again", "name", name)
+	}
+
+	slog.Info("downloading tarball", "name", name, "url", tarball)
+	auth := findAuth(tarball, registry.config.auths)
+	if auth.token == "" {
+		_, auth = registry.resolve(name)
+	}
+	req, err := http.NewRequest("GET", tarball, nil)
+	if err != nil {
+		return "", err
+	}
+	if auth.token != "" {
+		req.Header.Set("Authorization", auth.scheme+" "+auth.token)
+	}
+	client := registry.client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to download %s: %s", name, resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if err := VerifyIntegrity(data, integrity); err != nil {
+		return "", fmt.Errorf("%s: %w", name, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(cached), 0755); err != nil {
+		return "", err
+	}
+	tmp := cached + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp, cached); err != nil {
+		return "", err
+	}
+	return cached, nil
+}
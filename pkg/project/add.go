@@ -10,6 +10,15 @@ import (
 )
 
 func (p *Project) Add(provider string, version string, pkg string) error {
+	return p.editProviders(provider, version, pkg, "add")
+}
+
+// Upgrade sets the version of a provider that is already in the config.
+func (p *Project) Upgrade(provider string, version string) error {
+	return p.editProviders(provider, version, "", "upgrade")
+}
+
+func (p *Project) editProviders(provider string, version string, pkg string, mode string) error {
 	var stderr bytes.Buffer
 	cmd := process.Command("node",
 		"--no-warnings",
@@ -18,6 +27,7 @@ func (p *Project) Add(provider string, version string, pkg string) error {
 		provider,
 		version,
 		pkg,
+		mode,
 	)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = &stderr
@@ -0,0 +1,211 @@
+package project
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/events"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/sst/sst/v3/internal/util"
+	"github.com/sst/sst/v3/pkg/process"
+	"golang.org/x/exp/slices"
+)
+
+// ApprovalPolicy lists the changes that need to be approved before they are
+// deployed. It's set per stage in the config.
+type ApprovalPolicy struct {
+	StatefulDeletes      bool `json:"statefulDeletes"`
+	DatabaseReplacements bool `json:"databaseReplacements"`
+	MaxChanges           int  `json:"maxChanges"`
+}
+
+func (a *ApprovalPolicy) enabled() bool {
+	return a != nil && (a.StatefulDeletes || a.DatabaseReplacements || a.MaxChanges > 0)
+}
+
+var databaseTypes = []string{
+	"aws:rds/cluster:Cluster",
+	"aws:rds/instance:Instance",
+	"aws:docdb/cluster:Cluster",
+	"aws:neptune/cluster:Cluster",
+	"aws:dynamodb/table:Table",
+	"aws:dsql/cluster:Cluster",
+	"aws:elasticache/replicationGroup:ReplicationGroup",
+	"aws:elasticache/serverlessCache:ServerlessCache",
+	"aws:opensearch/domain:Domain",
+	"aws:redshift/cluster:Cluster",
+	"cloudflare:index/d1Database:D1Database",
+}
+
+var statefulTypes = append([]string{
+	"aws:s3/bucket:Bucket",
+	"aws:s3/bucketV2:BucketV2",
+	"aws:efs/fileSystem:FileSystem",
+	"aws:sqs/queue:Queue",
+	"aws:kinesis/stream:Stream",
+	"aws:cognito/userPool:UserPool",
+	"aws:secretsmanager/secret:Secret",
+	"aws:s3vectors/vectorBucket:VectorBucket",
+	"cloudflare:index/r2Bucket:R2Bucket",
+	"cloudflare:index/workersKvNamespace:WorkersKvNamespace",
+}, databaseTypes...)
+
+// ChangeViolation is a change that breaks a rule of the approval policy.
+type ChangeViolation struct {
+	Rule    string `json:"rule"`
+	URN     string `json:"urn,omitempty"`
+	Message string `json:"message"`
+}
+
+// ChangePolicyEvent is published when the changes of a deploy or a diff need
+// to be approved.
+type ChangePolicyEvent struct {
+	Violations []ChangeViolation
+	Token      string
+	Approved   bool
+}
+
+// ErrChangeNotApproved is returned when a deploy needs to be approved and
+// the token it was run with doesn't match the changes.
+type ErrChangeNotApproved struct {
+	Violations []ChangeViolation
+	Token      string
+}
+
+func (err *ErrChangeNotApproved) Error() string {
+	return "changes were not approved"
+}
+
+func isReplacement(op apitype.OpType) bool {
+	return op == apitype.OpReplace || op == apitype.OpCreateReplacement || op == apitype.OpDeleteReplaced
+}
+
+// checkChanges returns the changes that break the policy, and a token that
+// identifies the set of changes so they can be approved.
+func checkChanges(policy *ApprovalPolicy, stage string, steps []apitype.StepEventMetadata) ([]ChangeViolation, string) {
+	changes := map[string]apitype.StepEventMetadata{}
+	for _, step := range steps {
+		state := step.New
+		if state == nil {
+			state = step.Old
+		}
+		switch step.Op {
+		case apitype.OpSame, apitype.OpRead, apitype.OpRefresh, apitype.OpReadDiscard, apitype.OpDiscardReplaced:
+			continue
+		}
+		if state != nil && !state.Custom {
+			continue
+		}
+		// a replacement is reported as one step per phase
+		if existing, ok := changes[step.URN]; ok && isReplacement(existing.Op) {
+			continue
+		}
+		changes[step.URN] = step
+	}
+
+	urns := make([]string, 0, len(changes))
+	for urn := range changes {
+		urns = append(urns, urn)
+	}
+	sort.Strings(urns)
+
+	violations := []ChangeViolation{}
+	hash := sha256.New()
+	hash.Write([]byte(stage))
+	for _, urn := range urns {
+		step := changes[urn]
+		op := step.Op
+		if isReplacement(op) {
+			op = apitype.OpReplace
+		}
+		fmt.Fprintf(hash, "\n%s %s", op, urn)
+
+		name := resource.URN(urn).Name()
+		retained := step.Old != nil && step.Old.RetainOnDelete
+		if policy.StatefulDeletes && !retained && (op == apitype.OpDelete || op == apitype.OpReplace) && slices.Contains(statefulTypes, step.Type) {
+			violations = append(violations, ChangeViolation{
+				Rule:    "statefulDeletes",
+				URN:     urn,
+				Message: fmt.Sprintf("%s (%s) would be %s", name, step.Type, pastTense(op)),
+			})
+			continue
+		}
+		if policy.DatabaseReplacements && op == apitype.OpReplace && slices.Contains(databaseTypes, step.Type) {
+			violations = append(violations, ChangeViolation{
+				Rule:    "databaseReplacements",
+				URN:     urn,
+				Message: fmt.Sprintf("%s (%s) would be replaced", name, step.Type),
+			})
+		}
+	}
+	if policy.MaxChanges > 0 && len(urns) > policy.MaxChanges {
+		violations = append(violations, ChangeViolation{
+			Rule:    "maxChanges",
+			Message: fmt.Sprintf("%d resources would change, more than the %d allowed", len(urns), policy.MaxChanges),
+		})
+	}
+	return violations, hex.EncodeToString(hash.Sum(nil))[:12]
+}
+
+func pastTense(op apitype.OpType) string {
+	if op == apitype.OpReplace {
+		return "replaced"
+	}
+	return "deleted"
+}
+
+// previewChanges runs a preview with the same arguments as the deploy and
+// returns the steps it plans.
+func previewChanges(ctx context.Context, pulumiPath string, args []string, env []string, dir string, eventlogPath string) ([]apitype.StepEventMetadata, error) {
+	cmd := process.Command(pulumiPath, append([]string{"preview"}, args...)...)
+	cmd.Env = env
+	cmd.Dir = dir
+	slog.Info("previewing changes", "args", cmd.Args)
+	output, err := cmd.CombinedOutput()
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if err != nil {
+		slog.Error("preview failed", "err", err, "output", string(output))
+		return nil, util.NewReadableError(err, "Could not preview the changes to check them against the approval policy. Run `sst diff` to see the error.").WithCode("approval_preview_failed")
+	}
+
+	file, err := os.Open(eventlogPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	steps := []apitype.StepEventMetadata{}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 1024*1024), 64*1024*1024)
+	for scanner.Scan() {
+		var event events.EngineEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			continue
+		}
+		if event.ResourcePreEvent != nil {
+			steps = append(steps, event.ResourcePreEvent.Metadata)
+		}
+	}
+	return steps, scanner.Err()
+}
+
+// previewArgs turns the arguments of `pulumi up`, without the command, into
+// the ones for a preview that writes its events to another log.
+func previewArgs(args []string, eventlogPath string) []string {
+	result := append([]string{}, args...)
+	for i := range result {
+		if result[i] == "--event-log" && i+1 < len(result) {
+			result[i+1] = eventlogPath
+		}
+	}
+	return result
+}
@@ -0,0 +1,73 @@
+package project
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+)
+
+func changeStep(op apitype.OpType, kind string, name string, retain bool) apitype.StepEventMetadata {
+	urn := "urn:pulumi:production::app::" + kind + "::" + name
+	return apitype.StepEventMetadata{
+		Op:   op,
+		URN:  urn,
+		Type: kind,
+		Old:  &apitype.StepEventStateMetadata{Custom: true, RetainOnDelete: retain},
+		New:  &apitype.StepEventStateMetadata{Custom: true},
+	}
+}
+
+func rules(violations []ChangeViolation) []string {
+	result := []string{}
+	for _, item := range violations {
+		result = append(result, item.Rule)
+	}
+	return result
+}
+
+func TestCheckChanges(t *testing.T) {
+	steps := []apitype.StepEventMetadata{
+		changeStep(apitype.OpSame, "aws:s3/bucketV2:BucketV2", "Same", false),
+		changeStep(apitype.OpDelete, "aws:s3/bucketV2:BucketV2", "Deleted", false),
+		changeStep(apitype.OpDelete, "aws:dynamodb/table:Table", "Retained", true),
+		changeStep(apitype.OpCreateReplacement, "aws:rds/cluster:Cluster", "Database", false),
+		changeStep(apitype.OpReplace, "aws:rds/cluster:Cluster", "Database", false),
+		changeStep(apitype.OpDeleteReplaced, "aws:rds/cluster:Cluster", "Database", false),
+		changeStep(apitype.OpUpdate, "aws:lambda/function:Function", "Function", false),
+	}
+
+	violations, token := checkChanges(&ApprovalPolicy{StatefulDeletes: true}, "production", steps)
+	if !reflect.DeepEqual(rules(violations), []string{"statefulDeletes", "statefulDeletes"}) {
+		t.Fatalf("got %v", violations)
+	}
+	if violations[0].Message != "Database (aws:rds/cluster:Cluster) would be replaced" {
+		t.Fatalf("got %s", violations[0].Message)
+	}
+
+	violations, _ = checkChanges(&ApprovalPolicy{DatabaseReplacements: true, MaxChanges: 3}, "production", steps)
+	if !reflect.DeepEqual(rules(violations), []string{"databaseReplacements", "maxChanges"}) {
+		t.Fatalf("got %v", violations)
+	}
+
+	_, other := checkChanges(&ApprovalPolicy{MaxChanges: 1}, "production", steps)
+	if other != token {
+		t.Fatal("the token should only depend on the changes")
+	}
+	_, changed := checkChanges(&ApprovalPolicy{StatefulDeletes: true}, "production", steps[:3])
+	if changed == token {
+		t.Fatal("the token should change with the changes")
+	}
+	_, staged := checkChanges(&ApprovalPolicy{StatefulDeletes: true}, "staging", steps)
+	if staged == token {
+		t.Fatal("the token should change with the stage")
+	}
+}
+
+func TestPreviewArgs(t *testing.T) {
+	args := []string{"--stack", "organization/app/dev", "--event-log", "/tmp/eventlog.json", "--target", "urn"}
+	got := previewArgs(args, "/tmp/preview.json")
+	if got[3] != "/tmp/preview.json" || args[3] != "/tmp/eventlog.json" {
+		t.Fatalf("got %v", got)
+	}
+}
@@ -0,0 +1,139 @@
+package project
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/sst/sst/v3/internal/util"
+)
+
+// CommonError is an error with a known fix. The errors of a deploy and of the
+// CLI are matched against these to show a hint and where to learn more.
+type CommonError struct {
+	Code string `json:"code"`
+	// Message is an example of the error. It's matched as is when there's no
+	// Pattern.
+	Message string `json:"message"`
+	// Pattern is a regular expression that's matched against the error.
+	Pattern string `json:"pattern,omitempty"`
+	// Short is shown with the error and Long is the documentation.
+	Short []string `json:"short"`
+	Long  []string `json:"long"`
+	Link  string   `json:"link,omitempty"`
+
+	compiled *regexp.Regexp
+}
+
+// CommonErrorsFile is where a project adds its own entries to the catalog,
+// next to the sst.config.ts.
+const CommonErrorsFile = "sst.errors.json"
+
+//go:embed commonerrors.json
+var commonErrorsJson []byte
+
+// CommonErrors are the built-in entries of the catalog, these are also used to
+// generate the docs.
+var CommonErrors = func() []CommonError {
+	catalog, err := parseCommonErrors(commonErrorsJson)
+	if err != nil {
+		panic(fmt.Errorf("invalid commonerrors.json: %w", err))
+	}
+	return catalog
+}()
+
+func parseCommonErrors(data []byte) ([]CommonError, error) {
+	var catalog []CommonError
+	if err := json.Unmarshal(data, &catalog); err != nil {
+		return nil, err
+	}
+	for i := range catalog {
+		item := &catalog[i]
+		if item.Code == "" {
+			return nil, fmt.Errorf("entry %d is missing a code", i)
+		}
+		pattern := item.Pattern
+		if pattern == "" {
+			if item.Message == "" {
+				return nil, fmt.Errorf("%s needs a pattern or a message", item.Code)
+			}
+			pattern = regexp.QuoteMeta(item.Message)
+		}
+		compiled, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", item.Code, err)
+		}
+		item.compiled = compiled
+	}
+	return catalog, nil
+}
+
+// LoadCommonErrors returns the entries in the sst.errors.json of the project
+// followed by the built-in ones.
+func LoadCommonErrors(root string) ([]CommonError, error) {
+	path := filepath.Join(root, CommonErrorsFile)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return CommonErrors, nil
+		}
+		return nil, err
+	}
+	catalog, err := parseCommonErrors(data)
+	if err != nil {
+		return nil, util.NewReadableError(err, fmt.Sprintf("Invalid %s: %s", CommonErrorsFile, err.Error())).WithCode("common_errors_invalid")
+	}
+	return append(catalog, CommonErrors...), nil
+}
+
+func (e CommonError) Matches(message string) bool {
+	return e.compiled != nil && e.compiled.MatchString(message)
+}
+
+// Help is the hint that's shown with a matching error.
+func (e CommonError) Help() []string {
+	help := append([]string{}, e.Short...)
+	if e.Link != "" {
+		help = append(help, "Learn more about this "+e.Link)
+	}
+	return help
+}
+
+// MatchCommonErrors returns the entries in the catalog that match the error
+// message.
+func MatchCommonErrors(catalog []CommonError, message string) []CommonError {
+	result := []CommonError{}
+	for _, item := range catalog {
+		if item.Matches(message) {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// SearchCommonErrors returns the entries that mention the query, or that
+// match it when the query is an error message.
+func SearchCommonErrors(catalog []CommonError, query string) []CommonError {
+	query = strings.TrimSpace(query)
+	needle := strings.ToLower(query)
+	result := []CommonError{}
+	for _, item := range catalog {
+		text := strings.ToLower(strings.Join(append(append([]string{item.Code, item.Message}, item.Short...), item.Long...), "\n"))
+		if strings.Contains(text, needle) || item.Matches(query) {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// CommonErrors is the catalog of the project, with its own entries first.
+func (p *Project) CommonErrors() []CommonError {
+	if p.commonErrors == nil {
+		return CommonErrors
+	}
+	return p.commonErrors
+}
@@ -0,0 +1,60 @@
+package project
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCommonErrorsBuiltIn(t *testing.T) {
+	for _, item := range CommonErrors {
+		if !item.Matches(item.Message) {
+			t.Errorf("%s does not match its own message", item.Code)
+		}
+		if len(item.Long) == 0 {
+			t.Errorf("%s is missing the docs", item.Code)
+		}
+	}
+	matches := MatchCommonErrors(CommonErrors, "creating CloudFront Distribution: TooManyCacheBehaviors: Your request contains more CacheBehaviors than are allowed per distribution.")
+	if len(matches) != 1 || matches[0].Code != "TooManyCacheBehaviors" {
+		t.Fatalf("got %v", matches)
+	}
+	help := matches[0].Help()
+	if help[len(help)-1] != "Learn more about this https://sst.dev/docs/common-errors#toomanycachebehaviors" {
+		t.Fatalf("got %v", help)
+	}
+}
+
+func TestLoadCommonErrors(t *testing.T) {
+	root := t.TempDir()
+	catalog, err := LoadCommonErrors(root)
+	if err != nil || len(catalog) != len(CommonErrors) {
+		t.Fatalf("got %d entries, %v", len(catalog), err)
+	}
+
+	os.WriteFile(filepath.Join(root, CommonErrorsFile), []byte(`[
+  {"code": "LegacyBucket", "message": "BucketAlreadyExists: taken", "short": ["See the runbook"], "link": "https://example.com"},
+  {"code": "Throttled", "pattern": "(?i)rate exceeded", "short": ["Retry later"]}
+]`), 0644)
+	catalog, err = LoadCommonErrors(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(catalog) != len(CommonErrors)+2 || catalog[0].Code != "LegacyBucket" {
+		t.Fatalf("got %v", catalog)
+	}
+	if matches := MatchCommonErrors(catalog, "error: Rate Exceeded for CreateFunction"); len(matches) != 1 || matches[0].Code != "Throttled" {
+		t.Fatalf("got %v", matches)
+	}
+	if matches := SearchCommonErrors(catalog, "runbook"); len(matches) != 1 || matches[0].Code != "LegacyBucket" {
+		t.Fatalf("got %v", matches)
+	}
+	if matches := SearchCommonErrors(catalog, "aws: cached SSO token is expired"); len(matches) != 1 || matches[0].Code != "aws_sso_expired" {
+		t.Fatalf("got %v", matches)
+	}
+
+	os.WriteFile(filepath.Join(root, CommonErrorsFile), []byte(`[{"code": "Broken", "pattern": "("}]`), 0644)
+	if _, err := LoadCommonErrors(root); err == nil {
+		t.Fatal("expected an invalid pattern to fail")
+	}
+}
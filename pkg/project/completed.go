@@ -42,6 +42,7 @@ func getCompletedEvent(ctx context.Context, passphrase string, workdir *PulumiWo
 		Errors:      []Error{},
 		Finished:    false,
 		Resources:   []apitype.ResourceV3{},
+		Secrets:     []string{},
 	}
 	checkpoint, err := workdir.Export()
 	if err != nil {
@@ -57,7 +58,16 @@ func getCompletedEvent(ctx context.Context, passphrase string, workdir *PulumiWo
 	}
 	complete.Resources = deployment.Resources
 
+	// parsePlaintext unwraps secrets in place so they have to be found first
+	for key, value := range deployment.Resources[0].Outputs {
+		if !strings.HasPrefix(key, "_") {
+			complete.Secrets = secretPaths(value, jsonPathKey(jsonPathKey("$", "outputs"), key), complete.Secrets)
+		}
+	}
 	for _, resource := range complete.Resources {
+		if target, ok := resource.Outputs["target"].(string); ok && resource.Type == "sst:sst:LinkRef" {
+			complete.Secrets = secretPaths(resource.Outputs["properties"], jsonPathKey(jsonPathKey("$", "links"), target), complete.Secrets)
+		}
 		outputs, ok := parsePlaintext(resource.Outputs).(map[string]interface{})
 		if !ok {
 			continue
@@ -5,32 +5,93 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"net/url"
+	"sync"
+	"time"
 
 	awssdk "github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/sts"
 	"github.com/sst/sst/v3/pkg/project/provider"
+	"github.com/sst/sst/v3/pkg/tunnel/proxyenv"
 )
 
-func (p *Project) EnvFor(ctx context.Context, complete *CompleteEvent, name string) (map[string]string, error) {
+// CredentialsEndpoint serves the credentials of a dev role in the format of
+// the container credentials provider, so SDKs refresh them on their own
+// instead of the process being restarted.
+type CredentialsEndpoint struct {
+	URL   string
+	Token string
+}
+
+// credentials are renewed this long before they expire
+const credentialsRefreshWindow = 5 * time.Minute
+
+var devCredentials = struct {
+	sync.Mutex
+	roles map[string]awssdk.Credentials
+}{roles: map[string]awssdk.Credentials{}}
+
+// DevCredentials assumes the role of a dev process, reusing the last
+// credentials until they are about to expire. The lock is only held for the
+// cache so a slow STS call doesn't hold up the other processes.
+func (p *Project) DevCredentials(ctx context.Context, role string) (awssdk.Credentials, error) {
+	devCredentials.Lock()
+	cached, ok := devCredentials.roles[role]
+	devCredentials.Unlock()
+	if ok && time.Until(cached.Expires) > credentialsRefreshWindow {
+		return cached, nil
+	}
+	prov, ok := p.Provider("aws")
+	if !ok {
+		return awssdk.Credentials{}, fmt.Errorf("aws provider not found")
+	}
+	awsProvider := prov.(*provider.AwsProvider)
+	stsClient := sts.NewFromConfig(awsProvider.Config())
+	sessionName := "sst-dev"
+	result, err := stsClient.AssumeRole(ctx, &sts.AssumeRoleInput{
+		RoleArn:         &role,
+		RoleSessionName: &sessionName,
+		DurationSeconds: awssdk.Int32(3600),
+	})
+	if err != nil {
+		return awssdk.Credentials{}, err
+	}
+	credentials := awssdk.Credentials{
+		AccessKeyID:     *result.Credentials.AccessKeyId,
+		SecretAccessKey: *result.Credentials.SecretAccessKey,
+		SessionToken:    *result.Credentials.SessionToken,
+		CanExpire:       true,
+		Expires:         *result.Credentials.Expiration,
+	}
+	devCredentials.Lock()
+	devCredentials.roles[role] = credentials
+	devCredentials.Unlock()
+	return credentials, nil
+}
+
+// EnvFor returns the environment of a dev process. With an endpoint, the
+// role of the process is served from it instead of as static credentials.
+func (p *Project) EnvFor(ctx context.Context, complete *CompleteEvent, name string, endpoint *CredentialsEndpoint) (map[string]string, error) {
 	log := slog.Default().With("service", "project.env").With("resource", name)
 	dev := complete.Devs[name]
 	env := map[string]string{}
-	if dev.Aws != nil && dev.Aws.Role != "" {
+	if dev.Aws != nil && dev.Aws.Role != "" && endpoint != nil {
+		log.Info("serving aws credentials", "role", dev.Aws.Role)
+		env["AWS_CONTAINER_CREDENTIALS_FULL_URI"] = endpoint.URL + "?name=" + url.QueryEscape(name)
+		env["AWS_CONTAINER_AUTHORIZATION_TOKEN"] = endpoint.Token
+		if prov, ok := p.Provider("aws"); ok {
+			env["AWS_REGION"] = prov.(*provider.AwsProvider).Config().Region
+		}
+	}
+	if dev.Aws != nil && dev.Aws.Role != "" && endpoint == nil {
 		log.Info("loading aws credentials", "role", dev.Aws.Role)
-		prov, _ := p.Provider("aws")
-		awsProvider := prov.(*provider.AwsProvider)
-		stsClient := sts.NewFromConfig(awsProvider.Config())
-		sessionName := "sst-dev"
-		result, err := stsClient.AssumeRole(ctx, &sts.AssumeRoleInput{
-			RoleArn:         &dev.Aws.Role,
-			RoleSessionName: &sessionName,
-			DurationSeconds: awssdk.Int32(3600),
-		})
+		credentials, err := p.DevCredentials(ctx, dev.Aws.Role)
 		if err == nil {
-			env["AWS_ACCESS_KEY_ID"] = *result.Credentials.AccessKeyId
-			env["AWS_SECRET_ACCESS_KEY"] = *result.Credentials.SecretAccessKey
-			env["AWS_SESSION_TOKEN"] = *result.Credentials.SessionToken
-			env["AWS_REGION"] = awsProvider.Config().Region
+			prov, _ := p.Provider("aws")
+			env["AWS_ACCESS_KEY_ID"] = credentials.AccessKeyID
+			env["AWS_SECRET_ACCESS_KEY"] = credentials.SecretAccessKey
+			env["AWS_SESSION_TOKEN"] = credentials.SessionToken
+			env["AWS_REGION"] = prov.(*provider.AwsProvider).Config().Region
 		}
 
 		if err != nil {
@@ -44,6 +105,12 @@ func (p *Project) EnvFor(ctx context.Context, complete *CompleteEvent, name stri
 		env["SST_RESOURCE_"+resource] = string(jsonValue)
 	}
 	env["SST_RESOURCE_App"] = fmt.Sprintf(`{"name": "%s", "stage": "%s" }`, p.App().Name, p.App().Stage)
+	// without a network interface the tunnel is only reachable as a proxy
+	if len(complete.Tunnels) > 0 && proxyenv.Rootless() {
+		for key, value := range proxyenv.Env() {
+			env[key] = value
+		}
+	}
 	for key, value := range dev.Environment {
 		env[key] = value
 	}
@@ -0,0 +1,71 @@
+package project
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	awssdk "github.com/aws/aws-sdk-go-v2/aws"
+)
+
+func TestEnvForCredentialsEndpoint(t *testing.T) {
+	p := &Project{app: &App{Name: "app", Stage: "dev"}}
+	complete := &CompleteEvent{
+		Devs: Devs{
+			"Web": Dev{
+				Name: "Web",
+				Aws: &struct {
+					Role string `json:"role"`
+				}{Role: "arn:aws:iam::123456789012:role/web"},
+			},
+		},
+	}
+	env, err := p.EnvFor(context.Background(), complete, "Web", &CredentialsEndpoint{
+		URL:   "http://127.0.0.1:13557/api/credentials",
+		Token: "secret",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if env["AWS_CONTAINER_CREDENTIALS_FULL_URI"] != "http://127.0.0.1:13557/api/credentials?name=Web" {
+		t.Errorf("unexpected uri %q", env["AWS_CONTAINER_CREDENTIALS_FULL_URI"])
+	}
+	if env["AWS_CONTAINER_AUTHORIZATION_TOKEN"] != "secret" {
+		t.Errorf("unexpected token %q", env["AWS_CONTAINER_AUTHORIZATION_TOKEN"])
+	}
+	// the endpoint takes precedence over the shared files, the child unsets
+	// the profile and the static keys
+	for _, key := range []string{"AWS_SHARED_CREDENTIALS_FILE", "AWS_CONFIG_FILE"} {
+		if _, ok := env[key]; ok {
+			t.Errorf("expected %s to be left alone", key)
+		}
+	}
+	if _, ok := env["AWS_ACCESS_KEY_ID"]; ok {
+		t.Errorf("expected no static credentials")
+	}
+}
+
+func TestDevCredentialsCache(t *testing.T) {
+	role := "arn:aws:iam::123456789012:role/cached"
+	devCredentials.Lock()
+	devCredentials.roles[role] = awssdk.Credentials{AccessKeyID: "cached", CanExpire: true, Expires: time.Now().Add(time.Hour)}
+	devCredentials.Unlock()
+	defer func() {
+		devCredentials.Lock()
+		delete(devCredentials.roles, role)
+		devCredentials.Unlock()
+	}()
+
+	// no aws provider is loaded so anything but the cached value fails
+	credentials, err := (&Project{}).DevCredentials(context.Background(), role)
+	if err != nil || credentials.AccessKeyID != "cached" {
+		t.Fatalf("expected the cached credentials, got %v %v", credentials, err)
+	}
+
+	devCredentials.Lock()
+	devCredentials.roles[role] = awssdk.Credentials{AccessKeyID: "cached", CanExpire: true, Expires: time.Now().Add(time.Minute)}
+	devCredentials.Unlock()
+	if _, err := (&Project{}).DevCredentials(context.Background(), role); err == nil {
+		t.Fatal("expected credentials close to expiring to be renewed")
+	}
+}
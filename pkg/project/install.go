@@ -29,6 +29,9 @@ func (err *ErrProviderVersionTooLow) Error() string {
 	return "provider version too low"
 }
 
+var ErrProviderLockMissing = fmt.Errorf("provider lock missing")
+var ErrProviderLockOutdated = fmt.Errorf("provider lock outdated")
+
 func (p *Project) NeedsInstall() bool {
 	if len(p.app.Providers) != len(p.lock) {
 		return true
@@ -86,6 +89,51 @@ func (p *Project) Install() error {
 	return nil
 }
 
+// VerifyProviderLock checks that the provider lock next to the config exists
+// and matches the providers in the config.
+func (p *Project) VerifyProviderLock() error {
+	lockPath := path.ResolveProviderLock(p.PathConfig())
+	if _, err := os.Stat(lockPath); err != nil {
+		return ErrProviderLockMissing
+	}
+	err := p.loadProviderLock()
+	if err != nil {
+		return err
+	}
+	if p.NeedsInstall() {
+		return ErrProviderLockOutdated
+	}
+	for _, entry := range p.lock {
+		if entry.Integrity == "" {
+			return ErrProviderLockOutdated
+		}
+	}
+	return nil
+}
+
+// InstallFrozen installs the providers exactly as they are recorded in the
+// provider lock. It fails instead of resolving new versions if the lock is
+// missing or does not match the config.
+func (p *Project) InstallFrozen() error {
+	slog.Info("installing deps from lock")
+	err := p.VerifyProviderLock()
+	if err != nil {
+		return err
+	}
+
+	err = p.writePackageJson()
+	if err != nil {
+		return err
+	}
+
+	err = p.fetchDeps()
+	if err != nil {
+		return err
+	}
+
+	return p.writeTypes()
+}
+
 func (p *Project) writePackageJson() error {
 	slog.Info("writing package.json")
 	packageJsonPath := filepath.Join(p.PathPlatformDir(), "package.json")
@@ -106,12 +154,27 @@ func (p *Project) writePackageJson() error {
 		return err
 	}
 
+	registry := npm.LoadRegistry()
 	dependencies := result["dependencies"].(map[string]interface{})
 	for _, entry := range p.lock {
 		slog.Info("adding dependency", "name", entry.Name)
 		dependencies[entry.Package] = entry.Version
+		if entry.Integrity != "" {
+			tarball, err := resolveTarball(registry, entry)
+			if err != nil {
+				return fmt.Errorf("failed to find provider %s: %w", entry.Name, err)
+			}
+			tarball, err = npm.Download(registry, entry.Package, tarball, entry.Integrity)
+			if err != nil {
+				return fmt.Errorf("failed to verify provider %s: %w", entry.Name, err)
+			}
+			dependencies[entry.Package] = "file:" + filepath.ToSlash(tarball)
+		}
 	}
 	dependencies["@pulumi/pulumi"] = global.PULUMI_VERSION
+	if tarball, ok := registry.Mirrored("@pulumi/pulumi", strings.TrimPrefix(global.PULUMI_VERSION, "v")); ok {
+		dependencies["@pulumi/pulumi"] = "file:" + filepath.ToSlash(tarball)
+	}
 
 	dataToWrite, err := json.MarshalIndent(result, "", "  ")
 	if err != nil {
@@ -132,6 +195,26 @@ func (p *Project) writePackageJson() error {
 	return nil
 }
 
+// resolveTarball returns where to download the tarball of a locked provider
+// from. Providers from the mirror aren't locked to a path, since it's
+// different on each machine, so they're looked up in the mirror again.
+func resolveTarball(registry npm.Registry, entry *ProviderLockEntry) (string, error) {
+	if tarball, ok := registry.Mirrored(entry.Package, entry.Version); ok {
+		return "file:" + filepath.ToSlash(tarball), nil
+	}
+	if entry.Resolved != "" {
+		return entry.Resolved, nil
+	}
+	pkg, err := npm.Get(registry, entry.Package, entry.Version)
+	if err != nil {
+		return "", err
+	}
+	if pkg.Dist == nil || pkg.Dist.Tarball == "" {
+		return "", fmt.Errorf("%s@%s has no tarball", entry.Package, entry.Version)
+	}
+	return pkg.Dist.Tarball, nil
+}
+
 func (p *Project) writeTypes() error {
 	slog.Info("writing types")
 	typesPath := filepath.Join(p.PathPlatformDir(), "config.d.ts")
@@ -183,8 +266,14 @@ func (p *Project) fetchDeps() error {
 	if flag.SST_NO_BUN {
 		manager = "npm"
 	}
+	registry := npm.LoadRegistry()
+	err := registry.WriteNpmrc(p.PathPlatformDir())
+	if err != nil {
+		return err
+	}
 	cmd := process.Command(manager, "install")
 	cmd.Dir = p.PathPlatformDir()
+	cmd.Env = append(os.Environ(), registry.Env()...)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		return fmt.Errorf("failed to run %s install: %w\n%s", manager, err, output)
@@ -197,13 +286,27 @@ type ProviderLockEntry struct {
 	Package string `json:"package"`
 	Version string `json:"version"`
 	Alias   string `json:"alias"`
+	// Registry is the registry the package was resolved from.
+	Registry string `json:"registry,omitempty"`
+	// Resolved is the tarball the package was downloaded from. It's not set
+	// for packages from the mirror.
+	Resolved string `json:"resolved,omitempty"`
+	// Integrity is the subresource integrity of the tarball.
+	Integrity string `json:"integrity,omitempty"`
 }
 
 type ProviderLock = []*ProviderLockEntry
 
+func readProviderLock(cfgPath string) ([]byte, error) {
+	data, err := os.ReadFile(path.ResolveProviderLock(cfgPath))
+	if os.IsNotExist(err) {
+		return os.ReadFile(path.ResolveLegacyProviderLock(cfgPath))
+	}
+	return data, err
+}
+
 func (p *Project) loadProviderLock() error {
-	lockPath := path.ResolveProviderLock(p.PathConfig())
-	data, err := os.ReadFile(lockPath)
+	data, err := readProviderLock(p.PathConfig())
 	if err != nil {
 		p.lock = ProviderLock{}
 		return nil
@@ -223,6 +326,16 @@ func (p *Project) generateProviderLock() error {
 	if err != nil {
 		return err
 	}
+	// read the lock from disk since the in-memory one is reset when the
+	// platform is copied over
+	locked := map[string]*ProviderLockEntry{}
+	previous := ProviderLock{}
+	if data, err := readProviderLock(p.PathConfig()); err == nil {
+		json.Unmarshal(data, &previous)
+	}
+	for _, entry := range previous {
+		locked[entry.Name] = entry
+	}
 	for name, config := range p.app.Providers {
 		n := name
 		// Use the explicit package name if set, otherwise default to the provider name
@@ -231,8 +344,16 @@ func (p *Project) generateProviderLock() error {
 			pkgName = n
 		}
 		version := config.(map[string]interface{})["version"]
+		pinned := true
 		if version == nil || version == "" {
 			version = "latest"
+			pinned = false
+			// Keep the version that was resolved before so unpinned providers
+			// don't drift between installs.
+			if entry, ok := locked[n]; ok && entry.Version != "" && (pkgName == n || pkgName == entry.Package) {
+				version = entry.Version
+				pkgName = entry.Package
+			}
 		}
 		wg.Go(func() error {
 			result, err := FindProvider(n, version.(string), pkgName.(string))
@@ -240,8 +361,11 @@ func (p *Project) generateProviderLock() error {
 				return err
 			}
 			if match, ok := pkg.Dependencies[result.Package]; ok {
-				if version == "latest" {
-					result.Version = match
+				if !pinned && result.Version != match {
+					result, err = FindProvider(n, match, result.Package)
+					if err != nil {
+						return err
+					}
 				}
 				if semver.MustParse(result.Version).Compare(semver.MustParse(match)) < 0 {
 					results <- *result
@@ -271,8 +395,18 @@ func (p *Project) generateProviderLock() error {
 
 func FindProvider(provider string, version string, pkg string) (*ProviderLockEntry, error) {
 	registry := npm.LoadRegistry()
-	for _, prefix := range []string{"@sst-provider/", "@pulumi/", "@pulumiverse/", "pulumi-", "@", ""} {
+	prefixes := []string{"@sst-provider/", "@pulumi/", "@pulumiverse/", "pulumi-", "@", ""}
+	// a full scoped package name can only resolve without a prefix
+	if strings.HasPrefix(pkg, "@") && strings.Contains(pkg, "/") {
+		prefixes = []string{""}
+	}
+	for _, prefix := range prefixes {
 		npmPkg, err := npm.Get(registry, prefix+pkg, version)
+		// the registry can't be reached, so there's no point trying the
+		// other prefixes
+		if err != nil && npm.Unavailable(err) {
+			return nil, err
+		}
 		if err != nil {
 			continue
 		}
@@ -292,12 +426,24 @@ func FindProvider(provider string, version string, pkg string) (*ProviderLockEnt
 			}
 		}
 		alias = strings.ReplaceAll(alias, "-", "")
-		return &ProviderLockEntry{
-			Name:    provider,
-			Package: npmPkg.Name,
-			Version: npmPkg.Version,
-			Alias:   alias,
-		}, nil
+		entry := &ProviderLockEntry{
+			Name:     provider,
+			Package:  npmPkg.Name,
+			Version:  npmPkg.Version,
+			Alias:    alias,
+			Registry: registry.URL(npmPkg.Name),
+		}
+		if npmPkg.Dist != nil {
+			// the path to the mirror is only valid on this machine
+			if !strings.HasPrefix(npmPkg.Dist.Tarball, "file:") {
+				entry.Resolved = npmPkg.Dist.Tarball
+			}
+			entry.Integrity = npmPkg.Dist.Integrity
+			if entry.Integrity == "" {
+				entry.Integrity = npmPkg.Dist.Shasum
+			}
+		}
+		return entry, nil
 	}
 	return nil, fmt.Errorf("provider %s not found", provider)
 }
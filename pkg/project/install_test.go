@@ -0,0 +1,89 @@
+package project
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sst/sst/v3/pkg/project/path"
+)
+
+func writeLock(t *testing.T, file string, data string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(file, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVerifyProviderLock(t *testing.T) {
+	newProject := func(t *testing.T) *Project {
+		cfgPath := filepath.Join(t.TempDir(), "sst.config.ts")
+		return &Project{
+			config: cfgPath,
+			app: &App{
+				Providers: map[string]interface{}{
+					"aws": map[string]interface{}{"version": "6.52.0"},
+				},
+			},
+		}
+	}
+	locked := `[{"name":"aws","package":"@pulumi/aws","version":"6.52.0","integrity":"sha512-abc"}]`
+
+	t.Run("missing", func(t *testing.T) {
+		p := newProject(t)
+		if err := p.VerifyProviderLock(); err != ErrProviderLockMissing {
+			t.Fatalf("expected ErrProviderLockMissing, got %v", err)
+		}
+	})
+
+	t.Run("legacy lock is not enough", func(t *testing.T) {
+		p := newProject(t)
+		writeLock(t, path.ResolveLegacyProviderLock(p.PathConfig()), locked)
+		if err := p.VerifyProviderLock(); err != ErrProviderLockMissing {
+			t.Fatalf("expected ErrProviderLockMissing, got %v", err)
+		}
+	})
+
+	t.Run("matches", func(t *testing.T) {
+		p := newProject(t)
+		writeLock(t, path.ResolveProviderLock(p.PathConfig()), locked)
+		if err := p.VerifyProviderLock(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("version changed", func(t *testing.T) {
+		p := newProject(t)
+		writeLock(t, path.ResolveProviderLock(p.PathConfig()), locked)
+		p.app.Providers["aws"] = map[string]interface{}{"version": "6.53.0"}
+		if err := p.VerifyProviderLock(); err != ErrProviderLockOutdated {
+			t.Fatalf("expected ErrProviderLockOutdated, got %v", err)
+		}
+	})
+
+	t.Run("no integrity", func(t *testing.T) {
+		p := newProject(t)
+		writeLock(t, path.ResolveProviderLock(p.PathConfig()), `[{"name":"aws","package":"@pulumi/aws","version":"6.52.0"}]`)
+		if err := p.VerifyProviderLock(); err != ErrProviderLockOutdated {
+			t.Fatalf("expected ErrProviderLockOutdated, got %v", err)
+		}
+	})
+}
+
+func TestReadProviderLockLegacy(t *testing.T) {
+	cfgPath := filepath.Join(t.TempDir(), "sst.config.ts")
+	writeLock(t, path.ResolveLegacyProviderLock(cfgPath), "legacy")
+	data, err := readProviderLock(cfgPath)
+	if err != nil || string(data) != "legacy" {
+		t.Fatalf("expected the legacy lock, got %q %v", data, err)
+	}
+
+	writeLock(t, path.ResolveProviderLock(cfgPath), "current")
+	data, err = readProviderLock(cfgPath)
+	if err != nil || string(data) != "current" {
+		t.Fatalf("expected the current lock, got %q %v", data, err)
+	}
+}
@@ -0,0 +1,91 @@
+package project
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/sst/sst/v3/pkg/npm"
+	"github.com/sst/sst/v3/platform"
+	"golang.org/x/sync/errgroup"
+)
+
+type OutdatedProvider struct {
+	Name    string `json:"name"`
+	Package string `json:"package"`
+	// Current is the version in the provider lock.
+	Current string `json:"current"`
+	// Wanted is the newest version that matches the one the config asks for.
+	// For providers without a version this is the one SST is tested against,
+	// or the latest.
+	Wanted string `json:"wanted"`
+	// Range is the version the config asks for, as it's written.
+	Range  string `json:"range,omitempty"`
+	Latest string `json:"latest"`
+}
+
+func (o OutdatedProvider) IsOutdated() bool {
+	return o.Current != o.Wanted || o.Current != o.Latest
+}
+
+// Outdated looks up the latest version of every provider in the lock.
+func (p *Project) Outdated() ([]OutdatedProvider, error) {
+	pkg, err := platform.PackageJson()
+	if err != nil {
+		return nil, err
+	}
+	registry := npm.LoadRegistry()
+	result := []OutdatedProvider{}
+	var lock sync.Mutex
+	var wg errgroup.Group
+	for _, entry := range p.lock {
+		entry := entry
+		wg.Go(func() error {
+			latest, err := npm.Get(registry, entry.Package, "latest")
+			if err != nil {
+				return err
+			}
+			item := OutdatedProvider{
+				Name:    entry.Name,
+				Package: entry.Package,
+				Current: entry.Version,
+				Wanted:  latest.Version,
+				Latest:  latest.Version,
+			}
+			if config, ok := p.app.Providers[entry.Name].(map[string]interface{}); ok {
+				if version, ok := config["version"].(string); ok && version != "" {
+					item.Range = version
+				} else if match, ok := pkg.Dependencies[entry.Package]; ok {
+					item.Range = match
+				}
+			}
+			if item.Range != "" {
+				wanted, err := npm.Get(registry, entry.Package, item.Range)
+				if err != nil {
+					return err
+				}
+				item.Wanted = wanted.Version
+			}
+			lock.Lock()
+			result = append(result, item)
+			lock.Unlock()
+			return nil
+		})
+	}
+	if err := wg.Wait(); err != nil {
+		return nil, err
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result, nil
+}
+
+// LockedProvider returns the entry in the provider lock for a provider.
+func (p *Project) LockedProvider(name string) (*ProviderLockEntry, bool) {
+	for _, entry := range p.lock {
+		if entry.Name == name {
+			return entry, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,47 @@
+package project
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOutdatedResolvesRange(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/@pulumi/aws/latest":
+			w.Write([]byte(`{"name":"@pulumi/aws","version":"7.1.0"}`))
+		case "/@pulumi/aws/^6.0.0":
+			w.Write([]byte(`{"name":"@pulumi/aws","version":"6.60.0"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	t.Setenv("NPM_REGISTRY", server.URL)
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	p := &Project{
+		app: &App{
+			Providers: map[string]interface{}{
+				"aws": map[string]interface{}{"version": "^6.0.0"},
+			},
+		},
+		lock: ProviderLock{{Name: "aws", Package: "@pulumi/aws", Version: "6.60.0"}},
+	}
+	providers, err := p.Outdated()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(providers) != 1 {
+		t.Fatalf("got %v", providers)
+	}
+	got := providers[0]
+	if got.Wanted != "6.60.0" || got.Range != "^6.0.0" || got.Latest != "7.1.0" {
+		t.Fatalf("got %+v", got)
+	}
+	if got.Current != got.Wanted {
+		t.Errorf("the installed version matches the range")
+	}
+}
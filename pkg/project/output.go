@@ -0,0 +1,84 @@
+package project
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+)
+
+// SecretMask replaces the secret values in a Document.
+const SecretMask = "********"
+
+// Document returns the outputs and the properties of the linked resources,
+// keyed by `outputs` and `links`. Secret values are replaced with SecretMask
+// unless showSecrets is set. The result does not share any maps with the
+// event.
+func (c *CompleteEvent) Document(showSecrets bool) map[string]interface{} {
+	links := map[string]interface{}{}
+	for name, link := range c.Links {
+		links[name] = link.Properties
+	}
+	var document map[string]interface{}
+	data, _ := json.Marshal(map[string]interface{}{
+		"outputs": c.Outputs,
+		"links":   links,
+	})
+	json.Unmarshal(data, &document)
+	if showSecrets || len(c.Secrets) == 0 {
+		return document
+	}
+	secrets := map[string]bool{}
+	for _, path := range c.Secrets {
+		secrets[path] = true
+	}
+	return mask(document, "$", secrets).(map[string]interface{})
+}
+
+func mask(input interface{}, path string, secrets map[string]bool) interface{} {
+	if secrets[path] {
+		return SecretMask
+	}
+	switch cast := input.(type) {
+	case map[string]interface{}:
+		for key, value := range cast {
+			cast[key] = mask(value, jsonPathKey(path, key), secrets)
+		}
+	case []interface{}:
+		for index, value := range cast {
+			cast[index] = mask(value, jsonPathIndex(path, index), secrets)
+		}
+	}
+	return input
+}
+
+// secretPaths appends the path of every secret in input to result.
+func secretPaths(input interface{}, path string, result []string) []string {
+	switch cast := input.(type) {
+	case apitype.SecretV1, *apitype.SecretV1:
+		return append(result, path)
+	case map[string]interface{}:
+		keys := make([]string, 0, len(cast))
+		for key := range cast {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			result = secretPaths(cast[key], jsonPathKey(path, key), result)
+		}
+	case []interface{}:
+		for index, value := range cast {
+			result = secretPaths(value, jsonPathIndex(path, index), result)
+		}
+	}
+	return result
+}
+
+func jsonPathKey(path string, key string) string {
+	return path + "[" + strconv.Quote(key) + "]"
+}
+
+func jsonPathIndex(path string, index int) string {
+	return path + "[" + strconv.Itoa(index) + "]"
+}
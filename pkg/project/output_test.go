@@ -0,0 +1,52 @@
+package project
+
+import (
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/sst/sst/v3/pkg/project/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecretPaths(t *testing.T) {
+	input := map[string]interface{}{
+		"name":  "bucket",
+		"value": apitype.SecretV1{Plaintext: `"hunter2"`},
+		"list": []interface{}{
+			"a",
+			&apitype.SecretV1{Plaintext: `"b"`},
+		},
+	}
+	paths := secretPaths(input, `$["links"]["MySecret"]`, nil)
+	assert.Equal(t, []string{
+		`$["links"]["MySecret"]["list"][1]`,
+		`$["links"]["MySecret"]["value"]`,
+	}, paths)
+}
+
+func TestDocument(t *testing.T) {
+	complete := &CompleteEvent{
+		Outputs: map[string]interface{}{
+			"url":   "https://example.com",
+			"token": "abc",
+		},
+		Links: common.Links{
+			"MySecret": common.Link{Properties: map[string]interface{}{"value": "hunter2"}},
+			"MyBucket": common.Link{Properties: map[string]interface{}{"name": "bucket"}},
+		},
+		Secrets: []string{
+			`$["outputs"]["token"]`,
+			`$["links"]["MySecret"]["value"]`,
+		},
+	}
+
+	masked := complete.Document(false)
+	assert.Equal(t, SecretMask, masked["outputs"].(map[string]interface{})["token"])
+	assert.Equal(t, "https://example.com", masked["outputs"].(map[string]interface{})["url"])
+	assert.Equal(t, SecretMask, masked["links"].(map[string]interface{})["MySecret"].(map[string]interface{})["value"])
+	assert.Equal(t, "bucket", masked["links"].(map[string]interface{})["MyBucket"].(map[string]interface{})["name"])
+	assert.Equal(t, "hunter2", complete.Links["MySecret"].Properties["value"], "the event is not changed")
+
+	shown := complete.Document(true)
+	assert.Equal(t, "abc", shown["outputs"].(map[string]interface{})["token"])
+}
@@ -18,6 +18,13 @@ func ResolveRootDir(cfgPath string) string {
 	return filepath.Dir(cfgPath)
 }
 
+// ResolveProviderLock is next to the config so it can be committed.
 func ResolveProviderLock(cfgPath string) string {
+	return filepath.Join(ResolveRootDir(cfgPath), "sst.lock.json")
+}
+
+// ResolveLegacyProviderLock is where the provider lock used to be kept, it's
+// read if there's no lock next to the config.
+func ResolveLegacyProviderLock(cfgPath string) string {
 	return filepath.Join(ResolveWorkingDir(cfgPath), "provider-lock.json")
 }
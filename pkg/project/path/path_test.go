@@ -22,7 +22,8 @@ func TestPathResolvers(t *testing.T) {
 		{"ResolveWorkingDir", path.ResolveWorkingDir, working},
 		{"ResolvePlatformDir", path.ResolvePlatformDir, filepath.Join(working, "platform")},
 		{"ResolveLogDir", path.ResolveLogDir, filepath.Join(working, "log")},
-		{"ResolveProviderLock", path.ResolveProviderLock, filepath.Join(working, "provider-lock.json")},
+		{"ResolveProviderLock", path.ResolveProviderLock, filepath.Join(root, "sst.lock.json")},
+		{"ResolveLegacyProviderLock", path.ResolveLegacyProviderLock, filepath.Join(working, "provider-lock.json")},
 	}
 
 	for _, tt := range tests {
@@ -1,29 +1,75 @@
 package project
 
 import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
 	"strings"
 
 	"github.com/Masterminds/semver/v3"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
 )
 
-type migrationNotice struct {
-	providerName string
-	fromVersion  string
-	toVersion    string
-	message      string
+// upgradeRule describes a provider upgrade that needs the user's attention.
+// Rules are checked in order and only the first matching rule for a provider
+// is reported, so specific rules should come before generic ones.
+type upgradeRule struct {
+	// Provider is the name of the provider or "*" to match any provider.
+	Provider string `json:"provider"`
+	// From and To are semver constraints on the deployed and the new version.
+	From string `json:"from"`
+	To   string `json:"to"`
+	// Major matches any upgrade that crosses a major version.
+	Major bool `json:"major"`
+	// Blocking rules stop the deploy, the others are only warnings.
+	Blocking bool     `json:"blocking"`
+	Message  []string `json:"message"`
 }
 
-var migrationNotices = []migrationNotice{
-	{
-		providerName: "aws",
-		fromVersion:  "6.0.0",
-		toVersion:    "7.0.0",
-		message:      "Detected AWS provider upgrade from v6 to v7\n\nA one-time state migration is required before you can deploy.\nSST components are already updated — you may be affected if you\nuse transforms or the AWS provider directly.\n\n1. Run `sst diff` to preview changes\n2. Run `sst refresh` to migrate state (repeat for each stage)\n3. Run `sst deploy`\n\nIf you share resources across stages, refresh the stage where the\nresource is created first, not the one referencing it via get().\n\nMigration guide: https://sst.dev/docs/migrate-from-v3",
-	},
+//go:embed preflight.json
+var upgradeRulesJson []byte
+
+var upgradeRules = func() []upgradeRule {
+	var rules []upgradeRule
+	if err := json.Unmarshal(upgradeRulesJson, &rules); err != nil {
+		panic(fmt.Errorf("invalid preflight.json: %w", err))
+	}
+	return rules
+}()
+
+func (rule upgradeRule) matches(name string, current *semver.Version, target *semver.Version) bool {
+	if rule.Provider != "*" && rule.Provider != name {
+		return false
+	}
+	if rule.Major && target.Major() <= current.Major() {
+		return false
+	}
+	if rule.From != "" {
+		constraint, err := semver.NewConstraint(rule.From)
+		if err != nil || !constraint.Check(current) {
+			return false
+		}
+	}
+	if rule.To != "" {
+		constraint, err := semver.NewConstraint(rule.To)
+		if err != nil || !constraint.Check(target) {
+			return false
+		}
+	}
+	return true
 }
 
-func (p *Project) checkProviderUpgrade(resources []apitype.ResourceV3) []string {
+func (rule upgradeRule) render(name string, current *semver.Version, target *semver.Version) string {
+	return strings.NewReplacer(
+		"{provider}", name,
+		"{from}", fmt.Sprint(current.Major()),
+		"{to}", fmt.Sprint(target.Major()),
+	).Replace(strings.Join(rule.Message, "\n"))
+}
+
+// evaluateUpgradeRules compares the provider versions in the deployed state
+// with the ones in the lock and returns the messages of the matching rules.
+func (p *Project) evaluateUpgradeRules(resources []apitype.ResourceV3) (blocking []string, warnings []string) {
 	providerVersions := make(map[string]string)
 
 	// We iterate backwards over the slice b/c when multiple provider versions are present (i.e. just after refreshing but before deploying)
@@ -41,8 +87,6 @@ func (p *Project) checkProviderUpgrade(resources []apitype.ResourceV3) []string
 		providerVersions[name] = versionOutput
 	}
 
-	var messages []string
-
 	for _, entry := range p.lock {
 		currentVersionStr, ok := providerVersions[entry.Name]
 		if !ok {
@@ -64,32 +108,19 @@ func (p *Project) checkProviderUpgrade(resources []apitype.ResourceV3) []string
 			continue
 		}
 
-		// Check against all applicable upgrade rules
-		for _, rule := range migrationNotices {
-			if rule.providerName != entry.Name {
-				continue
-			}
-
-			ruleFrom, err := semver.NewVersion(rule.fromVersion)
-			if err != nil {
+		for _, rule := range upgradeRules {
+			if !rule.matches(entry.Name, currentVersion, targetVersion) {
 				continue
 			}
-
-			ruleTo, err := semver.NewVersion(rule.toVersion)
-			if err != nil {
-				continue
-			}
-
-			// Check if the upgrade path crosses this rule's version range
-			// Current version must be >= fromVersion AND < toVersion
-			// Target version must be >= toVersion
-			if currentVersion.Compare(ruleFrom) >= 0 &&
-				currentVersion.Compare(ruleTo) < 0 &&
-				targetVersion.Compare(ruleTo) >= 0 {
-				messages = append(messages, rule.message)
+			message := rule.render(entry.Name, currentVersion, targetVersion)
+			if rule.Blocking {
+				blocking = append(blocking, message)
+			} else {
+				warnings = append(warnings, message)
 			}
+			break
 		}
 	}
 
-	return messages
+	return blocking, warnings
 }
@@ -17,7 +17,7 @@ func makeProviderResource(provider string, version string) apitype.ResourceV3 {
 	}
 }
 
-func TestCheckProviderUpgrade_AWSV6ToV7(t *testing.T) {
+func TestEvaluateUpgradeRules_AWSV6ToV7(t *testing.T) {
 	p := &Project{
 		lock: ProviderLock{
 			{Name: "aws", Package: "@pulumi/aws", Alias: "aws", Version: "7.0.0"},
@@ -28,7 +28,7 @@ func TestCheckProviderUpgrade_AWSV6ToV7(t *testing.T) {
 		makeProviderResource("aws", "6.52.0"),
 	}
 
-	messages := p.checkProviderUpgrade(resources)
+	messages, _ := p.evaluateUpgradeRules(resources)
 	if len(messages) != 1 {
 		t.Fatalf("expected 1 message, got %d", len(messages))
 	}
@@ -37,7 +37,7 @@ func TestCheckProviderUpgrade_AWSV6ToV7(t *testing.T) {
 	}
 }
 
-func TestCheckProviderUpgrade_AWSAlreadyOnV7(t *testing.T) {
+func TestEvaluateUpgradeRules_AWSAlreadyOnV7(t *testing.T) {
 	p := &Project{
 		lock: ProviderLock{
 			{Name: "aws", Package: "@pulumi/aws", Alias: "aws", Version: "7.0.0"},
@@ -48,13 +48,13 @@ func TestCheckProviderUpgrade_AWSAlreadyOnV7(t *testing.T) {
 		makeProviderResource("aws", "7.0.0"),
 	}
 
-	messages := p.checkProviderUpgrade(resources)
+	messages, _ := p.evaluateUpgradeRules(resources)
 	if len(messages) != 0 {
 		t.Fatalf("expected 0 messages, got %d", len(messages))
 	}
 }
 
-func TestCheckProviderUpgrade_NoMatchingProvider(t *testing.T) {
+func TestEvaluateUpgradeRules_NoMatchingProvider(t *testing.T) {
 	p := &Project{
 		lock: ProviderLock{
 			{Name: "aws", Package: "@pulumi/aws", Alias: "aws", Version: "7.0.0"},
@@ -65,8 +65,62 @@ func TestCheckProviderUpgrade_NoMatchingProvider(t *testing.T) {
 		makeProviderResource("vercel", "1.11.0"),
 	}
 
-	messages := p.checkProviderUpgrade(resources)
+	messages, _ := p.evaluateUpgradeRules(resources)
 	if len(messages) != 0 {
 		t.Fatalf("expected 0 messages, got %d", len(messages))
 	}
 }
+
+func TestEvaluateUpgradeRules_GenericMajorWarning(t *testing.T) {
+	p := &Project{
+		lock: ProviderLock{
+			{Name: "vercel", Package: "@pulumiverse/vercel", Alias: "vercel", Version: "4.0.0"},
+		},
+	}
+
+	blocking, warnings := p.evaluateUpgradeRules([]apitype.ResourceV3{
+		makeProviderResource("vercel", "3.15.0"),
+	})
+	if len(blocking) != 0 {
+		t.Fatalf("expected 0 blocking messages, got %d", len(blocking))
+	}
+	if len(warnings) != 1 {
+		t.Fatalf("expected 1 warning, got %d", len(warnings))
+	}
+	if !strings.Contains(warnings[0], "vercel provider upgrade from v3 to v4") {
+		t.Fatalf("expected warning to mention the upgrade, got: %s", warnings[0])
+	}
+}
+
+func TestEvaluateUpgradeRules_SpecificRuleWins(t *testing.T) {
+	p := &Project{
+		lock: ProviderLock{
+			{Name: "cloudflare", Package: "@pulumi/cloudflare", Alias: "cloudflare", Version: "6.1.0"},
+		},
+	}
+
+	_, warnings := p.evaluateUpgradeRules([]apitype.ResourceV3{
+		makeProviderResource("cloudflare", "5.49.0"),
+	})
+	if len(warnings) != 1 {
+		t.Fatalf("expected 1 warning, got %d", len(warnings))
+	}
+	if !strings.Contains(warnings[0], "Cloudflare") {
+		t.Fatalf("expected the cloudflare rule, got: %s", warnings[0])
+	}
+}
+
+func TestEvaluateUpgradeRules_MinorUpgrade(t *testing.T) {
+	p := &Project{
+		lock: ProviderLock{
+			{Name: "vercel", Package: "@pulumiverse/vercel", Alias: "vercel", Version: "3.16.0"},
+		},
+	}
+
+	blocking, warnings := p.evaluateUpgradeRules([]apitype.ResourceV3{
+		makeProviderResource("vercel", "3.15.0"),
+	})
+	if len(blocking) != 0 || len(warnings) != 0 {
+		t.Fatalf("expected no messages, got %v %v", blocking, warnings)
+	}
+}
@@ -0,0 +1,222 @@
+package project
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/joho/godotenv"
+	"github.com/sst/sst/v3/internal/util"
+)
+
+// ProfilesFile defines the profiles of an app, it lives next to the config.
+const ProfilesFile = "sst.profiles.json"
+
+// Profile bundles the stage, the AWS credentials, and the env files that are
+// used together, so they can be switched with `sst profile use`.
+type Profile struct {
+	Stage      string `json:"stage,omitempty"`
+	AwsProfile string `json:"awsProfile,omitempty"`
+	// AwsRole is the ARN of a role that's assumed with the credentials.
+	AwsRole  string   `json:"awsRole,omitempty"`
+	Region   string   `json:"region,omitempty"`
+	EnvFiles []string `json:"envFiles,omitempty"`
+}
+
+// env returns the variables that the profile sets for the AWS credentials.
+func (p *Profile) env() map[string]string {
+	result := map[string]string{}
+	if p.AwsProfile != "" {
+		result["AWS_PROFILE"] = p.AwsProfile
+	}
+	if p.AwsRole != "" {
+		result["SST_AWS_ROLE_ARN"] = p.AwsRole
+	}
+	if p.Region != "" {
+		result["AWS_REGION"] = p.Region
+	}
+	return result
+}
+
+func LoadProfiles(cfgPath string) (map[string]*Profile, error) {
+	profiles := map[string]*Profile{}
+	data, err := os.ReadFile(filepath.Join(filepath.Dir(cfgPath), ProfilesFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return profiles, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &profiles); err != nil {
+		return nil, util.NewReadableError(err, fmt.Sprintf("Invalid %s: %s", ProfilesFile, err.Error())).WithCode("profiles_invalid")
+	}
+	for name, profile := range profiles {
+		if profile == nil {
+			profiles[name] = &Profile{}
+		}
+	}
+	return profiles, nil
+}
+
+func resolveProfileFile(cfgPath string) string {
+	return filepath.Join(ResolveWorkingDir(cfgPath), "profile")
+}
+
+// LoadActiveProfile returns the profile in use, set with the SST_PROFILE
+// environment variable or with `sst profile use`. The name is empty if no
+// profile is in use.
+func LoadActiveProfile(cfgPath string) (string, *Profile, error) {
+	name := os.Getenv("SST_PROFILE")
+	if name == "" {
+		data, err := os.ReadFile(resolveProfileFile(cfgPath))
+		if err != nil {
+			return "", nil, nil
+		}
+		name = strings.TrimSpace(string(data))
+	}
+	if name == "" {
+		return "", nil, nil
+	}
+	profiles, err := LoadProfiles(cfgPath)
+	if err != nil {
+		return "", nil, err
+	}
+	profile, ok := profiles[name]
+	if !ok {
+		return "", nil, util.NewReadableError(nil, fmt.Sprintf("The profile \"%s\" is not defined in %s. Run `sst profile list` to see the profiles.", name, ProfilesFile)).WithCode("profile_not_found")
+	}
+	return name, profile, nil
+}
+
+// SetActiveProfile switches to a profile, or stops using one if the name is
+// empty.
+func SetActiveProfile(cfgPath string, name string) error {
+	path := resolveProfileFile(cfgPath)
+	if name == "" {
+		err := os.Remove(path)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(name), 0644)
+}
+
+// EnvLayer is a source of environment variables.
+type EnvLayer struct {
+	Name string `json:"name"`
+	Path string `json:"path,omitempty"`
+	// Override is set for layers that replace the variables that are already
+	// set in the shell.
+	Override bool              `json:"override"`
+	Keys     []string          `json:"keys"`
+	Values   map[string]string `json:"-"`
+}
+
+// EnvLayers returns the sources of environment variables for a stage, from
+// the lowest precedence to the highest. Env files that don't exist are
+// skipped.
+//
+//   - .env
+//   - .env.local
+//   - the env files of the profile, in order
+//   - the shell
+//   - the AWS settings of the profile
+//   - .env.<stage>
+//   - .env.<stage>.local
+func EnvLayers(cfgPath string, stage string, profileName string, profile *Profile) ([]EnvLayer, error) {
+	root := filepath.Dir(cfgPath)
+	layers := []EnvLayer{}
+	addFile := func(name string, override bool) error {
+		path := name
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(root, name)
+		}
+		values, err := godotenv.Read(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return util.NewReadableError(err, fmt.Sprintf("Could not read %s: %s", name, err.Error())).WithCode("env_file_invalid")
+		}
+		layers = append(layers, newEnvLayer(name, path, override, values))
+		return nil
+	}
+
+	for _, name := range []string{".env", ".env.local"} {
+		if err := addFile(name, false); err != nil {
+			return nil, err
+		}
+	}
+	if profile != nil {
+		for _, name := range profile.EnvFiles {
+			if err := addFile(name, false); err != nil {
+				return nil, err
+			}
+		}
+		if values := profile.env(); len(values) > 0 {
+			layers = append(layers, newEnvLayer("profile "+profileName, "", true, values))
+		}
+	}
+	for _, name := range []string{".env." + stage, ".env." + stage + ".local"} {
+		if err := addFile(name, true); err != nil {
+			return nil, err
+		}
+	}
+	return layers, nil
+}
+
+func newEnvLayer(name, path string, override bool, values map[string]string) EnvLayer {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return EnvLayer{
+		Name:     name,
+		Path:     path,
+		Override: override,
+		Keys:     keys,
+		Values:   values,
+	}
+}
+
+// EnvFileStage returns the SST_STAGE set in the env files that don't depend on
+// the stage, and where it's set.
+func EnvFileStage(cfgPath string) (string, string) {
+	stage, source := "", ""
+	for _, name := range []string{".env", ".env.local"} {
+		values, err := godotenv.Read(filepath.Join(filepath.Dir(cfgPath), name))
+		if err != nil {
+			continue
+		}
+		if value := values["SST_STAGE"]; value != "" {
+			stage, source = value, "SST_STAGE in "+name
+		}
+	}
+	return stage, source
+}
+
+// ResolveEnv returns the variables to set from the layers, and the layer that
+// each variable comes from. Variables that are set in the shell are only
+// replaced by the layers that override it.
+func ResolveEnv(layers []EnvLayer, shell map[string]bool) (map[string]string, map[string]string) {
+	values := map[string]string{}
+	sources := map[string]string{}
+	for _, layer := range layers {
+		for _, key := range layer.Keys {
+			if shell[key] && !layer.Override {
+				continue
+			}
+			values[key] = layer.Values[key]
+			sources[key] = layer.Name
+		}
+	}
+	return values, sources
+}
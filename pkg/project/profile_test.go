@@ -0,0 +1,117 @@
+package project
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/sst/sst/v3/internal/util"
+)
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLoadActiveProfile(t *testing.T) {
+	t.Setenv("SST_PROFILE", "")
+	root := t.TempDir()
+	cfgPath := filepath.Join(root, "sst.config.ts")
+	writeFiles(t, root, map[string]string{
+		ProfilesFile: `{"dev": {"stage": "frank", "awsProfile": "acme-dev"}, "staging": {"stage": "staging"}}`,
+	})
+
+	if name, profile, err := LoadActiveProfile(cfgPath); err != nil || name != "" || profile != nil {
+		t.Fatalf("expected no profile, got %q %v %v", name, profile, err)
+	}
+	if err := SetActiveProfile(cfgPath, "dev"); err != nil {
+		t.Fatal(err)
+	}
+	name, profile, err := LoadActiveProfile(cfgPath)
+	if err != nil || name != "dev" || profile.Stage != "frank" || profile.AwsProfile != "acme-dev" {
+		t.Fatalf("got %q %v %v", name, profile, err)
+	}
+
+	t.Setenv("SST_PROFILE", "staging")
+	if name, _, _ := LoadActiveProfile(cfgPath); name != "staging" {
+		t.Fatalf("SST_PROFILE should take precedence, got %q", name)
+	}
+
+	t.Setenv("SST_PROFILE", "missing")
+	_, _, err = LoadActiveProfile(cfgPath)
+	readable, ok := err.(*util.ReadableError)
+	if !ok || readable.Code() != "profile_not_found" {
+		t.Fatalf("expected profile_not_found, got %v", err)
+	}
+
+	t.Setenv("SST_PROFILE", "")
+	if err := SetActiveProfile(cfgPath, ""); err != nil {
+		t.Fatal(err)
+	}
+	if name, _, _ := LoadActiveProfile(cfgPath); name != "" {
+		t.Fatalf("expected the profile to be cleared, got %q", name)
+	}
+}
+
+func TestEnvLayers(t *testing.T) {
+	root := t.TempDir()
+	cfgPath := filepath.Join(root, "sst.config.ts")
+	writeFiles(t, root, map[string]string{
+		".env":             "A=env\nB=env\nC=env\n",
+		".env.shared":      "B=shared\n",
+		".env.prod":        "C=prod\nAWS_PROFILE=from-stage-file\n",
+		".env.prod.local":  "C=prod-local\n",
+		".env.other-stage": "A=other\n",
+	})
+	profile := &Profile{AwsProfile: "acme", Region: "eu-west-1", EnvFiles: []string{".env.shared", ".env.missing"}}
+	layers, err := EnvLayers(cfgPath, "prod", "staging", profile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for _, layer := range layers {
+		names = append(names, layer.Name)
+	}
+	want := []string{".env", ".env.shared", "profile staging", ".env.prod", ".env.prod.local"}
+	if !reflect.DeepEqual(names, want) {
+		t.Fatalf("got %v, want %v", names, want)
+	}
+
+	values, sources := ResolveEnv(layers, map[string]bool{"A": true, "C": true, "AWS_REGION": true})
+	wantValues := map[string]string{
+		"B":           "shared",
+		"C":           "prod-local",
+		"AWS_PROFILE": "from-stage-file",
+		"AWS_REGION":  "eu-west-1",
+	}
+	if !reflect.DeepEqual(values, wantValues) {
+		t.Fatalf("got %v, want %v", values, wantValues)
+	}
+	if sources["B"] != ".env.shared" || sources["AWS_REGION"] != "profile staging" || sources["C"] != ".env.prod.local" {
+		t.Errorf("unexpected sources %v", sources)
+	}
+	if _, ok := sources["A"]; ok {
+		t.Errorf("A is set in the shell and should not be replaced")
+	}
+}
+
+func TestEnvFileStage(t *testing.T) {
+	root := t.TempDir()
+	cfgPath := filepath.Join(root, "sst.config.ts")
+	if stage, source := EnvFileStage(cfgPath); stage != "" || source != "" {
+		t.Fatalf("expected no stage, got %q from %q", stage, source)
+	}
+	writeFiles(t, root, map[string]string{".env": "SST_STAGE=shared\n"})
+	if stage, source := EnvFileStage(cfgPath); stage != "shared" || source != "SST_STAGE in .env" {
+		t.Fatalf("got %q from %q", stage, source)
+	}
+	writeFiles(t, root, map[string]string{".env.local": "SST_STAGE=mine\n"})
+	if stage, source := EnvFileStage(cfgPath); stage != "mine" || source != "SST_STAGE in .env.local" {
+		t.Fatalf("got %q from %q", stage, source)
+	}
+}
@@ -35,6 +35,7 @@ type App struct {
 	Home      string                 `json:"home"`
 	Version   string                 `json:"version"`
 	Protect   bool                   `json:"protect"`
+	Approval  *ApprovalPolicy        `json:"approval"`
 	Watch     []string               `json:"watch"`
 	// Deprecated: Backend is now Home
 	Backend string `json:"backend"`
@@ -51,6 +52,8 @@ type Project struct {
 	home            provider.Home
 	env             map[string]string
 	loadedProviders map[string]provider.Provider
+	commonErrors    []CommonError
+	resume          *resumeState
 	Runtime         *runtime.Collection
 }
 
@@ -86,6 +89,9 @@ type ProjectConfig struct {
 	Version string
 	Stage   string
 	Config  string
+	// AllowUnversioned loads providers that are set to true or are missing a
+	// version, so `sst add --upgrade` can set their version.
+	AllowUnversioned bool
 }
 
 var ErrInvalidStageName = fmt.Errorf("ErrInvalidStageName")
@@ -207,9 +213,13 @@ console.log("~j" + JSON.stringify(await mod.app({
 
 			for name, args := range proj.app.Providers {
 				if _, ok := args.(bool); ok {
+					if input.AllowUnversioned {
+						proj.app.Providers[name] = map[string]interface{}{}
+						continue
+					}
 					return nil, util.NewReadableError(nil,
 						fmt.Sprintf(`Setting providers.%s to true is deprecated. Specify the version explicitly instead.`, name),
-					)
+					).WithCode("provider_version_missing")
 				}
 
 				if argsString, ok := args.(string); ok {
@@ -219,10 +229,10 @@ console.log("~j" + JSON.stringify(await mod.app({
 				}
 
 				if argsMap, ok := args.(map[string]interface{}); ok {
-					if _, hasVersion := argsMap["version"]; !hasVersion && name != "aws" && name != "cloudflare" {
+					if _, hasVersion := argsMap["version"]; !hasVersion && name != "aws" && name != "cloudflare" && !input.AllowUnversioned {
 						return nil, util.NewReadableError(nil,
 							fmt.Sprintf(`Provider %s is missing a version. Specify the version explicitly instead.`, name),
-						)
+						).WithCode("provider_version_missing")
 					}
 				}
 			}
@@ -253,7 +263,7 @@ console.log("~j" + JSON.stringify(await mod.app({
 			}
 
 			if proj.app.Home == "" {
-				return nil, util.NewReadableError(nil, `You must specify a "home" provider in the project configuration file.`)
+				return nil, util.NewReadableError(nil, `You must specify a "home" provider in the project configuration file.`).WithCode("home_missing")
 			}
 
 			if _, ok := proj.app.Providers[proj.app.Home]; !ok && proj.app.Home != "local" {
@@ -261,7 +271,7 @@ console.log("~j" + JSON.stringify(await mod.app({
 			}
 
 			if proj.app.RemovalPolicy != "" {
-				return nil, util.NewReadableError(nil, `The "removalPolicy" has been renamed to "removal"`)
+				return nil, util.NewReadableError(nil, `The "removalPolicy" has been renamed to "removal"`).WithCode("removal_policy_renamed")
 			}
 
 			if proj.app.Removal == "" {
@@ -297,6 +307,11 @@ console.log("~j" + JSON.stringify(await mod.app({
 		return nil, err
 	}
 
+	proj.commonErrors, err = LoadCommonErrors(proj.PathRoot())
+	if err != nil {
+		return nil, err
+	}
+
 	return proj, nil
 }
 
@@ -305,20 +320,13 @@ func (proj *Project) LoadHome() error {
 	loadedProviders := make(map[string]provider.Provider)
 
 	for key, args := range proj.app.Providers {
-		var match provider.Provider
-		switch key {
-		case "cloudflare":
-			match = &provider.CloudflareProvider{}
-		case "aws":
-			match = provider.NewAwsProvider()
+		match, err := provider.Init(key, proj.app.Name, proj.app.Stage, args)
+		if err != nil {
+			return util.NewReadableError(err, key+": "+err.Error()).WithCode("provider_init_failed")
 		}
 		if match == nil {
 			continue
 		}
-		err := match.Init(proj.app.Name, proj.app.Stage, args.(map[string]interface{}))
-		if err != nil {
-			return util.NewReadableError(err, key+": "+err.Error())
-		}
 		env, err := match.Env()
 		if err != nil {
 			return err
@@ -329,20 +337,12 @@ func (proj *Project) LoadHome() error {
 		loadedProviders[key] = match
 	}
 
-	var home provider.Home
-
-	switch proj.app.Home {
-	case "local":
-		home = provider.NewLocalHome()
-	case "aws":
-		home = provider.NewAwsHome(loadedProviders["aws"].(*provider.AwsProvider))
-	case "cloudflare":
-		home = provider.NewCloudflareHome(loadedProviders["cloudflare"].(*provider.CloudflareProvider))
-	default:
-		return fmt.Errorf("Home provider %s is invalid", proj.app.Home)
+	home, err := provider.NewHome(proj.app.Home, loadedProviders)
+	if err != nil {
+		return util.NewReadableError(err, err.Error()).WithCode("home_init_failed")
 	}
 
-	err := home.Bootstrap()
+	err = home.Bootstrap()
 	if err != nil {
 		return fmt.Errorf("Error initializing %s:\n   %w", proj.app.Home, err)
 	}
@@ -39,6 +39,20 @@ type AwsProvider struct {
 
 var ErrBucketMissing = errors.New("sst state bucket missing")
 
+func init() {
+	Register("aws", Factory{
+		Provider: func() Provider { return NewAwsProvider() },
+		Args:     []string{"profile", "region"},
+		Home: func(providers map[string]Provider) (Home, error) {
+			aws, err := requireProvider[*AwsProvider](providers, "aws")
+			if err != nil {
+				return nil, err
+			}
+			return NewAwsHome(aws), nil
+		},
+	})
+}
+
 func NewAwsProvider() *AwsProvider {
 	return &AwsProvider{
 		bootstrapCache: map[string]*AwsBootstrapData{},
@@ -92,6 +106,10 @@ func (a *AwsProvider) Init(app string, stage string, args map[string]interface{}
 	if err != nil {
 		return err
 	}
+	// the role of the profile in use, the roles in the config are assumed with it
+	if role := os.Getenv("SST_AWS_ROLE_ARN"); role != "" {
+		cfg.Credentials = stscreds.NewAssumeRoleProvider(sts.NewFromConfig(cfg), role)
+	}
 	if assumeRoles, ok := args["assumeRoles"].([]interface{}); ok {
 		for _, role := range assumeRoles {
 			if roleMap, ok := role.(map[string]interface{}); ok {
@@ -26,6 +26,20 @@ type CloudflareProvider struct {
 
 var ErrCloudflareMissingAccount = fmt.Errorf("missing account")
 
+func init() {
+	Register("cloudflare", Factory{
+		Provider: func() Provider { return &CloudflareProvider{} },
+		Args:     []string{"apiToken", "apiKey", "email"},
+		Home: func(providers map[string]Provider) (Home, error) {
+			cloudflare, err := requireProvider[*CloudflareProvider](providers, "cloudflare")
+			if err != nil {
+				return nil, err
+			}
+			return NewCloudflareHome(cloudflare), nil
+		},
+	})
+}
+
 func (c *CloudflareProvider) Env() (map[string]string, error) {
 	return map[string]string{
 		"CLOUDFLARE_DEFAULT_ACCOUNT_ID": c.defaultAccountId,
@@ -53,7 +67,7 @@ func (c *CloudflareProvider) Init(app, stage string, args map[string]interface{}
 		api, _ = cloudflare.New(apiKey, email)
 	}
 	if api == nil {
-		return util.NewReadableError(nil, "Cloudflare API not initialized. Please provide CLOUDFLARE_API_TOKEN or CLOUDFLARE_API_KEY and CLOUDFLARE_EMAIL environment variables or in the provider section of the project configuration file.")
+		return util.NewReadableError(nil, "Cloudflare API not initialized. Please provide CLOUDFLARE_API_TOKEN or CLOUDFLARE_API_KEY and CLOUDFLARE_EMAIL environment variables or in the provider section of the project configuration file.").WithCode("cloudflare_not_initialized")
 	}
 	c.api = api
 	accountID := os.Getenv("CLOUDFLARE_DEFAULT_ACCOUNT_ID")
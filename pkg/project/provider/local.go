@@ -2,76 +2,234 @@ package provider
 
 import (
 	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
 	"fmt"
 	"io"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/sst/sst/v3/internal/util"
 	"github.com/sst/sst/v3/pkg/global"
 )
 
+const (
+	// snapshots and event logs newer than this are kept
+	localRetention = 30 * 24 * time.Hour
+	// this many of the newest snapshots and event logs are always kept
+	localRetentionCount = 10
+	// prefix of passphrases that are encrypted with the user key
+	localPassphrasePrefix = "sst:v1:"
+)
+
 type LocalHome struct {
+	// dir defaults to the global config directory
+	dir string
+
+	mu    sync.Mutex
+	locks map[string]*os.File
+}
+
+func init() {
+	Register("local", Factory{
+		Home: func(providers map[string]Provider) (Home, error) {
+			return NewLocalHome(), nil
+		},
+	})
 }
 
 func NewLocalHome() *LocalHome {
-	return &LocalHome{}
+	return &LocalHome{
+		dir: global.ConfigDir(),
+	}
 }
 
 func (l *LocalHome) Bootstrap() error {
 	return nil
 }
 
+func (l *LocalHome) root() string {
+	if l.dir == "" {
+		return global.ConfigDir()
+	}
+	return l.dir
+}
+
+// cleanup removes everything stored under key for a stage.
 func (l *LocalHome) cleanup(key, app, stage string) error {
-	return nil
+	return os.RemoveAll(filepath.Join(l.root(), "state", key, app, stage))
 }
 
 func (l *LocalHome) getData(key, app, stage string) (io.Reader, error) {
 	p := l.pathForData(key, app, stage)
-	result, err := os.Open(p)
+	// read it all so no handle is left open, which would block the rename in
+	// putData on Windows
+	result, err := os.ReadFile(p)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return nil, nil
 		}
 		return nil, err
 	}
-	return result, nil
+	return bytes.NewReader(result), nil
 }
 
+// putData writes to a temporary file next to the destination and renames it
+// over the destination, so a crash or a concurrent reader never sees a
+// partially written file.
 func (l *LocalHome) putData(key, app, stage string, data io.Reader) error {
 	if key == "summary" {
 		return nil
 	}
 	p := l.pathForData(key, app, stage)
-	err := os.MkdirAll(filepath.Dir(p), 0755)
+	err := writeAtomic(p, data)
 	if err != nil {
 		return err
 	}
-	file, err := os.Create(p)
+	if key == "snapshot" || key == "eventlog" {
+		if err := prune(filepath.Dir(p), time.Now()); err != nil {
+			slog.Warn("failed to clean up old files", "key", key, "app", app, "stage", stage, "err", err)
+		}
+	}
+	return nil
+}
+
+func writeAtomic(path string, data io.Reader) error {
+	err := os.MkdirAll(filepath.Dir(path), 0755)
 	if err != nil {
 		return err
 	}
-	defer file.Close()
+	file, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
 	_, err = io.Copy(file, data)
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
 	if err != nil {
 		return err
 	}
+	return os.Rename(file.Name(), path)
+}
+
+// prune removes files in dir that are older than localRetention, except for
+// the localRetentionCount newest ones.
+func prune(dir string, now time.Time) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	type file struct {
+		name     string
+		modified time.Time
+	}
+	files := []file{}
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, file{entry.Name(), info.ModTime()})
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modified.After(files[j].modified)
+	})
+	for index, item := range files {
+		if index < localRetentionCount || now.Sub(item.modified) < localRetention {
+			continue
+		}
+		slog.Info("removing old file", "path", filepath.Join(dir, item.name))
+		if err := os.Remove(filepath.Join(dir, item.name)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
 	return nil
 }
 
 func (l *LocalHome) removeData(key, app, stage string) error {
 	p := l.pathForData(key, app, stage)
-	return os.Remove(p)
+	err := os.Remove(p)
+	if key == "lock" {
+		l.unlockFile(app, stage)
+	}
+	return err
+}
+
+// lock takes an advisory lock on the stage that is held until unlockFile is
+// called or the process exits, so two local processes can't both pass the
+// check for an existing lock.
+func (l *LocalHome) lock(app, stage string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	key := app + "/" + stage
+	if _, ok := l.locks[key]; ok {
+		return ErrLockExists
+	}
+	p := strings.TrimSuffix(l.pathForData("lock", app, stage), ".json") + ".flock"
+	err := os.MkdirAll(filepath.Dir(p), 0755)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(p, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	err = lockFD(file)
+	if err != nil {
+		file.Close()
+		if err == errLocked {
+			return ErrLockExists
+		}
+		return err
+	}
+	if l.locks == nil {
+		l.locks = map[string]*os.File{}
+	}
+	l.locks[key] = file
+	return nil
 }
 
-// these should go into secrets manager once it's out of beta
-func (c *LocalHome) setPassphrase(app, stage string, passphrase string) error {
-	return c.putData("passphrase", app, stage, bytes.NewReader([]byte(passphrase)))
+func (l *LocalHome) unlockFile(app, stage string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	key := app + "/" + stage
+	file, ok := l.locks[key]
+	if !ok {
+		return
+	}
+	unlockFD(file)
+	file.Close()
+	delete(l.locks, key)
 }
 
-func (c *LocalHome) getPassphrase(app, stage string) (string, error) {
-	data, err := c.getData("passphrase", app, stage)
+// The passphrase is encrypted with a key that is kept in the config directory
+// of the user, so the state directory alone is not enough to decrypt secrets.
+// Losing this key makes the state of local stages unrecoverable.
+func (l *LocalHome) setPassphrase(app, stage string, passphrase string) error {
+	encrypted, err := l.encrypt([]byte(passphrase))
+	if err != nil {
+		return err
+	}
+	return l.putData("passphrase", app, stage, bytes.NewReader([]byte(localPassphrasePrefix+encrypted)))
+}
+
+func (l *LocalHome) getPassphrase(app, stage string) (string, error) {
+	data, err := l.getData("passphrase", app, stage)
 	if err != nil {
 		return "", err
 	}
@@ -82,15 +240,109 @@ func (c *LocalHome) getPassphrase(app, stage string) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	return string(read), nil
+	value := string(read)
+	if !strings.HasPrefix(value, localPassphrasePrefix) {
+		// stored in plain text by an older version
+		slog.Info("encrypting passphrase", "app", app, "stage", stage)
+		if err := l.setPassphrase(app, stage, value); err != nil {
+			return "", err
+		}
+		return value, nil
+	}
+	decrypted, err := l.decrypt(strings.TrimPrefix(value, localPassphrasePrefix))
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt the passphrase of %s/%s with %s: %w", app, stage, l.pathForKey(), err)
+	}
+	return string(decrypted), nil
+}
+
+func (l *LocalHome) pathForKey() string {
+	return filepath.Join(l.root(), "local.key")
+}
+
+// userKey reads the key that passphrases are encrypted with, creating it the
+// first time.
+func (l *LocalHome) userKey() ([]byte, error) {
+	path := l.pathForKey()
+	data, err := os.ReadFile(path)
+	if err == nil {
+		return base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return nil, err
+	}
+	// O_EXCL so a key created by another process at the same time wins
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if os.IsExist(err) {
+		return l.userKey()
+	}
+	if err != nil {
+		return nil, err
+	}
+	_, err = file.WriteString(base64.StdEncoding.EncodeToString(key))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func (l *LocalHome) gcm() (cipher.AEAD, error) {
+	key, err := l.userKey()
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func (l *LocalHome) encrypt(data []byte) (string, error) {
+	gcm, err := l.gcm()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, data, nil)), nil
+}
+
+func (l *LocalHome) decrypt(value string) ([]byte, error) {
+	gcm, err := l.gcm()
+	if err != nil {
+		return nil, err
+	}
+	data, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, fmt.Errorf("ciphertext too short")
+	}
+	nonce, ciphertext := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, ciphertext, nil)
 }
 
 func (l *LocalHome) pathForData(key, app, stage string) string {
-	return filepath.Join(global.ConfigDir(), "state", key, app, fmt.Sprintf("%v.json", stage))
+	return filepath.Join(l.root(), "state", key, app, fmt.Sprintf("%v.json", stage))
 }
 
 func (a *LocalHome) listStages(app string) ([]string, error) {
-	path := filepath.Join(global.ConfigDir(), "state", "app", app)
+	path := filepath.Join(a.root(), "state", "app", app)
 
 	entries, err := os.ReadDir(path)
 	if err != nil {
@@ -113,6 +365,7 @@ func (a *LocalHome) listStages(app string) ([]string, error) {
 func (c *LocalHome) info() (util.KeyValuePairs[string], error) {
 	return util.KeyValuePairs[string]{
 		{Key: "Provider", Value: "Local"},
-		{Key: "Path", Value: global.ConfigDir()},
+		{Key: "Path", Value: c.root()},
+		{Key: "Key", Value: c.pathForKey()},
 	}, nil
 }
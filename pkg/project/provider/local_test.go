@@ -0,0 +1,226 @@
+package provider
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLocalPutData(t *testing.T) {
+	home := &LocalHome{dir: t.TempDir()}
+	for _, value := range []string{"first", "second"} {
+		if err := home.putData("app", "app", "dev", strings.NewReader(value)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	data, err := os.ReadFile(home.pathForData("app", "app", "dev"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "second" {
+		t.Fatalf("got %q", data)
+	}
+	entries, err := os.ReadDir(filepath.Dir(home.pathForData("app", "app", "dev")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("temporary files were left behind: %v", entries)
+	}
+}
+
+func TestLocalPassphrase(t *testing.T) {
+	home := &LocalHome{dir: t.TempDir()}
+	if err := home.setPassphrase("app", "dev", "secret"); err != nil {
+		t.Fatal(err)
+	}
+	stored, err := os.ReadFile(home.pathForData("passphrase", "app", "dev"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(stored), localPassphrasePrefix) || strings.Contains(string(stored), "secret") {
+		t.Fatalf("passphrase is not encrypted: %s", stored)
+	}
+	passphrase, err := home.getPassphrase("app", "dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if passphrase != "secret" {
+		t.Fatalf("got %q", passphrase)
+	}
+
+	// plain text from an older version is encrypted when read
+	if err := home.putData("passphrase", "app", "old", bytes.NewReader([]byte("legacy"))); err != nil {
+		t.Fatal(err)
+	}
+	passphrase, err = home.getPassphrase("app", "old")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if passphrase != "legacy" {
+		t.Fatalf("got %q", passphrase)
+	}
+	stored, err = os.ReadFile(home.pathForData("passphrase", "app", "old"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(stored), localPassphrasePrefix) {
+		t.Fatalf("legacy passphrase was not migrated: %s", stored)
+	}
+
+	other := &LocalHome{dir: t.TempDir()}
+	if err := other.putData("passphrase", "app", "dev", bytes.NewReader(stored)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := other.getPassphrase("app", "dev"); err == nil {
+		t.Fatal("expected decrypting with another key to fail")
+	}
+}
+
+func TestPrune(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	total := localRetentionCount + 5
+	for i := 0; i < total; i++ {
+		path := filepath.Join(dir, fmt.Sprintf("%02d.json", i))
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+		// the first two are recent, the rest are past the retention
+		modified := now.Add(-time.Duration(i) * time.Hour)
+		if i >= 2 {
+			modified = now.Add(-localRetention - time.Duration(i)*time.Hour)
+		}
+		if err := os.Chtimes(path, modified, modified); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := prune(dir, now); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != localRetentionCount {
+		t.Fatalf("kept %d files", len(entries))
+	}
+	if entries[len(entries)-1].Name() != fmt.Sprintf("%02d.json", localRetentionCount-1) {
+		t.Fatalf("the oldest files should be removed, kept %s", entries[len(entries)-1].Name())
+	}
+}
+
+func TestLocalLock(t *testing.T) {
+	dir := t.TempDir()
+	first := &LocalHome{dir: dir}
+	second := &LocalHome{dir: dir}
+	if err := first.lock("app", "dev"); err != nil {
+		t.Fatal(err)
+	}
+	if err := second.lock("app", "dev"); !errors.Is(err, ErrLockExists) {
+		t.Fatalf("expected ErrLockExists, got %v", err)
+	}
+	if err := second.lock("app", "prod"); err != nil {
+		t.Fatal(err)
+	}
+	first.unlockFile("app", "dev")
+	if err := second.lock("app", "dev"); err != nil {
+		t.Fatalf("lock was not released: %v", err)
+	}
+}
+
+func TestResume(t *testing.T) {
+	home := &LocalHome{dir: t.TempDir()}
+	resume, err := GetResume(home, "app", "dev")
+	if err != nil || resume != nil {
+		t.Fatalf("expected no resume, got %v %v", resume, err)
+	}
+	update := &Update{
+		ID:        "01",
+		Command:   "deploy",
+		Resources: []UpdateResource{{URN: "urn:bucket", Op: "create", Status: ResourceFailed}},
+	}
+	if err := PutUpdate(home, "app", "dev", update); err != nil {
+		t.Fatal(err)
+	}
+	if err := PutResume(home, "app", "dev", &Resume{UpdateID: "01", Files: map[string]map[string]string{"bucket": {"index.html": "abc"}}}); err != nil {
+		t.Fatal(err)
+	}
+	resume, err = GetResume(home, "app", "dev")
+	if err != nil || resume.UpdateID != "01" || resume.Files["bucket"]["index.html"] != "abc" {
+		t.Fatalf("got %v %v", resume, err)
+	}
+	got, err := GetUpdate(home, "app", "dev", resume.UpdateID)
+	if err != nil || len(got.Resources) != 1 || got.Resources[0].Status != ResourceFailed {
+		t.Fatalf("got %v %v", got, err)
+	}
+	if missing, err := GetUpdate(home, "app", "dev", "02"); err != nil || missing != nil {
+		t.Fatalf("got %v %v", missing, err)
+	}
+	if err := RemoveResume(home, "app", "dev"); err != nil {
+		t.Fatal(err)
+	}
+	if resume, _ := GetResume(home, "app", "dev"); resume != nil {
+		t.Fatal("expected the resume to be removed")
+	}
+}
+
+func TestStage(t *testing.T) {
+	home := &LocalHome{dir: t.TempDir()}
+	stage, err := GetStage(home, "app", "dev")
+	if err != nil || stage != nil {
+		t.Fatalf("expected no stage, got %v %v", stage, err)
+	}
+	if err := PutStage(home, "app", "dev", &Stage{UpdateID: "01", Resources: 3, Expires: "2026-01-01T00:00:00Z"}); err != nil {
+		t.Fatal(err)
+	}
+	stage, err = GetStage(home, "app", "dev")
+	if err != nil || stage.UpdateID != "01" || stage.Resources != 3 || stage.Expires != "2026-01-01T00:00:00Z" {
+		t.Fatalf("got %v %v", stage, err)
+	}
+	if err := RemoveStage(home, "app", "dev"); err != nil {
+		t.Fatal(err)
+	}
+	if stage, _ := GetStage(home, "app", "dev"); stage != nil {
+		t.Fatal("expected the stage to be removed")
+	}
+}
+
+func TestGetLock(t *testing.T) {
+	home := &LocalHome{dir: t.TempDir()}
+	if lock, err := GetLock(home, "app", "dev"); err != nil || lock != nil {
+		t.Fatalf("expected no lock, got %v %v", lock, err)
+	}
+	update, err := Lock(home, "3.0.0", "deploy", "app", "dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Unlock(home, "3.0.0", "app", "dev")
+	lock, err := GetLock(home, "app", "dev")
+	if err != nil || lock == nil || lock.UpdateID != update.ID || lock.Command != "deploy" {
+		t.Fatalf("got %v %v", lock, err)
+	}
+}
+
+func TestRemoveUpdate(t *testing.T) {
+	home := &LocalHome{dir: t.TempDir()}
+	update, err := Lock(home, "3.0.0", "deploy", "app", "dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Unlock(home, "3.0.0", "app", "dev")
+	if stored, err := GetUpdate(home, "app", "dev", update.ID); err != nil || stored == nil {
+		t.Fatalf("expected the update to be stored, got %v %v", stored, err)
+	}
+	if err := RemoveUpdate(home, "app", "dev", update.ID); err != nil {
+		t.Fatal(err)
+	}
+	if stored, err := GetUpdate(home, "app", "dev", update.ID); err != nil || stored != nil {
+		t.Fatalf("expected the update to be removed, got %v %v", stored, err)
+	}
+}
@@ -145,6 +145,65 @@ type Update struct {
 	Errors        []SummaryError `json:"errors"`
 	TimeStarted   string         `json:"timeStarted"`
 	TimeCompleted string         `json:"timeCompleted,omitempty"`
+	// Resources is the outcome of each resource that changed.
+	Resources []UpdateResource `json:"resources,omitempty"`
+	// Resumed is the update that this one resumed with `sst deploy --resume`.
+	Resumed string `json:"resumed,omitempty"`
+}
+
+const (
+	ResourceDone    = "done"
+	ResourceFailed  = "failed"
+	ResourcePending = "pending"
+)
+
+type UpdateResource struct {
+	URN    string `json:"urn"`
+	Type   string `json:"type"`
+	Op     string `json:"op"`
+	Status string `json:"status"`
+}
+
+// Resume is kept while the last deploy of a stage did not complete, it's
+// what `sst deploy --resume` continues from.
+type Resume struct {
+	UpdateID string `json:"updateID"`
+	// Files are the hashes of the files that were uploaded to each bucket,
+	// by key.
+	Files map[string]map[string]string `json:"files,omitempty"`
+}
+
+// Stage is updated at the end of every update of a stage, it's what
+// `sst stage` uses to list and clean up stages.
+type Stage struct {
+	// UpdateID is the last update of the stage.
+	UpdateID  string `json:"updateID"`
+	Resources int    `json:"resources"`
+	Protected bool   `json:"protected"`
+	// Expires is when the stage can be removed by `sst stage gc`, it's not set
+	// if the stage does not expire.
+	Expires string `json:"expires,omitempty"`
+}
+
+func GetStage(backend Home, app, stage string) (*Stage, error) {
+	var result Stage
+	err := getData(backend, "stage", app, stage, false, &result)
+	if err != nil {
+		return nil, err
+	}
+	if result.UpdateID == "" {
+		return nil, nil
+	}
+	return &result, nil
+}
+
+func PutStage(backend Home, app, stage string, data *Stage) error {
+	slog.Info("putting stage", "app", app, "stage", stage)
+	return putData(backend, "stage", app, stage, false, data)
+}
+
+func RemoveStage(backend Home, app, stage string) error {
+	return removeData(backend, "stage", app, stage)
 }
 
 func PutSummary(backend Home, app, stage, updateID string, summary Summary) error {
@@ -158,6 +217,45 @@ func PutUpdate(backend Home, app, stage string, update *Update) error {
 	return putData(backend, "update", app, stage+"/"+update.ID, false, update)
 }
 
+// RemoveUpdate removes an update that was started but didn't change anything,
+// like a deploy whose changes weren't approved.
+func RemoveUpdate(backend Home, app, stage, updateID string) error {
+	return removeData(backend, "update", app, stage+"/"+updateID)
+}
+
+func GetUpdate(backend Home, app, stage, updateID string) (*Update, error) {
+	var update Update
+	err := getData(backend, "update", app, stage+"/"+updateID, false, &update)
+	if err != nil {
+		return nil, err
+	}
+	if update.ID == "" {
+		return nil, nil
+	}
+	return &update, nil
+}
+
+func GetResume(backend Home, app, stage string) (*Resume, error) {
+	var resume Resume
+	err := getData(backend, "resume", app, stage, false, &resume)
+	if err != nil {
+		return nil, err
+	}
+	if resume.UpdateID == "" {
+		return nil, nil
+	}
+	return &resume, nil
+}
+
+func PutResume(backend Home, app, stage string, resume *Resume) error {
+	slog.Info("putting resume", "app", app, "stage", stage, "updateID", resume.UpdateID)
+	return putData(backend, "resume", app, stage, false, resume)
+}
+
+func RemoveResume(backend Home, app, stage string) error {
+	return removeData(backend, "resume", app, stage)
+}
+
 func Cleanup(backend Home, app, stage string) error {
 	if err := backend.cleanup("eventlog", app, stage); err != nil {
 		return err
@@ -237,7 +335,7 @@ func PullState(backend Home, app, stage string, out string) error {
 	return nil
 }
 
-type lockData struct {
+type LockInfo struct {
 	Created  time.Time `json:"created"`
 	UpdateID string    `json:"updateID"`
 	RunID    string    `json:"runID"`
@@ -245,11 +343,28 @@ type lockData struct {
 	Ignore   bool      `json:"ignore"`
 }
 
-func Lock(backend Home, version, command, app, stage string) (*Update, error) {
+// fileLocker is implemented by homes that also hold an advisory lock while
+// the stage is locked, which is released when the process exits.
+type fileLocker interface {
+	lock(app, stage string) error
+	unlockFile(app, stage string)
+}
+
+func Lock(backend Home, version, command, app, stage string) (update *Update, err error) {
 	updateID := id.Descending()
 	slog.Info("locking", "app", app, "stage", stage)
-	var lockData lockData
-	err := getData(backend, "lock", app, stage, false, &lockData)
+	if locker, ok := backend.(fileLocker); ok {
+		if err := locker.lock(app, stage); err != nil {
+			return nil, err
+		}
+		defer func() {
+			if err != nil {
+				locker.unlockFile(app, stage)
+			}
+		}()
+	}
+	var lockData LockInfo
+	err = getData(backend, "lock", app, stage, false, &lockData)
 	if err != nil {
 		return nil, err
 	}
@@ -266,7 +381,7 @@ func Lock(backend Home, version, command, app, stage string) (*Update, error) {
 		return nil, err
 	}
 
-	update := &Update{
+	update = &Update{
 		ID:          updateID,
 		Version:     version,
 		Command:     command,
@@ -281,6 +396,19 @@ func Lock(backend Home, version, command, app, stage string) (*Update, error) {
 	return update, nil
 }
 
+// GetLock returns the lock of a stage, or nil if it's not locked.
+func GetLock(backend Home, app, stage string) (*LockInfo, error) {
+	var lock LockInfo
+	err := getData(backend, "lock", app, stage, false, &lock)
+	if err != nil {
+		return nil, err
+	}
+	if lock.Created.IsZero() {
+		return nil, nil
+	}
+	return &lock, nil
+}
+
 func Unlock(backend Home, version, app, stage string) error {
 	slog.Info("unlocking", "app", app, "stage", stage)
 	return removeData(backend, "lock", app, stage)
@@ -288,7 +416,7 @@ func Unlock(backend Home, version, app, stage string) error {
 
 func ForceUnlock(backend Home, version, app, stage string) error {
 	slog.Info("force unlocking", "app", app, "stage", stage)
-	var lockData lockData
+	var lockData LockInfo
 	err := getData(backend, "lock", app, stage, false, &lockData)
 	if err != nil {
 		return err
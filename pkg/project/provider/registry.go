@@ -0,0 +1,127 @@
+package provider
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Factory describes what a registered name can be used as. A name can be a
+// provider, a home, or both.
+type Factory struct {
+	// Provider creates an uninitialized provider.
+	Provider func() Provider
+	// Args are checked before Init is called. Values listed here must be
+	// strings when they are set.
+	Args []string
+	// Validate runs after the Args are checked, for anything more specific.
+	Validate func(args map[string]interface{}) error
+	// Home creates the home from the providers that were initialized.
+	Home func(providers map[string]Provider) (Home, error)
+}
+
+var registry = struct {
+	sync.RWMutex
+	factories map[string]Factory
+}{
+	factories: map[string]Factory{},
+}
+
+// Register makes a provider or home available under name. It panics if the
+// name is registered twice, like database/sql.Register.
+func Register(name string, factory Factory) {
+	registry.Lock()
+	defer registry.Unlock()
+	if factory.Provider == nil && factory.Home == nil {
+		panic("provider: Register " + name + " without a provider or home")
+	}
+	if _, ok := registry.factories[name]; ok {
+		panic("provider: Register called twice for " + name)
+	}
+	registry.factories[name] = factory
+}
+
+func lookup(name string) (Factory, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+	factory, ok := registry.factories[name]
+	return factory, ok
+}
+
+func registered(filter func(Factory) bool) []string {
+	registry.RLock()
+	defer registry.RUnlock()
+	result := []string{}
+	for name, factory := range registry.factories {
+		if filter(factory) {
+			result = append(result, name)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// Providers lists the names that can be initialized with Init.
+func Providers() []string {
+	return registered(func(factory Factory) bool { return factory.Provider != nil })
+}
+
+// Homes lists the names that can be used as the home of an app.
+func Homes() []string {
+	return registered(func(factory Factory) bool { return factory.Home != nil })
+}
+
+// Init creates and initializes the provider registered under name. It returns
+// nil if there isn't one, since most providers are only used by Pulumi.
+func Init(name, app, stage string, input interface{}) (Provider, error) {
+	factory, ok := lookup(name)
+	if !ok || factory.Provider == nil {
+		return nil, nil
+	}
+	args := map[string]interface{}{}
+	if input != nil {
+		cast, ok := input.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected the provider config to be an object, got %T", input)
+		}
+		args = cast
+	}
+	for _, key := range factory.Args {
+		value, ok := args[key]
+		if !ok || value == nil {
+			continue
+		}
+		if _, ok := value.(string); !ok {
+			return nil, fmt.Errorf("expected \"%s\" to be a string, got %T", key, value)
+		}
+	}
+	if factory.Validate != nil {
+		if err := factory.Validate(args); err != nil {
+			return nil, err
+		}
+	}
+	result := factory.Provider()
+	err := result.Init(app, stage, args)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// NewHome creates the home registered under name.
+func NewHome(name string, providers map[string]Provider) (Home, error) {
+	factory, ok := lookup(name)
+	if !ok || factory.Home == nil {
+		return nil, fmt.Errorf("Home provider %s is invalid, expected one of %v", name, Homes())
+	}
+	return factory.Home(providers)
+}
+
+// requireProvider returns the initialized provider a home is stored in.
+func requireProvider[T Provider](providers map[string]Provider, name string) (T, error) {
+	match, ok := providers[name].(T)
+	if !ok {
+		return match, fmt.Errorf("the %s home needs the %s provider to be configured", name, name)
+	}
+	return match, nil
+}
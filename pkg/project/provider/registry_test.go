@@ -0,0 +1,65 @@
+package provider
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+type testProvider struct {
+	args map[string]interface{}
+}
+
+func (t *testProvider) Init(app, stage string, args map[string]interface{}) error {
+	t.args = args
+	return nil
+}
+
+func (t *testProvider) Env() (map[string]string, error) {
+	return nil, nil
+}
+
+func TestRegistry(t *testing.T) {
+	Register("test", Factory{
+		Provider: func() Provider { return &testProvider{} },
+		Args:     []string{"token"},
+	})
+	defer func() {
+		registry.Lock()
+		delete(registry.factories, "test")
+		registry.Unlock()
+	}()
+
+	if !slices.Contains(Providers(), "test") || slices.Contains(Homes(), "test") {
+		t.Fatalf("unexpected listing %v %v", Providers(), Homes())
+	}
+	for _, name := range []string{"aws", "cloudflare", "local"} {
+		if !slices.Contains(Homes(), name) {
+			t.Fatalf("%s is not a home: %v", name, Homes())
+		}
+	}
+
+	result, err := Init("test", "app", "dev", map[string]interface{}{"token": "abc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.(*testProvider).args["token"] != "abc" {
+		t.Fatalf("args were not passed to Init")
+	}
+	if _, err := Init("test", "app", "dev", map[string]interface{}{"token": 1}); err == nil || !strings.Contains(err.Error(), "token") {
+		t.Fatalf("expected a validation error, got %v", err)
+	}
+	if _, err := Init("test", "app", "dev", "invalid"); err == nil {
+		t.Fatal("expected an error for args that aren't an object")
+	}
+	if result, err := Init("random", "app", "dev", nil); result != nil || err != nil {
+		t.Fatalf("unregistered providers should be skipped, got %v %v", result, err)
+	}
+
+	if _, err := NewHome("aws", map[string]Provider{}); err == nil {
+		t.Fatal("expected the aws home to need the aws provider")
+	}
+	if _, err := NewHome("test", nil); err == nil {
+		t.Fatal("expected an error for a name that isn't a home")
+	}
+}
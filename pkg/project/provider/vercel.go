@@ -0,0 +1,46 @@
+package provider
+
+import (
+	"os"
+)
+
+// VercelProvider passes the credentials set in the provider config to the
+// components that call the Vercel API directly, like the DNS adapter.
+type VercelProvider struct {
+	apiToken string
+	team     string
+}
+
+func init() {
+	Register("vercel", Factory{
+		Provider: func() Provider { return &VercelProvider{} },
+		Args:     []string{"apiToken", "team"},
+	})
+}
+
+func (v *VercelProvider) Init(app, stage string, args map[string]interface{}) error {
+	v.apiToken = os.Getenv("VERCEL_API_TOKEN")
+	v.team = os.Getenv("VERCEL_TEAM_ID")
+	if value, ok := args["apiToken"].(string); ok && value != "" {
+		v.apiToken = value
+	}
+	if value, ok := args["team"].(string); ok && value != "" {
+		v.team = value
+	}
+	return nil
+}
+
+func (v *VercelProvider) Env() (map[string]string, error) {
+	env := map[string]string{}
+	if v.apiToken != "" {
+		env["VERCEL_API_TOKEN"] = v.apiToken
+	}
+	if v.team != "" {
+		env["VERCEL_TEAM_ID"] = v.team
+	}
+	return env, nil
+}
+
+func (v *VercelProvider) ApiToken() string {
+	return v.apiToken
+}
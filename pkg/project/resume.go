@@ -0,0 +1,213 @@
+package project
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/events"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/sst/sst/v3/internal/util"
+	"github.com/sst/sst/v3/pkg/project/provider"
+)
+
+var ErrNothingToResume = fmt.Errorf("nothing to resume")
+
+// ResumeEvent is published when a deploy continues from one that did not
+// complete. Only the targets are deployed, along with their dependents.
+type ResumeEvent struct {
+	UpdateID string
+	Targets  []string
+	// Skipped are the resources that were deployed by the update that's being
+	// resumed.
+	Skipped []string
+}
+
+// outcomes tracks the status of each resource that changes in an update, so
+// a deploy that fails can be resumed.
+type outcomes struct {
+	order     []string
+	resources map[string]*provider.UpdateResource
+}
+
+func newOutcomes() *outcomes {
+	return &outcomes{
+		resources: map[string]*provider.UpdateResource{},
+	}
+}
+
+// pending marks resources as pending until they're deployed, so the targets of
+// a resume that fails again are resumed the next time.
+func (o *outcomes) pending(urns []string) {
+	for _, urn := range urns {
+		o.set(provider.UpdateResource{URN: urn, Status: provider.ResourcePending})
+	}
+}
+
+// planned marks the resources that a preview expects to change as pending,
+// unless they already have an outcome. Resources the deploy never gets to don't
+// have any events, so without this a resume would leave them out.
+func (o *outcomes) planned(steps []apitype.StepEventMetadata) {
+	for _, step := range steps {
+		if _, ok := o.resources[step.URN]; ok || !tracked(step) {
+			continue
+		}
+		o.set(provider.UpdateResource{
+			URN:    step.URN,
+			Type:   step.Type,
+			Op:     string(step.Op),
+			Status: provider.ResourcePending,
+		})
+	}
+}
+
+// tracked reports if a step changes a resource. Components don't get an
+// outputs event when one of their children fails, so only the resources
+// themselves are tracked.
+func tracked(metadata apitype.StepEventMetadata) bool {
+	state := metadata.New
+	if state == nil {
+		state = metadata.Old
+	}
+	return metadata.Op != apitype.OpSame && (state == nil || state.Custom)
+}
+
+func (o *outcomes) set(item provider.UpdateResource) {
+	if existing, ok := o.resources[item.URN]; ok {
+		if item.Type == "" {
+			item.Type = existing.Type
+		}
+		if item.Op == "" {
+			item.Op = existing.Op
+		}
+		*existing = item
+		return
+	}
+	o.order = append(o.order, item.URN)
+	o.resources[item.URN] = &item
+}
+
+func (o *outcomes) handle(event events.EngineEvent) {
+	update := func(metadata apitype.StepEventMetadata, status string) {
+		if !tracked(metadata) {
+			return
+		}
+		o.set(provider.UpdateResource{
+			URN:    metadata.URN,
+			Type:   metadata.Type,
+			Op:     string(metadata.Op),
+			Status: status,
+		})
+	}
+	if event.ResourcePreEvent != nil {
+		update(event.ResourcePreEvent.Metadata, provider.ResourcePending)
+	}
+	if event.ResOutputsEvent != nil {
+		update(event.ResOutputsEvent.Metadata, provider.ResourceDone)
+	}
+	if event.ResOpFailedEvent != nil {
+		update(event.ResOpFailedEvent.Metadata, provider.ResourceFailed)
+	}
+}
+
+func (o *outcomes) list() []provider.UpdateResource {
+	result := []provider.UpdateResource{}
+	for _, urn := range o.order {
+		result = append(result, *o.resources[urn])
+	}
+	return result
+}
+
+// resumeTargets returns the resources of an update that failed or did not
+// finish, and the ones that were deployed. Updates from before the outcomes
+// were recorded fall back to the resources in the errors.
+func resumeTargets(update *provider.Update) (targets []string, skipped []string) {
+	seen := map[string]bool{}
+	for _, item := range update.Resources {
+		if item.Status == provider.ResourceDone {
+			skipped = append(skipped, item.URN)
+			continue
+		}
+		seen[item.URN] = true
+		targets = append(targets, item.URN)
+	}
+	for _, item := range update.Errors {
+		if item.URN == "" || seen[item.URN] || len(update.Resources) > 0 {
+			continue
+		}
+		seen[item.URN] = true
+		targets = append(targets, item.URN)
+	}
+	return targets, skipped
+}
+
+// resumeState holds the files that were uploaded by the deploy that's being
+// resumed, and the ones uploaded by this one.
+type resumeState struct {
+	mu       sync.Mutex
+	previous *provider.Resume
+	files    map[string]map[string]string
+}
+
+func (p *Project) loadResume(resume bool) (*provider.Resume, *provider.Update, error) {
+	p.resume = &resumeState{files: map[string]map[string]string{}}
+	record, err := provider.GetResume(p.home, p.app.Name, p.app.Stage)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !resume {
+		return record, nil, nil
+	}
+	var update *provider.Update
+	if record != nil {
+		update, err = provider.GetUpdate(p.home, p.app.Name, p.app.Stage, record.UpdateID)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	if update == nil {
+		return nil, nil, util.NewReadableError(ErrNothingToResume, "The last deploy of this stage completed, there is nothing to resume. Run `sst deploy` instead.").WithCode("resume_nothing")
+	}
+	p.resume.previous = record
+	for bucket, files := range record.Files {
+		p.resume.files[bucket] = map[string]string{}
+		for key, hash := range files {
+			p.resume.files[bucket][key] = hash
+		}
+	}
+	return record, update, nil
+}
+
+// ResumedFiles returns a fingerprint of the files that the deploy that's being
+// resumed uploaded to a bucket, by key. It's empty if the deploy isn't
+// resuming.
+func (p *Project) ResumedFiles(bucket string) map[string]string {
+	if p.resume == nil || p.resume.previous == nil {
+		return nil
+	}
+	return p.resume.previous.Files[bucket]
+}
+
+// RecordUploadedFile remembers a file that was uploaded to a bucket, in case
+// the deploy fails and is resumed.
+func (p *Project) RecordUploadedFile(bucket, key, fingerprint string) {
+	if p.resume == nil {
+		return
+	}
+	p.resume.mu.Lock()
+	defer p.resume.mu.Unlock()
+	files, ok := p.resume.files[bucket]
+	if !ok {
+		files = map[string]string{}
+		p.resume.files[bucket] = files
+	}
+	files[key] = fingerprint
+}
+
+func (p *Project) saveResume(updateID string) error {
+	p.resume.mu.Lock()
+	defer p.resume.mu.Unlock()
+	return provider.PutResume(p.home, p.app.Name, p.app.Stage, &provider.Resume{
+		UpdateID: updateID,
+		Files:    p.resume.files,
+	})
+}
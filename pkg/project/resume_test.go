@@ -0,0 +1,88 @@
+package project
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/events"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/sst/sst/v3/pkg/project/provider"
+)
+
+func stepMetadata(urn string, op apitype.OpType, custom bool) apitype.StepEventMetadata {
+	return apitype.StepEventMetadata{
+		URN:  urn,
+		Type: "aws:s3/bucket:Bucket",
+		Op:   op,
+		New:  &apitype.StepEventStateMetadata{Custom: custom},
+	}
+}
+
+func TestOutcomes(t *testing.T) {
+	o := newOutcomes()
+	o.pending([]string{"urn:retry"})
+	o.handle(events.EngineEvent{EngineEvent: apitype.EngineEvent{ResourcePreEvent: &apitype.ResourcePreEvent{Metadata: stepMetadata("urn:component", apitype.OpCreate, false)}}})
+	o.handle(events.EngineEvent{EngineEvent: apitype.EngineEvent{ResourcePreEvent: &apitype.ResourcePreEvent{Metadata: stepMetadata("urn:same", apitype.OpSame, true)}}})
+	o.handle(events.EngineEvent{EngineEvent: apitype.EngineEvent{ResourcePreEvent: &apitype.ResourcePreEvent{Metadata: stepMetadata("urn:done", apitype.OpCreate, true)}}})
+	o.handle(events.EngineEvent{EngineEvent: apitype.EngineEvent{ResOutputsEvent: &apitype.ResOutputsEvent{Metadata: stepMetadata("urn:done", apitype.OpCreate, true)}}})
+	o.handle(events.EngineEvent{EngineEvent: apitype.EngineEvent{ResourcePreEvent: &apitype.ResourcePreEvent{Metadata: stepMetadata("urn:failed", apitype.OpUpdate, true)}}})
+	o.handle(events.EngineEvent{EngineEvent: apitype.EngineEvent{ResOpFailedEvent: &apitype.ResOpFailedEvent{Metadata: stepMetadata("urn:failed", apitype.OpUpdate, true)}}})
+	o.handle(events.EngineEvent{EngineEvent: apitype.EngineEvent{ResourcePreEvent: &apitype.ResourcePreEvent{Metadata: stepMetadata("urn:pending", apitype.OpCreate, true)}}})
+
+	got := map[string]string{}
+	for _, item := range o.list() {
+		got[item.URN] = item.Status
+	}
+	expected := map[string]string{
+		"urn:retry":   provider.ResourcePending,
+		"urn:done":    provider.ResourceDone,
+		"urn:failed":  provider.ResourceFailed,
+		"urn:pending": provider.ResourcePending,
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("got %v", got)
+	}
+}
+
+func TestResumeTargets(t *testing.T) {
+	targets, skipped := resumeTargets(&provider.Update{
+		Resources: []provider.UpdateResource{
+			{URN: "urn:done", Status: provider.ResourceDone},
+			{URN: "urn:failed", Status: provider.ResourceFailed},
+			{URN: "urn:pending", Status: provider.ResourcePending},
+		},
+		Errors: []provider.SummaryError{{URN: "urn:failed"}},
+	})
+	if !reflect.DeepEqual(targets, []string{"urn:failed", "urn:pending"}) || !reflect.DeepEqual(skipped, []string{"urn:done"}) {
+		t.Fatalf("got %v %v", targets, skipped)
+	}
+
+	// updates from before the outcomes were recorded
+	targets, skipped = resumeTargets(&provider.Update{
+		Errors: []provider.SummaryError{{URN: "urn:failed"}, {Message: "no resource"}},
+	})
+	if !reflect.DeepEqual(targets, []string{"urn:failed"}) || len(skipped) != 0 {
+		t.Fatalf("got %v %v", targets, skipped)
+	}
+}
+
+func TestOutcomesPlanned(t *testing.T) {
+	o := newOutcomes()
+	o.handle(events.EngineEvent{EngineEvent: apitype.EngineEvent{ResourcePreEvent: &apitype.ResourcePreEvent{Metadata: stepMetadata("urn:done", apitype.OpCreate, true)}}})
+	o.handle(events.EngineEvent{EngineEvent: apitype.EngineEvent{ResOutputsEvent: &apitype.ResOutputsEvent{Metadata: stepMetadata("urn:done", apitype.OpCreate, true)}}})
+	o.handle(events.EngineEvent{EngineEvent: apitype.EngineEvent{ResOpFailedEvent: &apitype.ResOpFailedEvent{Metadata: stepMetadata("urn:failed", apitype.OpUpdate, true)}}})
+	// the preview from before the deploy doesn't reset the outcomes that are
+	// known
+	o.planned([]apitype.StepEventMetadata{
+		stepMetadata("urn:done", apitype.OpCreate, true),
+		stepMetadata("urn:failed", apitype.OpUpdate, true),
+		stepMetadata("urn:component", apitype.OpCreate, false),
+		stepMetadata("urn:same", apitype.OpSame, true),
+		stepMetadata("urn:unreached", apitype.OpCreate, true),
+	})
+
+	targets, skipped := resumeTargets(&provider.Update{Resources: o.list()})
+	if !reflect.DeepEqual(targets, []string{"urn:failed", "urn:unreached"}) || !reflect.DeepEqual(skipped, []string{"urn:done"}) {
+		t.Fatalf("got %v %v", targets, skipped)
+	}
+}
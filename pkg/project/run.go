@@ -27,12 +27,21 @@ import (
 	"github.com/sst/sst/v3/pkg/process"
 	"github.com/sst/sst/v3/pkg/project/provider"
 	"github.com/sst/sst/v3/pkg/telemetry"
+	"github.com/sst/sst/v3/pkg/trace"
 	"github.com/sst/sst/v3/pkg/types"
 	"golang.org/x/exp/slices"
 	"golang.org/x/sync/errgroup"
 )
 
 func (p *Project) Run(ctx context.Context, input *StackInput) error {
+	span := trace.StartRoot("sst "+input.Command, stackAttributes(p, input))
+	err := p.run(ctx, input, span)
+	span.End(err)
+	trace.Flush(context.Background())
+	return err
+}
+
+func (p *Project) run(ctx context.Context, input *StackInput, span *trace.Span) error {
 	log := slog.Default().With("service", "project.run")
 	log.Info("running stack command", "cmd", input.Command)
 
@@ -61,9 +70,34 @@ func (p *Project) Run(ctx context.Context, input *StackInput) error {
 			return err
 		}
 		log = log.With("updateID", update.ID)
+		span.SetAttribute("sst.update", update.ID)
 		defer p.Unlock()
 	}
 
+	var resumeRecord *provider.Resume
+	var targets []string
+	if input.Command == "deploy" {
+		var resumed *provider.Update
+		resumeRecord, resumed, err = p.loadResume(input.Resume)
+		if err != nil {
+			return err
+		}
+		if resumed != nil {
+			var skipped []string
+			targets, skipped = resumeTargets(resumed)
+			if len(targets) == 0 {
+				return util.NewReadableError(ErrNothingToResume, "Could not find any resources that failed in the last deploy. Run `sst deploy` instead.").WithCode("resume_nothing")
+			}
+			log.Info("resuming", "from", resumed.ID, "targets", len(targets), "skipped", len(skipped))
+			update.Resumed = resumed.ID
+			bus.Publish(&ResumeEvent{
+				UpdateID: resumed.ID,
+				Targets:  targets,
+				Skipped:  skipped,
+			})
+		}
+	}
+
 	workdir, err := p.NewWorkdir(update.ID)
 	if err != nil {
 		return err
@@ -157,6 +191,7 @@ func (p *Project) Run(ctx context.Context, input *StackInput) error {
 	}
 	providerShim = append(providerShim, fmt.Sprintf("import * as sst from \"%s\";", path.Join(filepath.ToSlash(p.PathPlatformDir()), "src/components")))
 
+	buildSpan := trace.Start("build sst.config.ts", nil)
 	buildResult, err := js.Build(js.EvalOptions{
 		Dir:     p.PathRoot(),
 		Outfile: outfile,
@@ -177,6 +212,7 @@ func (p *Project) Run(ctx context.Context, input *StackInput) error {
 			filepath.ToSlash(p.PathConfig()),
 		),
 	})
+	buildSpan.End(err)
 	if err != nil {
 		bus.Publish(&BuildFailedEvent{
 			Error: err.Error(),
@@ -288,9 +324,14 @@ func (p *Project) Run(ctx context.Context, input *StackInput) error {
 	}
 
 	if input.Command == "deploy" {
-		upgradeMsgs := p.checkProviderUpgrade(completed.Resources)
+		upgradeMsgs, upgradeWarnings := p.evaluateUpgradeRules(completed.Resources)
 		if len(upgradeMsgs) > 0 {
-			return util.NewReadableError(nil, strings.Join(upgradeMsgs, "\n\n"))
+			return util.NewReadableError(nil, strings.Join(upgradeMsgs, "\n\n")).WithCode("provider_upgrade_blocked")
+		}
+		for _, warning := range upgradeWarnings {
+			bus.Publish(&ProviderUpgradeWarningEvent{
+				Message: warning,
+			})
 		}
 	}
 
@@ -335,7 +376,7 @@ func (p *Project) Run(ctx context.Context, input *StackInput) error {
 	if (input.Command == "diff" || input.Command == "deploy") && input.PolicyPath != "" {
 		policyPath, err := p.ResolvePolicyPackPath(input.PolicyPath)
 		if err != nil {
-			return util.NewReadableError(nil, err.Error())
+			return util.NewReadableError(nil, err.Error()).WithCode("policy_pack_not_found")
 		}
 		args = append(args, "--policy-pack", policyPath)
 	}
@@ -346,7 +387,7 @@ func (p *Project) Run(ctx context.Context, input *StackInput) error {
 				return res.URN.Name() == item
 			})
 			if index == -1 {
-				return util.NewReadableError(nil, fmt.Sprintf("Target not found: %v", item))
+				return util.NewReadableError(nil, fmt.Sprintf("Target not found: %v", item)).WithCode("target_not_found")
 			}
 			args = append(args, "--target", string(completed.Resources[index].URN))
 		}
@@ -361,7 +402,7 @@ func (p *Project) Run(ctx context.Context, input *StackInput) error {
 				return res.URN.Name() == item
 			})
 			if index == -1 {
-				return util.NewReadableError(nil, fmt.Sprintf("Exclude target not found: %v", item))
+				return util.NewReadableError(nil, fmt.Sprintf("Exclude target not found: %v", item)).WithCode("exclude_not_found")
 			}
 			args = append(args, "--exclude", string(completed.Resources[index].URN))
 		}
@@ -370,6 +411,43 @@ func (p *Project) Run(ctx context.Context, input *StackInput) error {
 		}
 	}
 
+	if len(targets) > 0 {
+		for _, urn := range targets {
+			args = append(args, "--target", urn)
+		}
+		args = append(args, "--target-dependents")
+	}
+
+	var planned []apitype.StepEventMetadata
+	if input.Command == "deploy" && p.app.Approval.enabled() {
+		previewLog := filepath.Join(filepath.Dir(eventlogPath), "preview.json")
+		steps, err := previewChanges(ctx, pulumiPath, previewArgs(args[3:], previewLog), env, workdir.Backend(), previewLog)
+		if err != nil {
+			return err
+		}
+		planned = steps
+		violations, token := checkChanges(p.app.Approval, p.app.Stage, steps)
+		if len(violations) > 0 {
+			approved := input.Approve == token
+			if !approved && input.ApproveChanges != nil {
+				approved = input.ApproveChanges(violations, token)
+			}
+			log.Info("changes need approval", "violations", len(violations), "approved", approved)
+			bus.Publish(&ChangePolicyEvent{
+				Violations: violations,
+				Token:      token,
+				Approved:   approved,
+			})
+			if !approved {
+				// nothing was deployed so the update isn't kept
+				if err := provider.RemoveUpdate(p.home, p.app.Name, p.app.Stage, update.ID); err != nil {
+					log.Error("failed to remove update", "err", err)
+				}
+				return &ErrChangeNotApproved{Violations: violations, Token: token}
+			}
+		}
+	}
+
 	cmd := process.Command(pulumiPath, args...)
 	process.Detach(cmd)
 	cmd.Env = env
@@ -452,6 +530,11 @@ func (p *Project) Run(ctx context.Context, input *StackInput) error {
 	}()
 
 	reader := bufio.NewReader(eventlog)
+	resources := newResourceSpans()
+	defer resources.end()
+	outcomes := newOutcomes()
+	outcomes.pending(targets)
+	steps := []apitype.StepEventMetadata{}
 
 	eofs := 0
 loop:
@@ -518,9 +601,11 @@ loop:
 
 			// check if the error is a common error
 			help := []string{}
-			for _, commonError := range CommonErrors {
-				if strings.Contains(event.DiagnosticEvent.Message, commonError.Message) {
-					help = append(help, commonError.Short...)
+			code := ""
+			for _, commonError := range MatchCommonErrors(p.CommonErrors(), event.DiagnosticEvent.Message) {
+				help = append(help, commonError.Help()...)
+				if code == "" {
+					code = commonError.Code
 				}
 			}
 
@@ -543,11 +628,13 @@ loop:
 					Message: strings.TrimSpace(event.DiagnosticEvent.Message),
 					URN:     event.DiagnosticEvent.URN,
 					Help:    help,
+					Code:    code,
 				})
 				log.Info("telemetry tracking error")
 				telemetry.Track("cli.resource.error", map[string]interface{}{
 					"error": event.DiagnosticEvent.Message,
 					"urn":   event.DiagnosticEvent.URN,
+					"code":  code,
 				})
 			}
 		}
@@ -575,6 +662,11 @@ loop:
 			partial <- 1
 		}
 
+		resources.handle(event)
+		outcomes.handle(event)
+		if input.Command == "diff" && event.ResourcePreEvent != nil {
+			steps = append(steps, event.ResourcePreEvent.Metadata)
+		}
 		for _, field := range getNotNilFields(event) {
 			bus.Publish(field)
 		}
@@ -625,6 +717,18 @@ loop:
 		}
 	}
 
+	// diff shows the changes that would need to be approved, and the token
+	// to approve them with
+	if input.Command == "diff" && p.app.Approval.enabled() {
+		violations, token := checkChanges(p.app.Approval, p.app.Stage, steps)
+		if len(violations) > 0 {
+			bus.Publish(&ChangePolicyEvent{
+				Violations: violations,
+				Token:      token,
+			})
+		}
+	}
+
 	log.Info("parsing state")
 	complete, err := getCompletedEvent(context.Background(), passphrase, workdir)
 	if err != nil {
@@ -634,7 +738,9 @@ loop:
 	complete.Finished = finished
 	complete.Errors = errors
 	complete.ImportDiffs = importDiffs
-	types.Generate(p.PathConfig(), complete.Links)
+	if err := types.Generate(p.PathConfig(), complete.Links); err != nil {
+		bus.Publish(&TypesWarningEvent{Message: err.Error()})
+	}
 	defer bus.Publish(complete)
 
 	if input.Command != "diff" {
@@ -654,8 +760,15 @@ loop:
 	defer outputsFile.Close()
 	json.NewEncoder(outputsFile).Encode(complete.Outputs)
 
+	if input.Command == "deploy" && (!finished || len(errors) > 0) {
+		// the deploy stops at the first failure, the changes from the preview
+		// that it never got to are picked up by a resume
+		outcomes.planned(planned)
+	}
+
 	if input.Command != "diff " {
 		update.TimeCompleted = time.Now().Format(time.RFC3339)
+		update.Resources = outcomes.list()
 		for _, err := range errors {
 			update.Errors = append(update.Errors, provider.SummaryError{
 				URN:     err.URN,
@@ -666,6 +779,21 @@ loop:
 		if err != nil {
 			return err
 		}
+		err = p.putStage(input, update.ID, len(complete.Resources))
+		if err != nil {
+			return err
+		}
+	}
+
+	if input.Command == "deploy" {
+		if !finished || len(errors) > 0 {
+			err = p.saveResume(update.ID)
+		} else if resumeRecord != nil {
+			err = provider.RemoveResume(p.home, p.app.Name, p.app.Stage)
+		}
+		if err != nil {
+			return err
+		}
 	}
 
 	if input.Command == "remove" && len(complete.Resources) == 0 {
@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"time"
 
 	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
 	"github.com/sst/sst/v3/pkg/project/common"
@@ -25,6 +26,18 @@ type StackInput struct {
 	Continue   bool
 	SkipHash   string
 	PolicyPath string
+	// Resume deploys only the resources that failed or did not finish in the
+	// last deploy, and their dependents.
+	Resume bool
+	// Approve is the token of the changes that were approved, when they break
+	// the approval policy of the stage.
+	Approve string
+	// ApproveChanges is asked about changes that break the approval policy and
+	// weren't approved with the token, it returns true to deploy them anyway.
+	ApproveChanges func(violations []ChangeViolation, token string) bool
+	// TTL sets the stage to expire this long after the deploy, so it's removed
+	// by `sst stage gc`.
+	TTL time.Duration
 }
 
 type ConcurrentUpdateEvent struct{}
@@ -45,6 +58,16 @@ type PolicyAdvisoryEvent struct {
 	URN     string
 }
 
+type ProviderUpgradeWarningEvent struct {
+	Message string
+}
+
+// TypesWarningEvent is published when the types for the links could not be
+// generated for one of the languages. The deploy itself is not affected.
+type TypesWarningEvent struct {
+	Message string
+}
+
 type Dev struct {
 	Name        string            `json:"name"`
 	Command     string            `json:"command"`
@@ -56,9 +79,18 @@ type Dev struct {
 	Aws         *struct {
 		Role string `json:"role"`
 	} `json:"aws"`
+	DependsOn []string  `json:"dependsOn"`
+	Ready     *DevReady `json:"ready"`
+	Restart   string    `json:"restart"`
 }
 type Devs map[string]Dev
 
+type DevReady struct {
+	Port int    `json:"port"`
+	URL  string `json:"url"`
+	Log  string `json:"log"`
+}
+
 type Task struct {
 	Name      string  `json:"-"`
 	Command   *string `json:"command"`
@@ -79,6 +111,9 @@ type CompleteEvent struct {
 	Resources   []apitype.ResourceV3
 	ImportDiffs map[string][]ImportDiff
 	Tunnels     map[string]Tunnel
+	// Secrets are the JSONPaths of the outputs and link properties that are
+	// marked as secret, see Document.
+	Secrets []string
 }
 
 type Tunnel struct {
@@ -107,48 +142,8 @@ type Error struct {
 	Message string   `json:"message"`
 	URN     string   `json:"urn"`
 	Help    []string `json:"help"`
-}
-
-type CommonError struct {
-	Code    string   `json:"code"`
-	Message string   `json:"message"`
-	Short   []string `json:"short"`
-	Long    []string `json:"long"`
-}
-
-var CommonErrors = []CommonError{
-	{
-		Code:    "TooManyCacheBehaviors",
-		Message: "TooManyCacheBehaviors: Your request contains more CacheBehaviors than are allowed per distribution",
-		Short: []string{
-			"There are too many top-level files and directories inside your app's public asset directory. Move some of them inside subdirectories.",
-			"Learn more about this https://sst.dev/docs/common-errors#toomanycachebehaviors",
-		},
-		Long: []string{
-			"This error usually happens to `SvelteKit`, `SolidStart`, `Nuxt`, and `Analog` components.",
-			"",
-			"CloudFront distributions have a **limit of 25 cache behaviors** per distribution. Each top-level file or directory in your frontend app's asset directory creates a cache behavior.",
-			"",
-			"For example, in the case of SvelteKit, the static assets are in the `static/` directory. If you have a file and a directory in it, it'll create 2 cache behaviors.",
-			"",
-			"```bash frame=\"none\"",
-			"static/",
-			"├── icons/       # Cache behavior for /icons/*",
-			"└── logo.png     # Cache behavior for /logo.png",
-			"```",
-			"So if you have many of these at the top-level, you'll hit the limit. You can request a limit increase through the AWS Support.",
-			"",
-			"Alternatively, you can move some of these into subdirectories. For example, moving them to an `images/` directory, will only create 1 cache behavior.",
-			"",
-			"```bash frame=\"none\"",
-			"static/",
-			"└── images/      # Cache behavior for /images/*",
-			"    ├── icons/",
-			"    └── logo.png",
-			"```",
-			"Learn more about these [CloudFront limits](https://docs.aws.amazon.com/AmazonCloudFront/latest/DeveloperGuide/cloudfront-limits.html#limits-web-distributions).",
-		},
-	},
+	// Code is the code of the common error that matched, if any.
+	Code string `json:"code,omitempty"`
 }
 
 var ErrStackRunFailed = fmt.Errorf("stack run had errors")
@@ -4,6 +4,9 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
+
+	"github.com/sst/sst/v3/pkg/project/provider"
 )
 
 func resolveStageFile(cfgPath string) string {
@@ -28,3 +31,33 @@ func SetPersonalStage(cfgPath string, stage string) error {
 	}
 	return nil
 }
+
+// putStage records the last update of the stage. The expiry is kept unless
+// the update sets a new one, and the record is removed with the stage.
+func (p *Project) putStage(input *StackInput, updateID string, resources int) error {
+	if input.Command == "diff" {
+		return nil
+	}
+	existing, err := provider.GetStage(p.home, p.app.Name, p.app.Stage)
+	if err != nil {
+		return err
+	}
+	if input.Command == "remove" && resources == 0 {
+		if existing == nil {
+			return nil
+		}
+		return provider.RemoveStage(p.home, p.app.Name, p.app.Stage)
+	}
+	record := &provider.Stage{
+		UpdateID:  updateID,
+		Resources: resources,
+		Protected: p.app.Protect,
+	}
+	if existing != nil {
+		record.Expires = existing.Expires
+	}
+	if input.TTL > 0 {
+		record.Expires = time.Now().Add(input.TTL).UTC().Format(time.RFC3339)
+	}
+	return provider.PutStage(p.home, p.app.Name, p.app.Stage, record)
+}
@@ -0,0 +1,73 @@
+package project
+
+import (
+	"fmt"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/events"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/sst/sst/v3/pkg/trace"
+)
+
+// resourceSpans turns the engine events of an update into a span per
+// resource operation, nested under the span of the component they're in.
+type resourceSpans struct {
+	spans map[string]*trace.Span
+}
+
+func newResourceSpans() *resourceSpans {
+	return &resourceSpans{
+		spans: map[string]*trace.Span{},
+	}
+}
+
+func (r *resourceSpans) handle(event events.EngineEvent) {
+	if !trace.Enabled() {
+		return
+	}
+	if event.ResourcePreEvent != nil {
+		metadata := event.ResourcePreEvent.Metadata
+		state := metadata.New
+		if state == nil {
+			state = metadata.Old
+		}
+		attributes := map[string]interface{}{
+			"sst.urn":  metadata.URN,
+			"sst.type": metadata.Type,
+			"sst.op":   string(metadata.Op),
+		}
+		var parent *trace.Span
+		if state != nil {
+			attributes["sst.custom"] = state.Custom
+			parent = r.spans[state.Parent]
+		}
+		r.spans[metadata.URN] = trace.StartChild(parent, string(metadata.Op)+" "+resource.URN(metadata.URN).Name(), attributes)
+	}
+	if event.ResOutputsEvent != nil {
+		r.spans[event.ResOutputsEvent.Metadata.URN].End(nil)
+	}
+	if event.ResOpFailedEvent != nil {
+		// keeps the message of the diagnostic event if there was one
+		r.spans[event.ResOpFailedEvent.Metadata.URN].End(fmt.Errorf("failed to %s", event.ResOpFailedEvent.Metadata.Op))
+	}
+	if event.DiagnosticEvent != nil && event.DiagnosticEvent.Severity == "error" && event.DiagnosticEvent.URN != "" {
+		r.spans[event.DiagnosticEvent.URN].SetError(event.DiagnosticEvent.Message)
+	}
+}
+
+// end ends the spans of components, which don't get an outputs event when
+// one of their children fails.
+func (r *resourceSpans) end() {
+	for _, span := range r.spans {
+		span.End(nil)
+	}
+}
+
+func stackAttributes(p *Project, input *StackInput) map[string]interface{} {
+	return map[string]interface{}{
+		"sst.app":     p.app.Name,
+		"sst.stage":   p.app.Stage,
+		"sst.command": input.Command,
+		"sst.dev":     input.Dev,
+		"sst.version": p.Version(),
+	}
+}
@@ -22,6 +22,19 @@ type BucketFile struct {
 	Hash         *string `json:"hash,omitempty"`
 }
 
+// fingerprint identifies the content and the headers of an uploaded file.
+func (f BucketFile) fingerprint() string {
+	hash := ""
+	if f.Hash != nil {
+		hash = *f.Hash
+	}
+	cacheControl := ""
+	if f.CacheControl != nil {
+		cacheControl = *f.CacheControl
+	}
+	return hash + ":" + f.ContentType + ":" + cacheControl
+}
+
 type BucketFilesInputs struct {
 	BucketName string       `json:"bucketName"`
 	Files      []BucketFile `json:"files"`
@@ -118,6 +131,9 @@ func (r *BucketFiles) upload(client *s3.Client, bucketName string, files []Bucke
 	for _, f := range oldFiles {
 		oldFilesMap[f.Key] = f
 	}
+	// Files uploaded by a failed deploy that's being resumed aren't in the
+	// state yet
+	resumed := r.project.ResumedFiles(bucketName)
 
 	// Split files into HTML and non-HTML to upload non-HTML first.
 	// This avoids a race condition where CloudFront serves new HTML
@@ -130,6 +146,9 @@ func (r *BucketFiles) upload(client *s3.Client, bucketName string, files []Bucke
 			oldFile.ContentType == file.ContentType {
 			continue
 		}
+		if fingerprint, ok := resumed[file.Key]; ok && file.Hash != nil && fingerprint == file.fingerprint() {
+			continue
+		}
 		if strings.HasSuffix(file.Key, ".html") {
 			htmlFiles = append(htmlFiles, file)
 		} else {
@@ -177,7 +196,9 @@ func (r *BucketFiles) uploadFiles(client *s3.Client, bucketName string, files []
 				})
 				if err != nil {
 					errChan <- err
+					continue
 				}
+				r.project.RecordUploadedFile(bucketName, file.Key, file.fingerprint())
 			}
 		}()
 	}
@@ -7,12 +7,14 @@ import (
 	"io"
 	"log/slog"
 	"os"
+	"path/filepath"
 	"strconv"
 
 	"github.com/sst/sst/v3/cmd/sst/mosaic/ui/common"
 	"github.com/sst/sst/v3/pkg/bus"
 	"github.com/sst/sst/v3/pkg/flag"
 	"github.com/sst/sst/v3/pkg/process"
+	"github.com/sst/sst/v3/pkg/trace"
 	"golang.org/x/sync/semaphore"
 )
 
@@ -67,9 +69,15 @@ func (r *Run) Update(input *UpdateInput[RunInputs, RunOutputs], output *UpdateRe
 	return nil
 }
 
-func (r *Run) executeCommand(input *RunInputs) error {
+func (r *Run) executeCommand(input *RunInputs) (err error) {
 	r.lock.Acquire(context.Background(), 1)
 	defer r.lock.Release(1)
+	// this is how sites are built
+	span := trace.Start("run "+filepath.Base(input.Cwd), map[string]interface{}{
+		"sst.run.command": input.Command,
+		"sst.run.cwd":     input.Cwd,
+	})
+	defer func() { span.End(err) }()
 	cmd := process.Command("sh", "-c", input.Command)
 	cmd.Dir = input.Cwd
 	cmd.Env = os.Environ()
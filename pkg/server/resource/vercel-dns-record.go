@@ -9,6 +9,7 @@ import (
 	"net/http"
 
 	"github.com/sst/sst/v3/pkg/project"
+	"github.com/sst/sst/v3/pkg/project/provider"
 )
 
 // Base resource for Vercel providers
@@ -17,6 +18,17 @@ type VercelResource struct {
 	project *project.Project
 }
 
+// apiToken falls back to the token in the vercel provider config.
+func (r *VercelResource) apiToken(input string) string {
+	if input != "" {
+		return input
+	}
+	if result, ok := r.project.Provider("vercel"); ok {
+		return result.(*provider.VercelProvider).ApiToken()
+	}
+	return ""
+}
+
 type VercelDnsRecord struct {
 	*VercelResource
 }
@@ -91,7 +103,7 @@ func (r *VercelDnsRecord) createOrUpdateRecord(input *VercelDnsRecordInputs) (st
 	}
 	
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+input.ApiToken)
+	req.Header.Set("Authorization", "Bearer "+r.apiToken(input.ApiToken))
 	
 	client := &http.Client{}
 	resp, err := client.Do(req)
@@ -0,0 +1,276 @@
+package state
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+)
+
+type GraphNode struct {
+	URN    resource.URN `json:"urn"`
+	Name   string       `json:"name"`
+	Type   string       `json:"type"`
+	Parent resource.URN `json:"parent,omitempty"`
+	Custom bool         `json:"custom"`
+	// Affected is set when the resource would be part of a deploy with the
+	// targets passed to NewGraph.
+	Affected bool `json:"affected"`
+}
+
+const (
+	EdgeParent     = "parent"
+	EdgeDependency = "dependency"
+)
+
+// GraphEdge goes from a resource to its parent or to a resource it depends
+// on. Properties lists the inputs that cause a dependency, if known.
+type GraphEdge struct {
+	From       resource.URN `json:"from"`
+	To         resource.URN `json:"to"`
+	Kind       string       `json:"kind"`
+	Properties []string     `json:"properties,omitempty"`
+}
+
+type Graph struct {
+	Nodes []GraphNode `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+}
+
+type GraphOptions struct {
+	// Components limits the graph to the resources with these names and
+	// everything they contain.
+	Components []string
+	// Types are globs like `aws:lambda*` that the resource types need to
+	// match. Providers are only included when they are matched here.
+	Types []string
+	// Targets are marked as affected along with everything that depends on
+	// them, the same way `--target` selects resources to deploy.
+	Targets []string
+}
+
+func NewGraph(resources []apitype.ResourceV3, options GraphOptions) (*Graph, error) {
+	for _, pattern := range options.Types {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid type filter %q: %w", pattern, err)
+		}
+	}
+
+	children := map[resource.URN][]resource.URN{}
+	dependents := map[resource.URN][]resource.URN{}
+	for _, item := range resources {
+		if item.Parent != "" {
+			children[item.Parent] = append(children[item.Parent], item.URN)
+			dependents[item.Parent] = append(dependents[item.Parent], item.URN)
+		}
+		for _, dependency := range dependencies(item) {
+			dependents[dependency] = append(dependents[dependency], item.URN)
+		}
+	}
+
+	var included map[resource.URN]bool
+	if len(options.Components) > 0 {
+		included = map[resource.URN]bool{}
+		roots := []resource.URN{}
+		for _, name := range options.Components {
+			found := false
+			for _, item := range resources {
+				if item.URN.Name() == name {
+					roots = append(roots, item.URN)
+					found = true
+				}
+			}
+			if !found {
+				return nil, fmt.Errorf("component not found: %s", name)
+			}
+		}
+		walk(roots, children, included)
+	}
+
+	affected := map[resource.URN]bool{}
+	for _, name := range options.Targets {
+		roots := []resource.URN{}
+		for _, item := range resources {
+			if item.URN.Name() == name {
+				roots = append(roots, item.URN)
+			}
+		}
+		if len(roots) == 0 {
+			return nil, fmt.Errorf("target not found: %s", name)
+		}
+		walk(roots, dependents, affected)
+	}
+
+	result := &Graph{
+		Nodes: []GraphNode{},
+		Edges: []GraphEdge{},
+	}
+	nodes := map[resource.URN]bool{}
+	for _, item := range resources {
+		if included != nil && !included[item.URN] {
+			continue
+		}
+		kind := string(item.URN.Type())
+		if len(options.Types) > 0 {
+			if !slices.ContainsFunc(options.Types, func(pattern string) bool {
+				matched, _ := path.Match(pattern, kind)
+				return matched
+			}) {
+				continue
+			}
+		} else if strings.HasPrefix(kind, "pulumi:providers:") {
+			continue
+		}
+		nodes[item.URN] = true
+		result.Nodes = append(result.Nodes, GraphNode{
+			URN:      item.URN,
+			Name:     item.URN.Name(),
+			Type:     kind,
+			Parent:   item.Parent,
+			Custom:   item.Custom,
+			Affected: affected[item.URN],
+		})
+	}
+
+	for _, item := range resources {
+		if !nodes[item.URN] {
+			continue
+		}
+		if item.Parent != "" && nodes[item.Parent] {
+			result.Edges = append(result.Edges, GraphEdge{
+				From: item.URN,
+				To:   item.Parent,
+				Kind: EdgeParent,
+			})
+		}
+		for _, dependency := range dependencies(item) {
+			if !nodes[dependency] || dependency == item.Parent {
+				continue
+			}
+			properties := []string{}
+			for key, values := range item.PropertyDependencies {
+				if slices.Contains(values, dependency) {
+					properties = append(properties, string(key))
+				}
+			}
+			slices.Sort(properties)
+			result.Edges = append(result.Edges, GraphEdge{
+				From:       item.URN,
+				To:         dependency,
+				Kind:       EdgeDependency,
+				Properties: properties,
+			})
+		}
+	}
+	return result, nil
+}
+
+// dependencies returns the resources item depends on, without duplicates.
+func dependencies(item apitype.ResourceV3) []resource.URN {
+	result := slices.Clone(item.Dependencies)
+	for _, values := range item.PropertyDependencies {
+		result = append(result, values...)
+	}
+	slices.Sort(result)
+	return slices.Compact(result)
+}
+
+func walk(roots []resource.URN, edges map[resource.URN][]resource.URN, visited map[resource.URN]bool) {
+	queue := slices.Clone(roots)
+	for len(queue) > 0 {
+		next := queue[0]
+		queue = queue[1:]
+		if visited[next] {
+			continue
+		}
+		visited[next] = true
+		queue = append(queue, edges[next]...)
+	}
+}
+
+// Dot renders the graph in the Graphviz DOT language. Parent edges are dashed
+// and affected resources are filled in.
+func (g *Graph) Dot() string {
+	var b strings.Builder
+	b.WriteString("digraph sst {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=box, fontname=\"Helvetica\"];\n")
+	for _, node := range g.Nodes {
+		attrs := fmt.Sprintf("label=%s", dotQuote(node.Name+"\n"+node.Type))
+		style := []string{}
+		if !node.Custom {
+			style = append(style, "rounded")
+		}
+		if node.Affected {
+			style = append(style, "filled")
+			attrs += ", fillcolor=\"#f8d0c8\", color=\"#c0392b\""
+		}
+		if len(style) > 0 {
+			attrs += fmt.Sprintf(", style=\"%s\"", strings.Join(style, ","))
+		}
+		fmt.Fprintf(&b, "  %s [%s];\n", dotQuote(string(node.URN)), attrs)
+	}
+	for _, edge := range g.Edges {
+		attrs := ""
+		if edge.Kind == EdgeParent {
+			attrs = " [style=dashed, arrowhead=none]"
+		} else if len(edge.Properties) > 0 {
+			attrs = fmt.Sprintf(" [label=%s]", dotQuote(strings.Join(edge.Properties, ", ")))
+		}
+		fmt.Fprintf(&b, "  %s -> %s%s;\n", dotQuote(string(edge.From)), dotQuote(string(edge.To)), attrs)
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+func dotQuote(value string) string {
+	value = strings.ReplaceAll(value, "\\", "\\\\")
+	value = strings.ReplaceAll(value, "\"", "\\\"")
+	value = strings.ReplaceAll(value, "\n", "\\n")
+	return "\"" + value + "\""
+}
+
+// Mermaid renders the graph as a Mermaid flowchart. Parent edges are dotted
+// and affected resources use the `affected` class.
+func (g *Graph) Mermaid() string {
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+	ids := map[resource.URN]string{}
+	affected := []string{}
+	for index, node := range g.Nodes {
+		id := fmt.Sprintf("n%d", index)
+		ids[node.URN] = id
+		label := mermaidQuote(node.Name) + "<br/><small>" + mermaidQuote(node.Type) + "</small>"
+		if node.Custom {
+			fmt.Fprintf(&b, "  %s[\"%s\"]\n", id, label)
+		} else {
+			fmt.Fprintf(&b, "  %s(\"%s\")\n", id, label)
+		}
+		if node.Affected {
+			affected = append(affected, id)
+		}
+	}
+	for _, edge := range g.Edges {
+		if edge.Kind == EdgeParent {
+			fmt.Fprintf(&b, "  %s -.- %s\n", ids[edge.To], ids[edge.From])
+			continue
+		}
+		if len(edge.Properties) > 0 {
+			fmt.Fprintf(&b, "  %s -->|\"%s\"| %s\n", ids[edge.From], mermaidQuote(strings.Join(edge.Properties, ", ")), ids[edge.To])
+			continue
+		}
+		fmt.Fprintf(&b, "  %s --> %s\n", ids[edge.From], ids[edge.To])
+	}
+	if len(affected) > 0 {
+		b.WriteString("  classDef affected fill:#f8d0c8,stroke:#c0392b\n")
+		fmt.Fprintf(&b, "  class %s affected\n", strings.Join(affected, ","))
+	}
+	return b.String()
+}
+
+func mermaidQuote(value string) string {
+	return strings.NewReplacer("\"", "#quot;", "<", "#lt;", ">", "#gt;").Replace(value)
+}
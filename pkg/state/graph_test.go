@@ -0,0 +1,129 @@
+package state
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+)
+
+func urn(kind string, name string) resource.URN {
+	return resource.URN("urn:pulumi:dev::app::" + kind + "::" + name)
+}
+
+func graphResources() []apitype.ResourceV3 {
+	stack := urn("pulumi:pulumi:Stack", "app-dev")
+	bucket := urn("sst:aws:Bucket", "MyBucket")
+	api := urn("sst:aws:Function", "MyApi")
+	return []apitype.ResourceV3{
+		{URN: stack, Type: "pulumi:pulumi:Stack"},
+		{URN: urn("pulumi:providers:aws", "default"), Custom: true},
+		{URN: bucket, Parent: stack},
+		{URN: urn("sst:aws:Bucket$aws:s3/bucketV2:BucketV2", "MyBucketBucket"), Parent: bucket, Custom: true},
+		{URN: api, Parent: stack, Dependencies: []resource.URN{bucket}},
+		{
+			URN:          urn("sst:aws:Function$aws:lambda/function:Function", "MyApiFunction"),
+			Parent:       api,
+			Custom:       true,
+			Dependencies: []resource.URN{bucket},
+			PropertyDependencies: map[resource.PropertyKey][]resource.URN{
+				"environment": {bucket},
+			},
+		},
+		{URN: urn("sst:aws:Queue", "MyQueue"), Parent: stack},
+	}
+}
+
+func names(graph *Graph) []string {
+	result := []string{}
+	for _, node := range graph.Nodes {
+		result = append(result, node.Name)
+	}
+	return result
+}
+
+func TestGraph(t *testing.T) {
+	graph, err := NewGraph(graphResources(), GraphOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(names(graph), ","); got != "app-dev,MyBucket,MyBucketBucket,MyApi,MyApiFunction,MyQueue" {
+		t.Fatalf("providers should be left out, got %s", got)
+	}
+	var dependency *GraphEdge
+	for i, edge := range graph.Edges {
+		if edge.Kind == EdgeDependency && edge.From.Name() == "MyApiFunction" {
+			dependency = &graph.Edges[i]
+		}
+	}
+	if dependency == nil || dependency.To.Name() != "MyBucket" || len(dependency.Properties) != 1 || dependency.Properties[0] != "environment" {
+		t.Fatalf("unexpected dependency edge %+v", dependency)
+	}
+}
+
+func TestGraphFilters(t *testing.T) {
+	graph, err := NewGraph(graphResources(), GraphOptions{Components: []string{"MyApi"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(names(graph), ","); got != "MyApi,MyApiFunction" {
+		t.Fatalf("got %s", got)
+	}
+
+	graph, err = NewGraph(graphResources(), GraphOptions{Types: []string{"sst:aws:*"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(names(graph), ","); got != "MyBucket,MyApi,MyQueue" {
+		t.Fatalf("got %s", got)
+	}
+
+	if _, err := NewGraph(graphResources(), GraphOptions{Components: []string{"Missing"}}); err == nil {
+		t.Fatal("expected an error for a missing component")
+	}
+}
+
+func TestGraphTargets(t *testing.T) {
+	graph, err := NewGraph(graphResources(), GraphOptions{Targets: []string{"MyBucket"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	affected := []string{}
+	for _, node := range graph.Nodes {
+		if node.Affected {
+			affected = append(affected, node.Name)
+		}
+	}
+	if got := strings.Join(affected, ","); got != "MyBucket,MyBucketBucket,MyApi,MyApiFunction" {
+		t.Fatalf("got %s", got)
+	}
+}
+
+func TestGraphRender(t *testing.T) {
+	graph, err := NewGraph(graphResources(), GraphOptions{Components: []string{"MyApi"}, Targets: []string{"MyApi"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dot := graph.Dot()
+	if !strings.Contains(dot, `label="MyApi\nsst:aws:Function", fillcolor="#f8d0c8", color="#c0392b", style="rounded,filled"`) {
+		t.Errorf("unexpected dot output:\n%s", dot)
+	}
+	if !strings.Contains(dot, "[style=dashed, arrowhead=none]") {
+		t.Errorf("missing parent edge:\n%s", dot)
+	}
+
+	mermaid := graph.Mermaid()
+	if !strings.HasPrefix(mermaid, "flowchart LR\n") || !strings.Contains(mermaid, "n0 -.- n1") || !strings.Contains(mermaid, "class n0,n1 affected") {
+		t.Errorf("unexpected mermaid output:\n%s", mermaid)
+	}
+
+	data, err := json.Marshal(graph)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"kind":"parent"`) {
+		t.Errorf("unexpected json output: %s", data)
+	}
+}
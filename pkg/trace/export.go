@@ -0,0 +1,212 @@
+package trace
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sst/sst/v3/pkg/flag"
+)
+
+const (
+	spanKindInternal = 1
+	statusCodeOk     = 1
+	statusCodeError  = 2
+)
+
+// The OTLP/JSON encoding of ExportTraceServiceRequest, with only the fields
+// that are used.
+type exportRequest struct {
+	ResourceSpans []resourceSpans `json:"resourceSpans"`
+}
+
+type resourceSpans struct {
+	Resource   resource     `json:"resource"`
+	ScopeSpans []scopeSpans `json:"scopeSpans"`
+}
+
+type resource struct {
+	Attributes []attribute `json:"attributes"`
+}
+
+type scopeSpans struct {
+	Scope scope      `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type scope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string      `json:"traceId"`
+	SpanID            string      `json:"spanId"`
+	ParentSpanID      string      `json:"parentSpanId,omitempty"`
+	Name              string      `json:"name"`
+	Kind              int         `json:"kind"`
+	StartTimeUnixNano string      `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string      `json:"endTimeUnixNano"`
+	Attributes        []attribute `json:"attributes"`
+	Status            status      `json:"status"`
+}
+
+type status struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type attribute struct {
+	Key   string         `json:"key"`
+	Value attributeValue `json:"value"`
+}
+
+type attributeValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
+
+// Flush sends the current trace to SST_TRACE_ENDPOINT and appends it to
+// SST_TRACE_FILE. Failures are logged, tracing never fails a deploy.
+func Flush(ctx context.Context) {
+	if !global.enabled {
+		return
+	}
+	root, spans := global.take()
+	if root == nil || len(spans) == 0 {
+		return
+	}
+	data, err := json.Marshal(encode(newID(16), spans, root.Attributes))
+	if err != nil {
+		slog.Warn("failed to encode trace", "err", err)
+		return
+	}
+	if flag.SST_TRACE_FILE != "" {
+		if err := appendFile(flag.SST_TRACE_FILE, data); err != nil {
+			slog.Warn("failed to write trace", "path", flag.SST_TRACE_FILE, "err", err)
+		}
+	}
+	if flag.SST_TRACE_ENDPOINT != "" {
+		if err := send(ctx, flag.SST_TRACE_ENDPOINT, flag.SST_TRACE_HEADERS, data); err != nil {
+			slog.Warn("failed to send trace", "endpoint", flag.SST_TRACE_ENDPOINT, "err", err)
+		}
+	}
+}
+
+func encode(traceID string, spans []Span, rootAttributes map[string]interface{}) *exportRequest {
+	result := []otlpSpan{}
+	for _, span := range spans {
+		item := otlpSpan{
+			TraceID:           traceID,
+			SpanID:            span.ID,
+			ParentSpanID:      span.Parent,
+			Name:              span.Name,
+			Kind:              spanKindInternal,
+			StartTimeUnixNano: strconv.FormatInt(span.Started.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(span.Ended.UnixNano(), 10),
+			Attributes:        attributes(span.Attributes),
+			Status:            status{Code: statusCodeOk},
+		}
+		if span.Error != "" {
+			item.Status = status{Code: statusCodeError, Message: span.Error}
+		}
+		result = append(result, item)
+	}
+	resourceAttrs := map[string]interface{}{
+		"service.name": "sst",
+	}
+	if version, ok := rootAttributes["sst.version"]; ok {
+		resourceAttrs["service.version"] = version
+	}
+	return &exportRequest{
+		ResourceSpans: []resourceSpans{{
+			Resource: resource{Attributes: attributes(resourceAttrs)},
+			ScopeSpans: []scopeSpans{{
+				Scope: scope{Name: "github.com/sst/sst/v3/pkg/trace"},
+				Spans: result,
+			}},
+		}},
+	}
+}
+
+func attributes(input map[string]interface{}) []attribute {
+	result := []attribute{}
+	for key, value := range input {
+		var item attributeValue
+		switch v := value.(type) {
+		case string:
+			item.StringValue = &v
+		case bool:
+			item.BoolValue = &v
+		case int:
+			formatted := strconv.Itoa(v)
+			item.IntValue = &formatted
+		case int64:
+			formatted := strconv.FormatInt(v, 10)
+			item.IntValue = &formatted
+		case float64:
+			item.DoubleValue = &v
+		default:
+			formatted := fmt.Sprint(v)
+			item.StringValue = &formatted
+		}
+		result = append(result, attribute{Key: key, Value: item})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Key < result[j].Key
+	})
+	return result
+}
+
+func appendFile(path string, data []byte) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(append(data, '\n'))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// send posts to /v1/traces of the endpoint, like OTEL_EXPORTER_OTLP_ENDPOINT,
+// unless the endpoint already ends with it.
+func send(ctx context.Context, endpoint string, headers string, data []byte) error {
+	url := strings.TrimSuffix(endpoint, "/")
+	if !strings.HasSuffix(url, "/v1/traces") {
+		url += "/v1/traces"
+	}
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for _, pair := range strings.Split(headers, ",") {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			continue
+		}
+		req.Header.Set(strings.TrimSpace(key), strings.TrimSpace(value))
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+// Package trace records deploys as OpenTelemetry traces. It's opt-in with
+// SST_TRACE_ENDPOINT or SST_TRACE_FILE and separate from pkg/telemetry.
+package trace
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/sst/sst/v3/pkg/flag"
+)
+
+type Span struct {
+	ID         string
+	Parent     string
+	Name       string
+	Started    time.Time
+	Ended      time.Time
+	Attributes map[string]interface{}
+	Error      string
+}
+
+type tracer struct {
+	mu      sync.Mutex
+	enabled bool
+	root    *Span
+	spans   []*Span
+}
+
+var global = &tracer{
+	enabled: flag.SST_TRACE_ENDPOINT != "" || flag.SST_TRACE_FILE != "",
+}
+
+func Enabled() bool {
+	return global.enabled
+}
+
+// StartRoot starts the span of an update. Spans started without a parent,
+// including the ones started before this, are nested under it.
+func StartRoot(name string, attributes map[string]interface{}) *Span {
+	span := global.start(name, "", attributes)
+	if span == nil {
+		return nil
+	}
+	global.mu.Lock()
+	global.root = span
+	global.mu.Unlock()
+	return span
+}
+
+// Start starts a span under the root span.
+func Start(name string, attributes map[string]interface{}) *Span {
+	return global.start(name, "", attributes)
+}
+
+// StartChild starts a span under parent, or under the root if parent is nil.
+func StartChild(parent *Span, name string, attributes map[string]interface{}) *Span {
+	if parent == nil {
+		return Start(name, attributes)
+	}
+	return global.start(name, parent.ID, attributes)
+}
+
+func (t *tracer) start(name string, parent string, attributes map[string]interface{}) *Span {
+	if !t.enabled {
+		return nil
+	}
+	if attributes == nil {
+		attributes = map[string]interface{}{}
+	}
+	span := &Span{
+		ID:         newID(8),
+		Parent:     parent,
+		Name:       name,
+		Started:    time.Now(),
+		Attributes: attributes,
+	}
+	t.mu.Lock()
+	t.spans = append(t.spans, span)
+	t.mu.Unlock()
+	return span
+}
+
+// SetAttribute is safe to call on a nil span, like the rest of the methods.
+func (s *Span) SetAttribute(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+	global.mu.Lock()
+	defer global.mu.Unlock()
+	s.Attributes[key] = value
+}
+
+func (s *Span) SetError(message string) {
+	if s == nil {
+		return
+	}
+	global.mu.Lock()
+	defer global.mu.Unlock()
+	s.Error = message
+}
+
+// End ends the span, marking it as failed if err is set. Ending a span twice
+// keeps the first end time.
+func (s *Span) End(err error) {
+	if s == nil {
+		return
+	}
+	global.mu.Lock()
+	defer global.mu.Unlock()
+	if !s.Ended.IsZero() {
+		return
+	}
+	s.Ended = time.Now()
+	if err != nil && s.Error == "" {
+		s.Error = err.Error()
+	}
+}
+
+// take removes the spans of the current trace, ending the ones that are still
+// open and nesting orphans under the root.
+func (t *tracer) take() (*Span, []Span) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	root := t.root
+	spans := make([]Span, 0, len(t.spans))
+	now := time.Now()
+	for _, span := range t.spans {
+		if span.Ended.IsZero() {
+			span.Ended = now
+		}
+		item := *span
+		item.Attributes = maps.Clone(span.Attributes)
+		if root != nil && item.Parent == "" && item.ID != root.ID {
+			item.Parent = root.ID
+		}
+		spans = append(spans, item)
+	}
+	t.root = nil
+	t.spans = nil
+	return root, spans
+}
+
+func newID(size int) string {
+	bytes := make([]byte, size)
+	rand.Read(bytes)
+	return hex.EncodeToString(bytes)
+}
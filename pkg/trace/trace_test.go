@@ -0,0 +1,100 @@
+package trace
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDisabled(t *testing.T) {
+	global = &tracer{}
+	span := StartRoot("sst deploy", nil)
+	if span != nil {
+		t.Fatal("expected no span when tracing is disabled")
+	}
+	span.SetAttribute("key", "value")
+	span.End(errors.New("failed"))
+	Flush(context.Background())
+}
+
+func TestTrace(t *testing.T) {
+	global = &tracer{enabled: true}
+	defer func() { global = &tracer{} }()
+
+	install := Start("install providers", nil)
+	install.End(nil)
+	root := StartRoot("sst deploy", map[string]interface{}{"sst.version": "3.0.0"})
+	component := StartChild(nil, "create MyBucket", nil)
+	resource := StartChild(component, "create MyBucketBucket", map[string]interface{}{"sst.custom": true})
+	resource.SetError("access denied")
+	resource.End(errors.New("failed to create"))
+	root.End(nil)
+
+	got, spans := global.take()
+	if got != root || len(spans) != 4 {
+		t.Fatalf("got %d spans", len(spans))
+	}
+	for _, span := range spans {
+		if span.Ended.IsZero() {
+			t.Fatalf("%s was not ended", span.Name)
+		}
+		if span.ID != root.ID && span.Parent == "" {
+			t.Fatalf("%s was not nested under the root", span.Name)
+		}
+	}
+	if spans[3].Parent != component.ID || spans[3].Error != "access denied" {
+		t.Fatalf("unexpected span %+v", spans[3])
+	}
+
+	data, err := json.Marshal(encode("0102", spans, root.Attributes))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded struct {
+		ResourceSpans []struct {
+			Resource struct {
+				Attributes []attribute
+			}
+			ScopeSpans []struct {
+				Spans []struct {
+					TraceID    string
+					Status     status
+					Attributes []attribute
+				}
+			}
+		}
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	resource0 := decoded.ResourceSpans[0]
+	if len(resource0.Resource.Attributes) != 2 || *resource0.Resource.Attributes[1].Value.StringValue != "3.0.0" {
+		t.Fatalf("unexpected resource %s", data)
+	}
+	last := resource0.ScopeSpans[0].Spans[3]
+	if last.TraceID != "0102" || last.Status.Code != statusCodeError || last.Status.Message != "access denied" || !*last.Attributes[0].Value.BoolValue {
+		t.Fatalf("unexpected span %s", data)
+	}
+}
+
+func TestSend(t *testing.T) {
+	var path, auth, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		auth = r.Header.Get("Authorization")
+		data, _ := io.ReadAll(r.Body)
+		body = string(data)
+	}))
+	defer server.Close()
+	err := send(context.Background(), server.URL+"/", "Authorization=Bearer abc, x-other = 1", []byte("{}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path != "/v1/traces" || auth != "Bearer abc" || body != "{}" {
+		t.Fatalf("got %s %s %s", path, auth, body)
+	}
+}
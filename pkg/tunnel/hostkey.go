@@ -0,0 +1,80 @@
+package tunnel
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+var ErrHostKeyMismatch = fmt.Errorf("bastion host key does not match the recorded key")
+
+var knownHostsLock sync.Mutex
+
+// hostKeyCallback trusts a bastion the first time it is seen and records its
+// key in the known hosts file. After that the key has to match, so a bastion
+// that was replaced has to be removed from the file by hand.
+func hostKeyCallback(path string) ssh.HostKeyCallback {
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		knownHostsLock.Lock()
+		defer knownHostsLock.Unlock()
+
+		if _, err := os.Stat(path); err == nil {
+			callback, err := knownhosts.New(path)
+			if err != nil {
+				return err
+			}
+			err = callback(hostname, remote, key)
+			if err == nil {
+				return nil
+			}
+			var keyErr *knownhosts.KeyError
+			if !errors.As(err, &keyErr) {
+				return err
+			}
+			if len(keyErr.Want) > 0 {
+				return fmt.Errorf("%w: %s, remove it from %s if the bastion was recreated", ErrHostKeyMismatch, hostname, path)
+			}
+		}
+
+		slog.Info("recording bastion host key", "host", hostname, "path", path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		addresses := []string{knownhosts.Normalize(hostname)}
+		if remote != nil && knownhosts.Normalize(remote.String()) != addresses[0] {
+			addresses = append(addresses, knownhosts.Normalize(remote.String()))
+		}
+		if _, err := file.WriteString(knownhosts.Line(addresses, key) + "\n"); err != nil {
+			return err
+		}
+		chownToSudoUser(path)
+		return nil
+	}
+}
+
+// The proxy runs as root through sudo but the known hosts file lives in the
+// user's config directory, so hand it back to them.
+func chownToSudoUser(path string) {
+	uid, err := strconv.Atoi(os.Getenv("SUDO_UID"))
+	if err != nil {
+		return
+	}
+	gid, err := strconv.Atoi(os.Getenv("SUDO_GID"))
+	if err != nil {
+		return
+	}
+	os.Chown(path, uid, gid)
+}
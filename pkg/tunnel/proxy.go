@@ -2,48 +2,408 @@ package tunnel
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
 	"net"
+	"sort"
+	"sync"
+	"time"
 
 	"github.com/armon/go-socks5"
 	"github.com/sst/sst/v3/cmd/sst/mosaic/ui"
+	"github.com/sst/sst/v3/pkg/tunnel/proxyenv"
 	"golang.org/x/crypto/ssh"
+	"golang.org/x/sync/errgroup"
 )
 
-func StartProxy(ctx context.Context, username string, host string, key []byte) error {
-	signer, err := ssh.ParsePrivateKey(key)
-	if err != nil {
-		return err
+// DefaultPort is the SOCKS port the network interface forwards to. It routes
+// each connection to the tunnel whose subnets contain the destination.
+const DefaultPort = proxyenv.SocksPort
+
+const (
+	keepaliveInterval = 15 * time.Second
+	keepaliveTimeout  = 10 * time.Second
+	minBackoff        = time.Second
+	maxBackoff        = 30 * time.Second
+)
+
+// Config describes a single bastion to tunnel through.
+type Config struct {
+	Name       string   `json:"name"`
+	Host       string   `json:"host"`
+	Username   string   `json:"username"`
+	PrivateKey string   `json:"privateKey"`
+	Subnets    []string `json:"subnets"`
+	// Port is the SOCKS port for this tunnel alone. When it is 0 the ports
+	// after DefaultPort are tried in order before picking any free one.
+	Port int `json:"port,omitempty"`
+}
+
+// Options configures the proxies started next to the tunnels.
+type Options struct {
+	KnownHosts string
+	// Rootless is set when there is no network interface in front of the
+	// proxies. An HTTP proxy is served as well and destinations outside of the
+	// tunneled subnets are dialed directly instead of through a bastion.
+	Rootless bool
+	Forwards []Forward
+}
+
+type bastion struct {
+	config   Config
+	ssh      *ssh.ClientConfig
+	networks []*net.IPNet
+	verbose  bool
+	stats    *stats
+
+	mu     sync.RWMutex
+	client *ssh.Client
+}
+
+// StartProxies connects to every bastion and serves a SOCKS proxy for each
+// of them, plus one on DefaultPort that routes by destination subnet. SSH
+// connections that drop are re-established with backoff. It only returns
+// early for errors that retrying won't fix, like a host key mismatch.
+func StartProxies(ctx context.Context, configs []Config, options Options) error {
+	stats := newStats(options.Rootless)
+	bastions := []*bastion{}
+	for _, config := range configs {
+		signer, err := ssh.ParsePrivateKey([]byte(config.PrivateKey))
+		if err != nil {
+			return fmt.Errorf("%s: %w", config.Name, err)
+		}
+		b := &bastion{
+			config:  config,
+			verbose: len(configs) > 1,
+			stats:   stats,
+			ssh: &ssh.ClientConfig{
+				User: config.Username,
+				Auth: []ssh.AuthMethod{
+					ssh.PublicKeys(signer),
+				},
+				HostKeyCallback: hostKeyCallback(options.KnownHosts),
+				Timeout:         10 * time.Second,
+			},
+		}
+		for _, subnet := range config.Subnets {
+			_, network, err := net.ParseCIDR(subnet)
+			if err != nil {
+				slog.Warn("invalid subnet", "tunnel", config.Name, "subnet", subnet)
+				continue
+			}
+			b.networks = append(b.networks, network)
+		}
+		bastions = append(bastions, b)
+	}
+	for i, a := range bastions {
+		for _, b := range bastions[i+1:] {
+			if overlaps(a.networks, b.networks) {
+				slog.Warn("tunnel subnets overlap", "first", a.config.Name, "second", b.config.Name)
+				fmt.Println(ui.TEXT_WARNING_BOLD.Render("| ") + ui.TEXT_NORMAL.Render(fmt.Sprintf("%s and %s have overlapping ranges, use the port of %s to reach it", a.config.Name, b.config.Name, b.config.Name)))
+			}
+		}
+	}
+
+	listeners := []net.Listener{}
+	closeAll := func() {
+		for _, listener := range listeners {
+			listener.Close()
+		}
 	}
-	config := &ssh.ClientConfig{
-		User: username,
-		Auth: []ssh.AuthMethod{
-			ssh.PublicKeys(signer),
-		},
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+	for i, b := range bastions {
+		listener, err := listen(b.config.Port, DefaultPort+1+i)
+		if err != nil {
+			closeAll()
+			return fmt.Errorf("%s: %w", b.config.Name, err)
+		}
+		listeners = append(listeners, listener)
+		port := listener.Addr().(*net.TCPAddr).Port
+		stats.tunnel(b.config.Name, func(status *TunnelStatus) {
+			status.Host = b.config.Host
+			status.Port = port
+		})
+		fmt.Println(ui.TEXT_HIGHLIGHT_BOLD.Render("| ") + ui.TEXT_NORMAL.Render(fmt.Sprintf("%s listening on socks5://127.0.0.1:%d", b.config.Name, port)))
 	}
-	sshClient, err := ssh.Dial("tcp", host, config)
+	router, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", DefaultPort))
 	if err != nil {
+		closeAll()
 		return err
 	}
-	defer sshClient.Close()
+	listeners = append(listeners, router)
+	dial := func(ctx context.Context, network, addr string) (net.Conn, error) {
+		b := route(ctx, bastions, addr, options.Rootless)
+		if b == nil {
+			var dialer net.Dialer
+			conn, err := dialer.DialContext(ctx, network, addr)
+			return stats.track("", addr, conn, err)
+		}
+		return b.dial(ctx, network, addr)
+	}
+	var httpListener net.Listener
+	if options.Rootless {
+		httpListener, err = net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", proxyenv.HTTPPort))
+		if err != nil {
+			closeAll()
+			return err
+		}
+		listeners = append(listeners, httpListener)
+		fmt.Println(ui.TEXT_HIGHLIGHT_BOLD.Render("| ") + ui.TEXT_NORMAL.Render(fmt.Sprintf("Proxy listening on socks5://127.0.0.1:%d and http://127.0.0.1:%d", DefaultPort, proxyenv.HTTPPort)))
+	}
+	forwards := []net.Listener{}
+	for _, forward := range options.Forwards {
+		listener, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", forward.Local))
+		if err != nil {
+			closeAll()
+			return fmt.Errorf("forward %d: %w", forward.Local, err)
+		}
+		listeners = append(listeners, listener)
+		forwards = append(forwards, listener)
+		fmt.Println(ui.TEXT_HIGHLIGHT_BOLD.Render("| ") + ui.TEXT_NORMAL.Render(fmt.Sprintf("Forwarding localhost:%d to %s", forward.Local, forward.Remote)))
+	}
+
+	group, ctx := errgroup.WithContext(ctx)
+	group.Go(func() error {
+		stats.serve(ctx)
+		return nil
+	})
+	for i, b := range bastions {
+		b := b
+		listener := listeners[i]
+		group.Go(func() error {
+			return b.run(ctx)
+		})
+		group.Go(func() error {
+			return serve(ctx, listener, b.dial)
+		})
+	}
+	group.Go(func() error {
+		return serve(ctx, router, dial)
+	})
+	if httpListener != nil {
+		group.Go(func() error {
+			return serveHTTP(ctx, httpListener, dial)
+		})
+	}
+	for i, forward := range options.Forwards {
+		forward := forward
+		listener := forwards[i]
+		group.Go(func() error {
+			return serveForward(ctx, listener, forward.Remote, dial)
+		})
+	}
+	return group.Wait()
+}
+
+func listen(port int, preferred int) (net.Listener, error) {
+	if port != 0 {
+		return net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	}
+	if listener, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", preferred)); err == nil {
+		return listener, nil
+	}
+	return net.Listen("tcp", "127.0.0.1:0")
+}
+
+func serve(ctx context.Context, listener net.Listener, dial func(context.Context, string, string) (net.Conn, error)) error {
 	server, err := socks5.New(&socks5.Config{
-		Dial: func(ctx context.Context, network, addr string) (net.Conn, error) {
-			fmt.Println(ui.GetColor(addr).Bold(true).Render("| ") + ui.TEXT_NORMAL.Render("Tunneling "+network+" "+addr))
-			return sshClient.Dial(network, addr)
-		},
+		Dial:     dial,
+		Resolver: remoteResolver{},
 	})
 	if err != nil {
+		listener.Close()
 		return err
 	}
-	errChan := make(chan error, 1)
 	go func() {
-		errChan <- server.ListenAndServe("tcp", fmt.Sprintf("%s:%d", "127.0.0.1", 1080))
+		<-ctx.Done()
+		listener.Close()
 	}()
-	select {
-	case err := <-errChan:
-		return err
-	case <-ctx.Done():
+	err = server.Serve(listener)
+	if ctx.Err() != nil {
 		return nil
 	}
+	return err
+}
+
+// remoteResolver leaves hostnames unresolved so they are looked up by the
+// bastion, which can see the private DNS of the VPC.
+type remoteResolver struct{}
+
+func (remoteResolver) Resolve(ctx context.Context, name string) (context.Context, net.IP, error) {
+	return ctx, nil, nil
+}
+
+// route picks the tunnel with the most specific subnet for the destination.
+// Hostnames are resolved locally to find their subnet. Destinations outside
+// of every subnet go through the first tunnel by name, or nowhere when direct
+// is set, in which case it returns nil.
+func route(ctx context.Context, bastions []*bastion, addr string, direct bool) *bastion {
+	sorted := append([]*bastion{}, bastions...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].config.Name < sorted[j].config.Name
+	})
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+	ips := []net.IP{}
+	if ip := net.ParseIP(host); ip != nil {
+		ips = append(ips, ip)
+	} else {
+		resolved, err := net.DefaultResolver.LookupIP(ctx, "ip", host)
+		if err != nil {
+			// only resolvable inside the VPC
+			return sorted[0]
+		}
+		ips = append(ips, resolved...)
+	}
+	var best *bastion
+	bestSize := -1
+	for _, ip := range ips {
+		for _, b := range sorted {
+			for _, network := range b.networks {
+				size, _ := network.Mask.Size()
+				if network.Contains(ip) && size > bestSize {
+					best = b
+					bestSize = size
+				}
+			}
+		}
+	}
+	if best == nil && !direct {
+		best = sorted[0]
+	}
+	return best
+}
+
+func overlaps(a, b []*net.IPNet) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if x.Contains(y.IP) || y.Contains(x.IP) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (b *bastion) dial(ctx context.Context, network, addr string) (net.Conn, error) {
+	b.mu.RLock()
+	client := b.client
+	b.mu.RUnlock()
+	if client == nil {
+		return b.stats.track(b.config.Name, addr, nil, fmt.Errorf("tunnel %s is not connected", b.config.Name))
+	}
+	label := "Tunneling " + network + " " + addr
+	if b.verbose {
+		label += " via " + b.config.Name
+	}
+	fmt.Println(ui.GetColor(addr).Bold(true).Render("| ") + ui.TEXT_NORMAL.Render(label))
+	conn, err := client.Dial(network, addr)
+	return b.stats.track(b.config.Name, addr, conn, err)
+}
+
+func (b *bastion) run(ctx context.Context) error {
+	backoff := minBackoff
+	for {
+		client, err := ssh.Dial("tcp", b.config.Host, b.ssh)
+		if err != nil {
+			if errors.Is(err, ErrHostKeyMismatch) {
+				return fmt.Errorf("%s: %w", b.config.Name, err)
+			}
+			slog.Error("failed to connect to bastion", "tunnel", b.config.Name, "error", err, "retry", backoff)
+			b.stats.tunnel(b.config.Name, func(status *TunnelStatus) {
+				status.LastError = err.Error()
+			})
+			fmt.Println(ui.TEXT_DANGER_BOLD.Render("| ") + ui.TEXT_NORMAL.Render(fmt.Sprintf("Failed to connect to %s, retrying in %s", b.config.Name, backoff)))
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(backoff):
+			}
+			backoff = min(backoff*2, maxBackoff)
+			continue
+		}
+		backoff = minBackoff
+		slog.Info("connected to bastion", "tunnel", b.config.Name, "host", b.config.Host)
+		b.mu.Lock()
+		b.client = client
+		b.mu.Unlock()
+		b.stats.tunnel(b.config.Name, func(status *TunnelStatus) {
+			if !status.ConnectedAt.IsZero() {
+				status.Reconnects++
+			}
+			status.Connected = true
+			status.ConnectedAt = time.Now()
+			status.LastError = ""
+		})
+
+		done := make(chan struct{})
+		go b.keepalive(client, done)
+		go func() {
+			select {
+			case <-ctx.Done():
+				client.Close()
+			case <-done:
+			}
+		}()
+		client.Wait()
+		close(done)
+
+		b.mu.Lock()
+		b.client = nil
+		b.mu.Unlock()
+		b.stats.tunnel(b.config.Name, func(status *TunnelStatus) {
+			status.Connected = false
+		})
+		if ctx.Err() != nil {
+			return nil
+		}
+		slog.Warn("bastion connection dropped", "tunnel", b.config.Name)
+		fmt.Println(ui.TEXT_WARNING_BOLD.Render("| ") + ui.TEXT_NORMAL.Render("Lost connection to "+b.config.Name+", reconnecting"))
+	}
+}
+
+// keepalive closes the client when the bastion stops answering so a silently
+// dropped connection is noticed and re-established.
+func (b *bastion) keepalive(client *ssh.Client, done chan struct{}) {
+	ticker := time.NewTicker(keepaliveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+		result := make(chan error, 1)
+		sent := time.Now()
+		go func() {
+			_, _, err := client.SendRequest("keepalive@openssh.com", true, nil)
+			result <- err
+		}()
+		select {
+		case err := <-result:
+			if err == nil {
+				rtt := time.Since(sent)
+				b.stats.tunnel(b.config.Name, func(status *TunnelStatus) {
+					status.RTT = float64(rtt.Microseconds()) / 1000
+					status.LastKeepalive = time.Now()
+				})
+				continue
+			}
+			slog.Warn("keepalive failed", "tunnel", b.config.Name, "error", err)
+			b.stats.tunnel(b.config.Name, func(status *TunnelStatus) {
+				status.LastError = "keepalive failed: " + err.Error()
+			})
+		case <-time.After(keepaliveTimeout):
+			slog.Warn("keepalive timed out", "tunnel", b.config.Name)
+			b.stats.tunnel(b.config.Name, func(status *TunnelStatus) {
+				status.LastError = "keepalive timed out"
+			})
+		case <-done:
+			return
+		}
+		client.Close()
+		return
+	}
 }
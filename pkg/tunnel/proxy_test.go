@@ -0,0 +1,102 @@
+package tunnel
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func testBastion(t *testing.T, name string, subnets ...string) *bastion {
+	t.Helper()
+	b := &bastion{config: Config{Name: name, Subnets: subnets}}
+	for _, subnet := range subnets {
+		_, network, err := net.ParseCIDR(subnet)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b.networks = append(b.networks, network)
+	}
+	return b
+}
+
+func TestRoute(t *testing.T) {
+	prod := testBastion(t, "Prod", "10.0.0.0/16")
+	data := testBastion(t, "Data", "10.1.0.0/16", "10.0.8.0/22")
+	bastions := []*bastion{prod, data}
+
+	cases := map[string]*bastion{
+		"10.0.1.5:5432":  prod,
+		"10.1.2.3:6379":  data,
+		"10.0.9.1:443":   data,
+		"192.168.0.1:80": data,
+	}
+	for addr, want := range cases {
+		if got := route(context.Background(), bastions, addr, false); got != want {
+			t.Errorf("route(%s) = %s, want %s", addr, got.config.Name, want.config.Name)
+		}
+	}
+	if got := route(context.Background(), bastions, "192.168.0.1:80", true); got != nil {
+		t.Errorf("expected direct route, got %s", got.config.Name)
+	}
+	if got := route(context.Background(), bastions, "10.1.2.3:6379", true); got != data {
+		t.Error("expected tunneled route in direct mode")
+	}
+	if !overlaps(prod.networks, data.networks) {
+		t.Error("expected overlap")
+	}
+}
+
+func TestHostKeyCallback(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "known_hosts")
+	callback := hostKeyCallback(path)
+	remote := &net.TCPAddr{IP: net.ParseIP("1.2.3.4"), Port: 22}
+
+	first := newHostKey(t)
+	if err := callback("1.2.3.4:22", remote, first); err != nil {
+		t.Fatalf("first connect: %v", err)
+	}
+	if err := callback("1.2.3.4:22", remote, first); err != nil {
+		t.Fatalf("second connect: %v", err)
+	}
+	err := callback("1.2.3.4:22", remote, newHostKey(t))
+	if !errors.Is(err, ErrHostKeyMismatch) {
+		t.Fatalf("expected mismatch, got %v", err)
+	}
+	if err := callback("5.6.7.8:22", &net.TCPAddr{IP: net.ParseIP("5.6.7.8"), Port: 22}, newHostKey(t)); err != nil {
+		t.Fatalf("other host: %v", err)
+	}
+}
+
+func newHostKey(t *testing.T) ssh.PublicKey {
+	t.Helper()
+	public, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ssh.NewPublicKey(public)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestParseForwards(t *testing.T) {
+	forwards, err := ParseForwards(",5432=db.internal:5432, 6379=10.0.1.4:6379")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(forwards) != 2 || forwards[0].Local != 5432 || forwards[1].Remote != "10.0.1.4:6379" {
+		t.Fatalf("unexpected forwards %+v", forwards)
+	}
+	for _, input := range []string{"5432", "abc=db:5432", "5432=db"} {
+		if _, err := ParseForwards(input); err == nil {
+			t.Errorf("expected error for %q", input)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+// Package proxyenv holds the ports of the local tunnel proxies and the
+// environment that points processes at them. It is separate from the tunnel
+// package so the project can use it without pulling in the UI.
+package proxyenv
+
+import (
+	"fmt"
+
+	"github.com/sst/sst/v3/pkg/flag"
+)
+
+// SocksPort routes each connection to the tunnel whose subnets contain the
+// destination.
+const SocksPort = 1080
+
+// HTTPPort serves an HTTP proxy with CONNECT support in rootless mode.
+const HTTPPort = 3128
+
+// Rootless reports whether the tunnel runs without a network interface, in
+// which case processes need to be pointed at the proxies explicitly.
+func Rootless() bool {
+	return flag.SST_TUNNEL_MODE == "rootless"
+}
+
+// Env returns the proxy variables most HTTP clients and SDKs understand.
+func Env() map[string]string {
+	socks := fmt.Sprintf("socks5h://127.0.0.1:%d", SocksPort)
+	http := fmt.Sprintf("http://127.0.0.1:%d", HTTPPort)
+	noProxy := "localhost,127.0.0.1,::1"
+	return map[string]string{
+		"ALL_PROXY":   socks,
+		"all_proxy":   socks,
+		"HTTP_PROXY":  http,
+		"http_proxy":  http,
+		"HTTPS_PROXY": http,
+		"https_proxy": http,
+		"NO_PROXY":    noProxy,
+		"no_proxy":    noProxy,
+	}
+}
@@ -0,0 +1,154 @@
+package tunnel
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Forward exposes a destination in the VPC on a local port, for tools that
+// can't use a proxy.
+type Forward struct {
+	Local  int    `json:"local"`
+	Remote string `json:"remote"`
+}
+
+// ParseForwards parses a comma separated list of `port=host:port` entries.
+func ParseForwards(input string) ([]Forward, error) {
+	result := []Forward{}
+	for _, entry := range strings.Split(input, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		local, remote, ok := strings.Cut(entry, "=")
+		port, err := strconv.Atoi(local)
+		if !ok || err != nil || port <= 0 || port > 65535 {
+			return nil, fmt.Errorf("invalid forward %q, use the format port=host:port", entry)
+		}
+		if _, _, err := net.SplitHostPort(remote); err != nil {
+			return nil, fmt.Errorf("invalid forward %q: %w", entry, err)
+		}
+		result = append(result, Forward{Local: port, Remote: remote})
+	}
+	return result, nil
+}
+
+type dialFunc = func(ctx context.Context, network, addr string) (net.Conn, error)
+
+func serveForward(ctx context.Context, listener net.Listener, remote string, dial dialFunc) error {
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+	}()
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		go func() {
+			defer conn.Close()
+			upstream, err := dial(ctx, "tcp", remote)
+			if err != nil {
+				slog.Error("failed to forward", "remote", remote, "error", err)
+				return
+			}
+			defer upstream.Close()
+			pipe(conn, upstream)
+		}()
+	}
+}
+
+// serveHTTP is a plain HTTP proxy for clients that don't speak SOCKS. HTTPS
+// goes through CONNECT, plain HTTP requests are forwarded as is.
+func serveHTTP(ctx context.Context, listener net.Listener, dial dialFunc) error {
+	transport := &http.Transport{
+		DialContext:       dial,
+		DisableKeepAlives: true,
+	}
+	server := &http.Server{
+		ReadHeaderTimeout: 10 * time.Second,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodConnect {
+				upstream, err := dial(r.Context(), "tcp", r.Host)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusBadGateway)
+					return
+				}
+				defer upstream.Close()
+				hijacker, ok := w.(http.Hijacker)
+				if !ok {
+					http.Error(w, "hijacking not supported", http.StatusInternalServerError)
+					return
+				}
+				conn, buffered, err := hijacker.Hijack()
+				if err != nil {
+					return
+				}
+				defer conn.Close()
+				conn.Write([]byte("HTTP/1.1 200 Connection Established\r\n\r\n"))
+				if buffered.Reader.Buffered() > 0 {
+					io.CopyN(upstream, buffered, int64(buffered.Reader.Buffered()))
+				}
+				pipe(conn, upstream)
+				return
+			}
+			if r.URL.Host == "" {
+				http.Error(w, "not a proxy request", http.StatusBadRequest)
+				return
+			}
+			r.RequestURI = ""
+			r.Header.Del("Proxy-Connection")
+			r.Header.Del("Proxy-Authorization")
+			resp, err := transport.RoundTrip(r)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadGateway)
+				return
+			}
+			defer resp.Body.Close()
+			for key, values := range resp.Header {
+				for _, value := range values {
+					w.Header().Add(key, value)
+				}
+			}
+			w.WriteHeader(resp.StatusCode)
+			io.Copy(w, resp.Body)
+		}),
+	}
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+	err := server.Serve(listener)
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+func pipe(a, b net.Conn) {
+	var wg sync.WaitGroup
+	wg.Add(2)
+	copy := func(dst, src net.Conn) {
+		defer wg.Done()
+		io.Copy(dst, src)
+		if conn, ok := dst.(interface{ CloseWrite() error }); ok {
+			conn.CloseWrite()
+		} else {
+			dst.Close()
+		}
+	}
+	go copy(a, b)
+	go copy(b, a)
+	wg.Wait()
+}
@@ -0,0 +1,247 @@
+package tunnel
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// StatusPort serves the Status of the running tunnel as JSON on /status.
+const StatusPort = 13080
+
+// maxDialErrors is how many of the most recent dial errors are kept.
+const maxDialErrors = 20
+
+type Status struct {
+	Started      time.Time           `json:"started"`
+	Rootless     bool                `json:"rootless"`
+	Tunnels      []TunnelStatus      `json:"tunnels"`
+	Destinations []DestinationStatus `json:"destinations"`
+	Errors       []DialError         `json:"errors"`
+}
+
+type TunnelStatus struct {
+	Name        string    `json:"name"`
+	Host        string    `json:"host"`
+	Port        int       `json:"port"`
+	Connected   bool      `json:"connected"`
+	ConnectedAt time.Time `json:"connectedAt,omitempty"`
+	Reconnects  int       `json:"reconnects"`
+	// RTT is the round trip time of the last keepalive, in milliseconds.
+	RTT           float64   `json:"rtt"`
+	LastKeepalive time.Time `json:"lastKeepalive,omitempty"`
+	LastError     string    `json:"lastError,omitempty"`
+}
+
+// DestinationStatus counts the connections to an address. Tunnel is empty
+// for connections that were dialed directly in rootless mode.
+type DestinationStatus struct {
+	Address  string    `json:"address"`
+	Tunnel   string    `json:"tunnel"`
+	Open     int64     `json:"open"`
+	Total    int64     `json:"total"`
+	BytesIn  int64     `json:"bytesIn"`
+	BytesOut int64     `json:"bytesOut"`
+	LastUsed time.Time `json:"lastUsed"`
+}
+
+type DialError struct {
+	Time    time.Time `json:"time"`
+	Address string    `json:"address"`
+	Tunnel  string    `json:"tunnel"`
+	Error   string    `json:"error"`
+}
+
+// stats is shared by the proxies and bastions of StartProxies.
+type stats struct {
+	mu           sync.Mutex
+	started      time.Time
+	rootless     bool
+	tunnels      map[string]*TunnelStatus
+	destinations map[string]*destination
+	errors       []DialError
+}
+
+type destination struct {
+	address  string
+	tunnel   string
+	open     atomic.Int64
+	total    atomic.Int64
+	bytesIn  atomic.Int64
+	bytesOut atomic.Int64
+	lastUsed atomic.Int64
+}
+
+func newStats(rootless bool) *stats {
+	return &stats{
+		started:      time.Now(),
+		rootless:     rootless,
+		tunnels:      map[string]*TunnelStatus{},
+		destinations: map[string]*destination{},
+	}
+}
+
+func (s *stats) tunnel(name string, update func(*TunnelStatus)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	item, ok := s.tunnels[name]
+	if !ok {
+		item = &TunnelStatus{Name: name}
+		s.tunnels[name] = item
+	}
+	update(item)
+}
+
+// track records the outcome of a dial and wraps the connection to count the
+// bytes going through it.
+func (s *stats) track(tunnel string, addr string, conn net.Conn, err error) (net.Conn, error) {
+	if err != nil {
+		s.mu.Lock()
+		s.errors = append(s.errors, DialError{
+			Time:    time.Now(),
+			Address: addr,
+			Tunnel:  tunnel,
+			Error:   err.Error(),
+		})
+		if len(s.errors) > maxDialErrors {
+			s.errors = s.errors[len(s.errors)-maxDialErrors:]
+		}
+		s.mu.Unlock()
+		return nil, err
+	}
+	key := tunnel + "|" + addr
+	s.mu.Lock()
+	dest, ok := s.destinations[key]
+	if !ok {
+		dest = &destination{address: addr, tunnel: tunnel}
+		s.destinations[key] = dest
+	}
+	s.mu.Unlock()
+	dest.open.Add(1)
+	dest.total.Add(1)
+	dest.lastUsed.Store(time.Now().UnixNano())
+	return &trackedConn{Conn: conn, destination: dest}, nil
+}
+
+func (s *stats) status() Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result := Status{
+		Started:      s.started,
+		Rootless:     s.rootless,
+		Tunnels:      []TunnelStatus{},
+		Destinations: []DestinationStatus{},
+		Errors:       append([]DialError{}, s.errors...),
+	}
+	for _, item := range s.tunnels {
+		result.Tunnels = append(result.Tunnels, *item)
+	}
+	sort.Slice(result.Tunnels, func(i, j int) bool {
+		return result.Tunnels[i].Name < result.Tunnels[j].Name
+	})
+	for _, dest := range s.destinations {
+		result.Destinations = append(result.Destinations, DestinationStatus{
+			Address:  dest.address,
+			Tunnel:   dest.tunnel,
+			Open:     dest.open.Load(),
+			Total:    dest.total.Load(),
+			BytesIn:  dest.bytesIn.Load(),
+			BytesOut: dest.bytesOut.Load(),
+			LastUsed: time.Unix(0, dest.lastUsed.Load()),
+		})
+	}
+	sort.Slice(result.Destinations, func(i, j int) bool {
+		a, b := result.Destinations[i], result.Destinations[j]
+		if a.Open != b.Open {
+			return a.Open > b.Open
+		}
+		return a.LastUsed.After(b.LastUsed)
+	})
+	return result
+}
+
+type trackedConn struct {
+	net.Conn
+	destination *destination
+	closed      atomic.Bool
+}
+
+func (c *trackedConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	c.destination.bytesIn.Add(int64(n))
+	return n, err
+}
+
+func (c *trackedConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	c.destination.bytesOut.Add(int64(n))
+	return n, err
+}
+
+func (c *trackedConn) Close() error {
+	if c.closed.CompareAndSwap(false, true) {
+		c.destination.open.Add(-1)
+		c.destination.lastUsed.Store(time.Now().UnixNano())
+	}
+	return c.Conn.Close()
+}
+
+// CloseWrite is passed through so half closed connections keep working.
+func (c *trackedConn) CloseWrite() error {
+	if conn, ok := c.Conn.(interface{ CloseWrite() error }); ok {
+		return conn.CloseWrite()
+	}
+	return c.Close()
+}
+
+// serve is best effort, another tunnel might already be using the port.
+func (s *stats) serve(ctx context.Context) {
+	listener, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", StatusPort))
+	if err != nil {
+		slog.Warn("failed to serve tunnel status", "error", err)
+		return
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(s.status())
+	})
+	server := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+	server.Serve(listener)
+}
+
+// GetStatus reads the status of the tunnel running on this machine.
+func GetStatus(ctx context.Context) (*Status, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("http://127.0.0.1:%d/status", StatusPort), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	var result Status
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
@@ -0,0 +1,60 @@
+package tunnel
+
+import (
+	"errors"
+	"io"
+	"net"
+	"testing"
+)
+
+func TestStatsTrack(t *testing.T) {
+	s := newStats(true)
+	s.tunnel("MyVpc", func(status *TunnelStatus) {
+		status.Connected = true
+	})
+
+	local, remote := net.Pipe()
+	conn, err := s.track("MyVpc", "db:5432", local, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		remote.Write([]byte("hello"))
+		io.ReadFull(remote, make([]byte, 3))
+	}()
+	io.ReadFull(conn, make([]byte, 5))
+	conn.Write([]byte("hey"))
+
+	status := s.status()
+	if len(status.Destinations) != 1 {
+		t.Fatalf("got %d destinations", len(status.Destinations))
+	}
+	dest := status.Destinations[0]
+	if dest.Open != 1 || dest.Total != 1 || dest.BytesIn != 5 || dest.BytesOut != 3 {
+		t.Fatalf("unexpected destination %+v", dest)
+	}
+
+	conn.Close()
+	conn.Close()
+	if open := s.status().Destinations[0].Open; open != 0 {
+		t.Fatalf("open = %d after close", open)
+	}
+
+	if _, err := s.track("MyVpc", "db:5432", nil, errors.New("connect failed")); err == nil {
+		t.Fatal("expected the dial error to be returned")
+	}
+	status = s.status()
+	if len(status.Errors) != 1 || status.Errors[0].Error != "connect failed" {
+		t.Fatalf("unexpected errors %+v", status.Errors)
+	}
+	if len(status.Tunnels) != 1 || !status.Tunnels[0].Connected {
+		t.Fatalf("unexpected tunnels %+v", status.Tunnels)
+	}
+
+	for i := 0; i < maxDialErrors+5; i++ {
+		s.track("", "example.com:80", nil, errors.New("refused"))
+	}
+	if got := len(s.status().Errors); got != maxDialErrors {
+		t.Fatalf("kept %d errors", got)
+	}
+}
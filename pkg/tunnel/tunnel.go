@@ -57,7 +57,7 @@ func Start(routes ...string) error {
 func tun2socks(name string) {
 	key := new(engine.Key)
 	key.Device = name
-	key.Proxy = "socks5://127.0.0.1:1080"
+	key.Proxy = fmt.Sprintf("socks5://127.0.0.1:%d", DefaultPort)
 	engine.Insert(key)
 	engine.Start()
 }
@@ -0,0 +1,176 @@
+package golang
+
+import (
+	"fmt"
+	"go/format"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/sst/sst/v3/internal/fs"
+	"github.com/sst/sst/v3/pkg/project/common"
+)
+
+// Generate writes a typed `resource` package into every Go module under the
+// root, at <module>/sst/resource. Each link becomes a struct built from its
+// properties and a function that loads it with the sdk/golang/resource
+// package.
+func Generate(root string, links common.Links) error {
+	modules := fs.FindDown(root, "go.mod")
+	if len(modules) == 0 {
+		return nil
+	}
+	properties := map[string]interface{}{}
+	for name, link := range links {
+		properties[name] = link.Properties
+	}
+	properties["App"] = map[string]interface{}{
+		"name":  "",
+		"stage": "",
+	}
+	source, err := Render(properties)
+	if err != nil {
+		return err
+	}
+	for _, module := range modules {
+		dir := filepath.Join(filepath.Dir(module), "sst", "resource")
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(dir, "resource.go"), source, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Render returns the formatted source of the resource package for the given
+// link properties.
+func Render(properties map[string]interface{}) ([]byte, error) {
+	names := sortedKeys(properties)
+	// every link declares a function and a type
+	declared := map[string]string{}
+	for _, name := range names {
+		for _, id := range []string{identifier(name), identifier(name) + "Resource"} {
+			if other, ok := declared[id]; ok {
+				return nil, fmt.Errorf("the links %q and %q are both named %s in the Go resource package, rename one of them", other, name, id)
+			}
+			declared[id] = name
+		}
+	}
+	var builder strings.Builder
+	builder.WriteString("// Code generated by SST. DO NOT EDIT.\n\n")
+	builder.WriteString("package resource\n\n")
+	builder.WriteString(header)
+	for _, name := range names {
+		fields, _ := properties[name].(map[string]interface{})
+		typ, err := infer(name, fields)
+		if err != nil {
+			return nil, err
+		}
+		id := identifier(name)
+		builder.WriteString(fmt.Sprintf("type %sResource %s\n\n", id, typ))
+		builder.WriteString(fmt.Sprintf("// %s returns the linked %q resource.\n", id, name))
+		builder.WriteString(fmt.Sprintf("func %s() (%sResource, error) {\n", id, id))
+		builder.WriteString(fmt.Sprintf("\tvar result %sResource\n", id))
+		builder.WriteString(fmt.Sprintf("\treturn result, get(%q, &result)\n", name))
+		builder.WriteString("}\n\n")
+	}
+	builder.WriteString(footer)
+	return format.Source([]byte(builder.String()))
+}
+
+func infer(path string, input map[string]interface{}) (string, error) {
+	var builder strings.Builder
+	builder.WriteString("struct {\n")
+	fields := map[string]string{}
+	for _, key := range sortedKeys(input) {
+		id := identifier(key)
+		if other, ok := fields[id]; ok {
+			return "", fmt.Errorf("the properties %q and %q of %q are both named %s in the Go resource package", other, key, path, id)
+		}
+		fields[id] = key
+		typ, err := inferType(path+"."+key, input[key])
+		if err != nil {
+			return "", err
+		}
+		builder.WriteString(fmt.Sprintf("%s %s `json:%q`\n", id, typ, key))
+	}
+	builder.WriteString("}")
+	return builder.String(), nil
+}
+
+func inferType(path string, value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return "string", nil
+	case bool:
+		return "bool", nil
+	case int:
+		return "int", nil
+	case float64, float32:
+		return "float64", nil
+	case []interface{}:
+		return "[]any", nil
+	case map[string]interface{}:
+		return infer(path, v)
+	default:
+		return "any", nil
+	}
+}
+
+// identifier turns a link or property name into an exported Go identifier.
+func identifier(name string) string {
+	var builder strings.Builder
+	upper := true
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		builder.WriteRune(r)
+	}
+	result := builder.String()
+	if result == "" || unicode.IsDigit(rune(result[0])) {
+		result = "X" + result
+	}
+	return result
+}
+
+func sortedKeys(input map[string]interface{}) []string {
+	keys := make([]string, 0, len(input))
+	for key := range input {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+const header = `import (
+	"encoding/json"
+	"fmt"
+
+	sst "github.com/sst/sst/v3/sdk/golang/resource"
+)
+
+`
+
+const footer = `
+func get(name string, target any) error {
+	value, err := sst.Get(name)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, target)
+}
+`
@@ -0,0 +1,96 @@
+package golang_test
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sst/sst/v3/pkg/project/common"
+	"github.com/sst/sst/v3/pkg/types/golang"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate(t *testing.T) {
+	t.Run("no go.mod returns nil", func(t *testing.T) {
+		dir := t.TempDir()
+		err := golang.Generate(dir, common.Links{})
+		require.NoError(t, err)
+		assert.NoDirExists(t, filepath.Join(dir, "sst"))
+	})
+
+	t.Run("creates typed resource package", func(t *testing.T) {
+		dir := t.TempDir()
+		sub := filepath.Join(dir, "functions", "api")
+		os.MkdirAll(sub, 0755)
+		os.WriteFile(filepath.Join(sub, "go.mod"), []byte("module api"), 0644)
+
+		links := common.Links{
+			"MyBucket": {
+				Properties: map[string]interface{}{
+					"name": "my-bucket",
+					"arn":  "arn:aws:s3:::my-bucket",
+				},
+			},
+			"my-db": {
+				Properties: map[string]interface{}{
+					"port":    float64(5432),
+					"enabled": true,
+					"config": map[string]interface{}{
+						"type": "postgres",
+					},
+				},
+			},
+		}
+
+		err := golang.Generate(dir, links)
+		require.NoError(t, err)
+
+		path := filepath.Join(sub, "sst", "resource", "resource.go")
+		content, err := os.ReadFile(path)
+		require.NoError(t, err)
+		fset := token.NewFileSet()
+		file, err := parser.ParseFile(fset, path, content, 0)
+		require.NoError(t, err)
+
+		out := string(content)
+		assert.Contains(t, out, "package resource")
+		assert.Contains(t, out, "type MyBucketResource struct")
+		assert.Contains(t, out, "Arn  string `json:\"arn\"`")
+		assert.Contains(t, out, "type MyDbResource struct")
+		assert.Contains(t, out, "Port    float64 `json:\"port\"`")
+		assert.Contains(t, out, "Type string `json:\"type\"`")
+		assert.Contains(t, out, "type AppResource struct")
+		assert.Contains(t, out, "func MyDb() (MyDbResource, error)")
+		assert.Contains(t, out, `get("my-db", &result)`)
+		assert.NotContains(t, out, "panic(")
+
+		conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+		_, err = conf.Check("resource", fset, []*ast.File{file}, nil)
+		require.NoError(t, err)
+	})
+
+	t.Run("colliding names", func(t *testing.T) {
+		_, err := golang.Render(map[string]interface{}{
+			"my-bucket": map[string]interface{}{},
+			"MyBucket":  map[string]interface{}{},
+		})
+		require.ErrorContains(t, err, `"MyBucket" and "my-bucket" are both named MyBucket`)
+
+		_, err = golang.Render(map[string]interface{}{
+			"Api":         map[string]interface{}{},
+			"ApiResource": map[string]interface{}{},
+		})
+		require.ErrorContains(t, err, "both named ApiResource")
+
+		_, err = golang.Render(map[string]interface{}{
+			"Db": map[string]interface{}{"user-name": "", "userName": ""},
+		})
+		require.ErrorContains(t, err, `"user-name" and "userName" of "Db" are both named UserName`)
+	})
+}
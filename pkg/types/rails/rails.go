@@ -1,53 +1,91 @@
 package rails
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/sst/sst/v3/internal/fs"
 	"github.com/sst/sst/v3/pkg/project/common"
 )
 
-// Generate is stubbed out — Rails support is not yet implemented.
+var methodName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Generate writes lib/sst.rb into every Rack app under the root. It defines
+// an SST module with a method per link, e.g. `SST.MyBucket["name"]`.
 func Generate(root string, links common.Links) error {
+	projects := fs.FindDown(root, "config.ru")
+	if len(projects) == 0 {
+		return nil
+	}
+	source := Render(links)
+	for _, project := range projects {
+		lib := filepath.Join(filepath.Dir(project), "lib")
+		if _, err := os.Stat(lib); err != nil {
+			continue
+		}
+		if err := os.WriteFile(filepath.Join(lib, "sst.rb"), []byte(source), 0644); err != nil {
+			return err
+		}
+	}
 	return nil
-	// projects := fs.FindDown(root, "config.ru")
-	// files := []io.Writer{}
-	// for _, project := range projects {
-	// 	// check if lib path exists
-	// 	if _, err := os.Stat(filepath.Join(filepath.Dir(project), "lib")); err == nil {
-	// 		path := filepath.Join(filepath.Dir(project), "lib", "sst.rb")
-	// 		file, _ := os.Create(path)
-	// 		files = append(files, file)
-	// 	}
-	// }
-	// writer := io.MultiWriter(files...)
-	// writer.Write([]byte(`require 'json'
-	// module SST
-	//   class << self
-	//     private
-	//
-	//     def parse_resource(resource_name)
-	//       env_var = "SST_RESOURCE_#{resource_name}"
-	//       parse_json(ENV[env_var])
-	//     end
-	//
-	//     def parse_json(json_string)
-	//       return nil if json_string.nil?
-	//       JSON.parse(json_string)
-	//     rescue JSON::ParserError
-	//       json_string  # Return the original string if it's not valid JSON
-	//     end
-	//
-	//   end
-	// `,
-	// ))
-	//
-	// for name := range links {
-	// 	writer.Write([]byte(fmt.Sprintf(`
-	//   def %s
-	//     @%s ||= parse_resource('%s')
-	//   end
-	// `, name, name, name)))
-	// }
-	//
-	// writer.Write([]byte("\nend"))
-	//
-	// return nil
+}
+
+// Render returns the source of the SST module for the given links.
+func Render(links common.Links) string {
+	names := []string{"App"}
+	for name := range links {
+		if name != "App" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var builder strings.Builder
+	builder.WriteString(`# Automatically generated by SST. Do not edit.
+require 'json'
+
+module SST
+  class << self
+`)
+	for _, name := range names {
+		if methodName.MatchString(name) {
+			builder.WriteString(fmt.Sprintf(`    def %s
+      parse_resource('%s')
+    end
+
+`, name, name))
+			continue
+		}
+		builder.WriteString(fmt.Sprintf(`    define_method(%q) do
+      parse_resource(%q)
+    end
+
+`, name, name))
+	}
+	builder.WriteString(`    def [](name)
+      parse_resource(name.to_s)
+    end
+
+    private
+
+    def parse_resource(resource_name)
+      @resources ||= {}
+      return @resources[resource_name] if @resources.key?(resource_name)
+      @resources[resource_name] = parse_json(ENV["SST_RESOURCE_#{resource_name}"])
+    end
+
+    def parse_json(json_string)
+      return nil if json_string.nil?
+      JSON.parse(json_string)
+    rescue JSON::ParserError
+      json_string
+    end
+  end
+end
+`)
+	return builder.String()
 }
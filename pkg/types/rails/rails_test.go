@@ -0,0 +1,43 @@
+package rails_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sst/sst/v3/pkg/project/common"
+	"github.com/sst/sst/v3/pkg/types/rails"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate(t *testing.T) {
+	t.Run("app without lib is skipped", func(t *testing.T) {
+		dir := t.TempDir()
+		os.WriteFile(filepath.Join(dir, "config.ru"), []byte(""), 0644)
+		err := rails.Generate(dir, common.Links{})
+		require.NoError(t, err)
+		assert.NoFileExists(t, filepath.Join(dir, "lib", "sst.rb"))
+	})
+
+	t.Run("creates lib/sst.rb", func(t *testing.T) {
+		dir := t.TempDir()
+		os.MkdirAll(filepath.Join(dir, "lib"), 0755)
+		os.WriteFile(filepath.Join(dir, "config.ru"), []byte(""), 0644)
+
+		err := rails.Generate(dir, common.Links{
+			"MyBucket": {Properties: map[string]interface{}{"name": "my-bucket"}},
+			"my-db":    {Properties: map[string]interface{}{}},
+		})
+		require.NoError(t, err)
+
+		content, err := os.ReadFile(filepath.Join(dir, "lib", "sst.rb"))
+		require.NoError(t, err)
+
+		out := string(content)
+		assert.Contains(t, out, "module SST")
+		assert.Contains(t, out, "def MyBucket\n      parse_resource('MyBucket')")
+		assert.Contains(t, out, "def App\n")
+		assert.Contains(t, out, `define_method("my-db")`)
+	})
+}
@@ -0,0 +1,194 @@
+package rust
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/sst/sst/v3/internal/fs"
+	"github.com/sst/sst/v3/pkg/project/common"
+)
+
+// Generate writes src/sst.rs into every Cargo crate under the root. The
+// module has a serde struct per link and a Resource struct that loads them
+// through the sst_sdk crate.
+func Generate(root string, links common.Links) error {
+	crates := fs.FindDown(root, "Cargo.toml")
+	if len(crates) == 0 {
+		return nil
+	}
+	properties := map[string]interface{}{}
+	for name, link := range links {
+		properties[name] = link.Properties
+	}
+	properties["App"] = map[string]interface{}{
+		"name":  "",
+		"stage": "",
+	}
+	source := Render(properties)
+	for _, crate := range crates {
+		src := filepath.Join(filepath.Dir(crate), "src")
+		if _, err := os.Stat(src); err != nil {
+			continue
+		}
+		if err := os.WriteFile(filepath.Join(src, "sst.rs"), []byte(source), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Render returns the source of the Rust module for the given link
+// properties.
+func Render(properties map[string]interface{}) string {
+	names := sortedKeys(properties)
+	var builder strings.Builder
+	builder.WriteString("// Automatically generated by SST. Do not edit.\n")
+	builder.WriteString("#![allow(dead_code)]\n\n")
+	builder.WriteString("use serde::Deserialize;\n")
+	for _, name := range names {
+		fields, _ := properties[name].(map[string]interface{})
+		writeStruct(&builder, typeName(name), fields)
+	}
+	builder.WriteString("\n#[derive(Debug, Clone)]\npub struct Resource {\n")
+	for _, name := range names {
+		builder.WriteString(fmt.Sprintf("    pub %s: Option<%s>,\n", fieldName(name), typeName(name)))
+	}
+	builder.WriteString("}\n\nimpl Resource {\n")
+	builder.WriteString("    pub fn init() -> Result<Self, sst_sdk::ResourceError> {\n")
+	builder.WriteString("        let resource = sst_sdk::Resource::init()?;\n")
+	builder.WriteString("        Ok(Self {\n")
+	for _, name := range names {
+		builder.WriteString(fmt.Sprintf("            %s: optional(resource.get(%q))?,\n", fieldName(name), name))
+	}
+	builder.WriteString("        })\n    }\n}\n")
+	builder.WriteString(footer)
+	return builder.String()
+}
+
+// writeStruct writes the struct and, before it, any structs for nested
+// objects so every type is declared at the top level of the module.
+func writeStruct(builder *strings.Builder, name string, fields map[string]interface{}) {
+	keys := sortedKeys(fields)
+	for _, key := range keys {
+		if nested, ok := fields[key].(map[string]interface{}); ok {
+			writeStruct(builder, name+typeName(key), nested)
+		}
+	}
+	builder.WriteString("\n#[derive(Debug, Clone, Deserialize)]\n")
+	builder.WriteString(fmt.Sprintf("pub struct %s {\n", name))
+	for _, key := range keys {
+		builder.WriteString(fmt.Sprintf("    #[serde(rename = %q)]\n", key))
+		builder.WriteString(fmt.Sprintf("    pub %s: %s,\n", fieldName(key), inferType(name, key, fields[key])))
+	}
+	builder.WriteString("}\n")
+}
+
+func inferType(parent string, key string, value interface{}) string {
+	switch value.(type) {
+	case string:
+		return "String"
+	case bool:
+		return "bool"
+	case int:
+		return "i64"
+	case float64, float32:
+		return "f64"
+	case []interface{}:
+		return "Vec<serde_json::Value>"
+	case map[string]interface{}:
+		return parent + typeName(key)
+	default:
+		return "serde_json::Value"
+	}
+}
+
+func words(name string) []string {
+	var result []string
+	var current []rune
+	runes := []rune(name)
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			if len(current) > 0 {
+				result = append(result, string(current))
+				current = nil
+			}
+			continue
+		}
+		boundary := unicode.IsUpper(r) && len(current) > 0 &&
+			(unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1])))
+		if boundary {
+			result = append(result, string(current))
+			current = nil
+		}
+		current = append(current, r)
+	}
+	if len(current) > 0 {
+		result = append(result, string(current))
+	}
+	return result
+}
+
+func typeName(name string) string {
+	var builder strings.Builder
+	for _, word := range words(name) {
+		runes := []rune(word)
+		builder.WriteRune(unicode.ToUpper(runes[0]))
+		builder.WriteString(string(runes[1:]))
+	}
+	result := builder.String()
+	if result == "" || unicode.IsDigit(rune(result[0])) {
+		result = "X" + result
+	}
+	return result
+}
+
+func fieldName(name string) string {
+	parts := words(name)
+	for i, word := range parts {
+		parts[i] = strings.ToLower(word)
+	}
+	result := strings.Join(parts, "_")
+	if result == "" || unicode.IsDigit(rune(result[0])) {
+		result = "_" + result
+	}
+	if keywords[result] {
+		result = "r#" + result
+	}
+	return result
+}
+
+var keywords = map[string]bool{
+	"as": true, "async": true, "await": true, "break": true, "const": true,
+	"continue": true, "crate": true, "dyn": true, "else": true, "enum": true,
+	"extern": true, "false": true, "fn": true, "for": true, "if": true,
+	"impl": true, "in": true, "let": true, "loop": true, "match": true,
+	"mod": true, "move": true, "mut": true, "pub": true, "ref": true,
+	"return": true, "static": true, "struct": true, "trait": true,
+	"true": true, "type": true, "unsafe": true, "use": true, "where": true,
+	"while": true, "abstract": true, "become": true, "box": true, "do": true,
+	"final": true, "macro": true, "override": true, "priv": true, "try": true,
+	"typeof": true, "unsized": true, "virtual": true, "yield": true,
+}
+
+func sortedKeys(input map[string]interface{}) []string {
+	keys := make([]string, 0, len(input))
+	for key := range input {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+const footer = `
+fn optional<T>(result: Result<T, sst_sdk::ResourceError>) -> Result<Option<T>, sst_sdk::ResourceError> {
+    match result {
+        Ok(value) => Ok(Some(value)),
+        Err(sst_sdk::ResourceError::NotFound) => Ok(None),
+        Err(err) => Err(err),
+    }
+}
+`
@@ -0,0 +1,56 @@
+package rust_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sst/sst/v3/pkg/project/common"
+	"github.com/sst/sst/v3/pkg/types/rust"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate(t *testing.T) {
+	t.Run("crate without src is skipped", func(t *testing.T) {
+		dir := t.TempDir()
+		os.WriteFile(filepath.Join(dir, "Cargo.toml"), []byte("[workspace]"), 0644)
+		err := rust.Generate(dir, common.Links{})
+		require.NoError(t, err)
+		assert.NoFileExists(t, filepath.Join(dir, "src", "sst.rs"))
+	})
+
+	t.Run("creates sst.rs with structs", func(t *testing.T) {
+		dir := t.TempDir()
+		os.MkdirAll(filepath.Join(dir, "src"), 0755)
+		os.WriteFile(filepath.Join(dir, "Cargo.toml"), []byte("[package]"), 0644)
+
+		links := common.Links{
+			"MyBucket": {
+				Properties: map[string]interface{}{
+					"name": "my-bucket",
+					"type": "bucket",
+					"cors": map[string]interface{}{
+						"maxAge": float64(60),
+					},
+				},
+			},
+		}
+
+		err := rust.Generate(dir, links)
+		require.NoError(t, err)
+
+		content, err := os.ReadFile(filepath.Join(dir, "src", "sst.rs"))
+		require.NoError(t, err)
+
+		out := string(content)
+		assert.Contains(t, out, "pub struct MyBucket {")
+		assert.Contains(t, out, "pub struct MyBucketCors {")
+		assert.Contains(t, out, "#[serde(rename = \"maxAge\")]\n    pub max_age: f64,")
+		assert.Contains(t, out, "pub r#type: String,")
+		assert.Contains(t, out, "pub cors: MyBucketCors,")
+		assert.Contains(t, out, "pub my_bucket: Option<MyBucket>,")
+		assert.Contains(t, out, `my_bucket: optional(resource.get("MyBucket"))?,`)
+		assert.Contains(t, out, "pub app: Option<App>,")
+	})
+}
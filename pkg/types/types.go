@@ -1,17 +1,22 @@
 package types
 
 import (
+	"errors"
 	"log/slog"
 
 	"github.com/sst/sst/v3/pkg/project/common"
 	"github.com/sst/sst/v3/pkg/project/path"
+	"github.com/sst/sst/v3/pkg/types/golang"
 	"github.com/sst/sst/v3/pkg/types/python"
 	"github.com/sst/sst/v3/pkg/types/rails"
+	"github.com/sst/sst/v3/pkg/types/rust"
 	"github.com/sst/sst/v3/pkg/types/typescript"
 )
 
 type Generator = func(root string, complete common.Links) error
 
+// Generate runs every generator, a generator that fails doesn't stop the
+// others. The errors are returned together.
 func Generate(cfgPath string, complete common.Links) error {
 	root := path.ResolveRootDir(cfgPath)
 	// gitroot, err := fs.FindUp(root, ".git")
@@ -19,17 +24,21 @@ func Generate(cfgPath string, complete common.Links) error {
 	// 	root = filepath.Dir(gitroot)
 	// }
 	slog.Info("generating types", "root", root)
+	errs := []error{}
 	for _, generator := range All {
 		err := generator(root, complete)
 		if err != nil {
-			return err
+			slog.Error("failed to generate types", "err", err)
+			errs = append(errs, err)
 		}
 	}
-	return nil
+	return errors.Join(errs...)
 }
 
 var All = []Generator{
 	typescript.Generate,
 	python.Generate,
 	rails.Generate,
+	golang.Generate,
+	rust.Generate,
 }
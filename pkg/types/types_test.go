@@ -0,0 +1,31 @@
+package types
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/sst/sst/v3/pkg/project/common"
+)
+
+func TestGenerateRunsEveryGenerator(t *testing.T) {
+	original := All
+	defer func() { All = original }()
+
+	first := errors.New("first")
+	ran := false
+	All = []Generator{
+		func(root string, links common.Links) error { return first },
+		func(root string, links common.Links) error {
+			ran = true
+			return nil
+		},
+	}
+	err := Generate(filepath.Join(t.TempDir(), "sst.config.ts"), common.Links{})
+	if !errors.Is(err, first) {
+		t.Fatalf("expected the error of the first generator, got %v", err)
+	}
+	if !ran {
+		t.Fatal("expected the generators after a failure to run")
+	}
+}
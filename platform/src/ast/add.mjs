@@ -8,6 +8,7 @@ const config = process.argv[2];
 const pkg = process.argv[3];
 const version = process.argv[4];
 const pkgName = process.argv[5] || "";
+const upgrade = process.argv[6] === "upgrade";
 
 const code = fs.readFileSync(config);
 
@@ -77,11 +78,43 @@ if (!ts.isObjectLiteralExpression(providersProperty.initializer)) {
   process.exit(1);
 }
 
-if (
-  providersProperty.initializer.properties.find(
-    (property) => property.name.getText().replaceAll('"', "") === pkg,
-  )
-) {
+const existingProperty = providersProperty.initializer.properties.find(
+  (property) => property.name.getText().replaceAll('"', "") === pkg,
+);
+if (existingProperty && !upgrade) {
+  process.exit(0);
+}
+
+if (existingProperty) {
+  // "aws": true, "aws": "6.0.0" or "aws": { version: "6.0.0", ... }
+  if (
+    ts.isStringLiteral(existingProperty.initializer) ||
+    existingProperty.initializer.kind === ts.SyntaxKind.TrueKeyword
+  ) {
+    existingProperty.initializer = ts.factory.createStringLiteral(version);
+  } else if (ts.isObjectLiteralExpression(existingProperty.initializer)) {
+    const versionProperty = existingProperty.initializer.properties.find(
+      (property) =>
+        ts.isPropertyAssignment(property) &&
+        property.name.getText().replaceAll('"', "") === "version",
+    );
+    if (versionProperty) {
+      versionProperty.initializer = ts.factory.createStringLiteral(version);
+    } else {
+      existingProperty.initializer.properties.push(
+        ts.factory.createPropertyAssignment(
+          "version",
+          ts.factory.createStringLiteral(version),
+        ),
+      );
+    }
+  } else {
+    console.error(
+      `The "${pkg}" provider must be a string or a plain object to be upgraded.`,
+    );
+    process.exit(1);
+  }
+  await write();
   process.exit(0);
 }
 // Create a new property node
@@ -107,14 +140,18 @@ const newProperty = ts.factory.createPropertyAssignment(
 
 providersProperty.initializer.properties.push(newProperty);
 
-const printer = ts.createPrinter();
-const modifiedCode = printer.printNode(
-  ts.EmitHint.Unspecified,
-  sourceFile,
-  sourceFile,
-);
+await write();
+
+async function write() {
+  const printer = ts.createPrinter();
+  const modifiedCode = printer.printNode(
+    ts.EmitHint.Unspecified,
+    sourceFile,
+    sourceFile,
+  );
 
-const formattedCode = await prettier.format(modifiedCode, {
-  parser: "typescript",
-});
-fs.writeFileSync(config, formattedCode);
+  const formattedCode = await prettier.format(modifiedCode, {
+    parser: "typescript",
+  });
+  fs.writeFileSync(config, formattedCode);
+}
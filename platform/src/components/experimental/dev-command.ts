@@ -1,4 +1,4 @@
-import { ComponentResourceOptions, output } from "@pulumi/pulumi";
+import { ComponentResourceOptions, all, output } from "@pulumi/pulumi";
 import { Component } from "../component";
 import { Link } from "../link.js";
 import { Input } from "../input";
@@ -27,6 +27,62 @@ export interface DevCommandArgs {
      * @default The name of the component.
      */
     title?: Input<string>;
+    /**
+     * Other dev commands, or the names of the components that run in dev, that need to be
+     * ready before this one is started.
+     *
+     * They need to start on their own, with `autostart` enabled, and can't depend on this
+     * command in turn. `sst dev` fails if they don't.
+     *
+     * @example
+     * ```js
+     * {
+     *   dev: {
+     *     dependsOn: [database]
+     *   }
+     * }
+     * ```
+     */
+    dependsOn?: (string | Component)[];
+    /**
+     * Configure when the command is considered ready. Commands that depend on this one are
+     * only started once every check that's set passes.
+     *
+     * @default Ready as soon as it starts.
+     * @example
+     * ```js
+     * {
+     *   dev: {
+     *     ready: {
+     *       port: 5432,
+     *       log: "database system is ready to accept connections"
+     *     }
+     *   }
+     * }
+     * ```
+     */
+    ready?: {
+      /**
+       * A TCP port on `localhost` that accepts connections once it's ready.
+       */
+      port?: Input<number>;
+      /**
+       * A URL that responds to a `GET` request with a status below 500 once it's ready.
+       */
+      url?: Input<string>;
+      /**
+       * A regular expression that's matched against each line of output.
+       */
+      log?: Input<string>;
+    };
+    /**
+     * Restart the command when it exits. With `on-failure` it's only restarted when it exits
+     * with a non-zero code. Restarts back off from 1 to 30 seconds, and commands you stop
+     * yourself are not restarted.
+     *
+     * @default `"never"`
+     */
+    restart?: Input<"never" | "on-failure" | "always">;
   };
   /**
    * [Link resources](/docs/linking/) to your command. This will allow you to access it in your
@@ -114,6 +170,15 @@ export class DevCommand extends Component {
         directory: args.dev?.directory,
         autostart: args.dev?.autostart !== false,
         command: args.dev?.command,
+        dependsOn: all(
+          (args.dev?.dependsOn ?? []).map((dep) =>
+            typeof dep === "string"
+              ? dep
+              : dep.urn.apply((urn) => urn.split("::").at(-1)!),
+          ),
+        ),
+        ready: args.dev?.ready,
+        restart: args.dev?.restart,
         aws: {
           role: args.aws?.role,
         },
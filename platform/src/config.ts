@@ -271,6 +271,51 @@ export interface App {
    */
   protect?: boolean;
 
+  /**
+   * Require an approval before `sst deploy` makes risky changes. The changes are previewed
+   * before they are deployed, and if they break any of these rules the deploy stops.
+   *
+   * For example, require approval for the _production_ stage.
+   *
+   * ```ts
+   * {
+   *   approval: input.stage === "production" ? {
+   *     statefulDeletes: true,
+   *     databaseReplacements: true,
+   *     maxChanges: 50
+   *   } : undefined
+   * }
+   * ```
+   *
+   * When you run `sst deploy` in a terminal, you are asked to approve the changes. Otherwise,
+   * it fails with a token for the exact set of changes, that you pass in to deploy them.
+   *
+   * ```bash frame="none"
+   * sst deploy --stage production --approve 3f2a9c1b7d4e
+   * ```
+   *
+   * The token changes if the changes do, so an approval can't be reused for a different deploy.
+   * The same rules are checked by `sst diff`, which also prints the token.
+   */
+  approval?: {
+    /**
+     * Require approval to delete or replace stateful resources, like databases, buckets,
+     * tables, queues, and file systems. Resources that are retained on delete are not
+     * included.
+     * @default `false`
+     */
+    statefulDeletes?: boolean;
+    /**
+     * Require approval to replace a database.
+     * @default `false`
+     */
+    databaseReplacements?: boolean;
+    /**
+     * Require approval if more than this many resources are changed.
+     */
+    maxChanges?: number;
+  };
+
   /**
    * Configure which directories should be watched for changes when running `sst dev`.
    * By default, all directories are watched (except node_modules and hidden directories).
@@ -284,6 +329,13 @@ export interface App {
    *
    * This will only watch the `packages/www` and `packages/api` directories.
    * The paths are relative to the project root.
+   *
+   * Files and directories matched by a `.gitignore` or a `.sstignore` are not watched.
+   * These work the same way as in git, so a `.sstignore` can also include a hidden
+   * directory with `!.storybook/`, or ignore a build output that's checked in.
+   *
+   * Changes are batched together, so saving many files at once, or running
+   * `git checkout`, only triggers a single rebuild.
    */
   watch?: string[];
 }